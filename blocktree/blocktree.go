@@ -0,0 +1,316 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package blocktree
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/core/types"
+)
+
+var (
+	// ErrBlockExists is returned when adding a block which is already in the tree
+	ErrBlockExists = errors.New("block already exists in block tree")
+	// ErrParentNotFound is returned when adding a block whose parent isn't in the tree
+	ErrParentNotFound = errors.New("cannot find parent block in block tree")
+	// ErrNodeNotFound is returned when a block isn't in the tree
+	ErrNodeNotFound = errors.New("cannot find block in block tree")
+)
+
+// BlockInfo describes a block in the tree, used by ForkChoice to compare chains
+type BlockInfo struct {
+	Hash   common.Hash
+	Number *big.Int
+	// Arrival is the order in which the block was added to the tree, starting from 0 for the root
+	Arrival uint64
+}
+
+// ForkChoice returns true if the chain ending with block a is better than the chain ending with block b
+type ForkChoice func(a, b *BlockInfo) bool
+
+// LongestChain is the fork choice rule which prefers the chain with the highest block number;
+// if two chains are the same length, the one which was seen first is preferred
+func LongestChain(a, b *BlockInfo) bool {
+	cmp := a.Number.Cmp(b.Number)
+	if cmp != 0 {
+		return cmp > 0
+	}
+	return a.Arrival < b.Arrival
+}
+
+// node is a block in the tree
+type node struct {
+	info     *BlockInfo
+	parent   *node
+	children []*node
+}
+
+// BlockTree tracks the blocks descending from the last finalized block, including competing forks
+type BlockTree struct {
+	root       *node
+	nodes      map[common.Hash]*node
+	leaves     map[common.Hash]*node
+	forkChoice ForkChoice
+	arrival    uint64
+	lock       sync.RWMutex
+}
+
+// NewBlockTreeFromRoot returns a block tree with the given header as its root, usually the genesis or last finalized block
+// if forkChoice is nil, LongestChain is used
+func NewBlockTreeFromRoot(root *types.Header, forkChoice ForkChoice) (*BlockTree, error) {
+	hash, err := root.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if forkChoice == nil {
+		forkChoice = LongestChain
+	}
+
+	n := &node{
+		info: &BlockInfo{
+			Hash:   hash,
+			Number: new(big.Int).Set(root.Number),
+		},
+	}
+
+	return &BlockTree{
+		root:       n,
+		nodes:      map[common.Hash]*node{hash: n},
+		leaves:     map[common.Hash]*node{hash: n},
+		forkChoice: forkChoice,
+	}, nil
+}
+
+// AddBlock adds the block with the given header to the tree, returning its hash
+// the block's parent must already be in the tree
+func (bt *BlockTree) AddBlock(header *types.Header) (common.Hash, error) {
+	hash, err := header.Hash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	bt.lock.Lock()
+	defer bt.lock.Unlock()
+
+	if _, ok := bt.nodes[hash]; ok {
+		return [32]byte{}, ErrBlockExists
+	}
+
+	parent, ok := bt.nodes[header.ParentHash]
+	if !ok {
+		return [32]byte{}, ErrParentNotFound
+	}
+
+	expected := new(big.Int).Add(parent.info.Number, big.NewInt(1))
+	if header.Number == nil || header.Number.Cmp(expected) != 0 {
+		return [32]byte{}, fmt.Errorf("invalid block number %v: parent has number %v", header.Number, parent.info.Number)
+	}
+
+	bt.arrival++
+	n := &node{
+		info: &BlockInfo{
+			Hash:    hash,
+			Number:  expected,
+			Arrival: bt.arrival,
+		},
+		parent: parent,
+	}
+
+	parent.children = append(parent.children, n)
+	bt.nodes[hash] = n
+	delete(bt.leaves, parent.info.Hash)
+	bt.leaves[hash] = n
+
+	return hash, nil
+}
+
+// Has returns true if the block with the given hash is in the tree
+func (bt *BlockTree) Has(hash common.Hash) bool {
+	bt.lock.RLock()
+	defer bt.lock.RUnlock()
+
+	_, ok := bt.nodes[hash]
+	return ok
+}
+
+// Get returns information about the block with the given hash
+func (bt *BlockTree) Get(hash common.Hash) (*BlockInfo, error) {
+	bt.lock.RLock()
+	defer bt.lock.RUnlock()
+
+	n, ok := bt.nodes[hash]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+
+	info := *n.info
+	return &info, nil
+}
+
+// FinalizedHash returns the hash of the root of the tree, the last finalized block
+func (bt *BlockTree) FinalizedHash() common.Hash {
+	bt.lock.RLock()
+	defer bt.lock.RUnlock()
+
+	return bt.root.info.Hash
+}
+
+// Leaves returns the hashes of the blocks with no children, in the order they were added to the tree
+func (bt *BlockTree) Leaves() []common.Hash {
+	bt.lock.RLock()
+	defer bt.lock.RUnlock()
+
+	leaves := make([]*node, 0, len(bt.leaves))
+	for _, n := range bt.leaves {
+		leaves = append(leaves, n)
+	}
+
+	sort.Slice(leaves, func(i, j int) bool {
+		return leaves[i].info.Arrival < leaves[j].info.Arrival
+	})
+
+	hashes := make([]common.Hash, len(leaves))
+	for i, n := range leaves {
+		hashes[i] = n.info.Hash
+	}
+	return hashes
+}
+
+// BestBlockHash returns the hash of the head of the best chain, chosen from the leaves by the fork choice rule
+func (bt *BlockTree) BestBlockHash() common.Hash {
+	bt.lock.RLock()
+	defer bt.lock.RUnlock()
+
+	var best *node
+	for _, n := range bt.leaves {
+		if best == nil || bt.forkChoice(n.info, best.info) {
+			best = n
+		}
+	}
+
+	return best.info.Hash
+}
+
+// IsDescendantOf returns true if the block with hash descendant is the block with hash ancestor, or one of its descendants
+func (bt *BlockTree) IsDescendantOf(ancestor, descendant common.Hash) (bool, error) {
+	bt.lock.RLock()
+	defer bt.lock.RUnlock()
+
+	a, ok := bt.nodes[ancestor]
+	if !ok {
+		return false, ErrNodeNotFound
+	}
+
+	d, ok := bt.nodes[descendant]
+	if !ok {
+		return false, ErrNodeNotFound
+	}
+
+	for d != nil && d.info.Number.Cmp(a.info.Number) > 0 {
+		d = d.parent
+	}
+
+	return d == a, nil
+}
+
+// HighestCommonAncestor returns the hash of the highest block which both of the given blocks descend from
+func (bt *BlockTree) HighestCommonAncestor(a, b common.Hash) (common.Hash, error) {
+	bt.lock.RLock()
+	defer bt.lock.RUnlock()
+
+	an, ok := bt.nodes[a]
+	if !ok {
+		return [32]byte{}, ErrNodeNotFound
+	}
+
+	bn, ok := bt.nodes[b]
+	if !ok {
+		return [32]byte{}, ErrNodeNotFound
+	}
+
+	for an.info.Number.Cmp(bn.info.Number) > 0 {
+		an = an.parent
+	}
+
+	for bn.info.Number.Cmp(an.info.Number) > 0 {
+		bn = bn.parent
+	}
+
+	// every block in the tree descends from the root, so this always ends
+	for an != bn {
+		an = an.parent
+		bn = bn.parent
+	}
+
+	return an.info.Hash, nil
+}
+
+// Finalize makes the block with the given hash the root of the tree
+// the blocks on forks abandoned by finalization, which are neither ancestors nor descendants of the finalized block,
+// are removed from the tree and their hashes are returned so that they can be removed from storage. the finalized
+// block's ancestors are removed from the tree too, but they're part of the finalized chain so they aren't returned
+func (bt *BlockTree) Finalize(hash common.Hash) ([]common.Hash, error) {
+	bt.lock.Lock()
+	defer bt.lock.Unlock()
+
+	n, ok := bt.nodes[hash]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+
+	keep := make(map[common.Hash]bool)
+	n.walk(func(d *node) {
+		keep[d.info.Hash] = true
+	})
+
+	ancestors := make(map[common.Hash]bool)
+	for p := n.parent; p != nil; p = p.parent {
+		ancestors[p.info.Hash] = true
+	}
+
+	pruned := []common.Hash{}
+	bt.root.walk(func(d *node) {
+		if keep[d.info.Hash] {
+			return
+		}
+
+		delete(bt.nodes, d.info.Hash)
+		delete(bt.leaves, d.info.Hash)
+		if !ancestors[d.info.Hash] {
+			pruned = append(pruned, d.info.Hash)
+		}
+	})
+
+	n.parent = nil
+	bt.root = n
+
+	return pruned, nil
+}
+
+// walk calls f with the node and each of its descendants, parents before children
+func (n *node) walk(f func(*node)) {
+	f(n)
+	for _, child := range n.children {
+		child.walk(f)
+	}
+}
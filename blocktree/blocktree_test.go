@@ -0,0 +1,300 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package blocktree
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/core/types"
+)
+
+var testGenesisHeader = &types.Header{
+	ParentHash: common.Hash{},
+	Number:     big.NewInt(0),
+	Digest:     types.Digest{},
+}
+
+// addBlocks adds a chain of depth blocks to the tree, starting from the block with the given parent hash
+// the digest is used to make the blocks on different forks distinct
+func addBlocks(t *testing.T, bt *BlockTree, parent common.Hash, depth int, digest byte) []common.Hash {
+	info, err := bt.Get(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := []common.Hash{}
+	number := info.Number
+	for i := 0; i < depth; i++ {
+		number = new(big.Int).Add(number, big.NewInt(1))
+		hash, err := bt.AddBlock(&types.Header{
+			ParentHash: parent,
+			Number:     number,
+			Digest:     types.Digest{&types.OtherDigest{Data: []byte{digest}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashes = append(hashes, hash)
+		parent = hash
+	}
+
+	return hashes
+}
+
+// createTestTree returns the tree
+//
+//	genesis - a1 - a2 - a3 - a4
+//	             \ b2 - b3
+//	                  \ c3
+func createTestTree(t *testing.T) (*BlockTree, common.Hash, []common.Hash, []common.Hash, []common.Hash) {
+	bt, err := NewBlockTreeFromRoot(testGenesisHeader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis := bt.FinalizedHash()
+	a := addBlocks(t, bt, genesis, 4, 0)
+	b := addBlocks(t, bt, a[0], 2, 1)
+	c := addBlocks(t, bt, b[0], 1, 2)
+	return bt, genesis, a, b, c
+}
+
+func TestBlockTree_AddBlock(t *testing.T) {
+	bt, genesis, a, _, _ := createTestTree(t)
+
+	_, err := bt.AddBlock(&types.Header{
+		ParentHash: genesis,
+		Number:     big.NewInt(1),
+		Digest:     types.Digest{&types.OtherDigest{Data: []byte{0}}},
+	})
+	if err != ErrBlockExists {
+		t.Fatalf("Fail: got error %v expected %v", err, ErrBlockExists)
+	}
+
+	_, err = bt.AddBlock(&types.Header{
+		ParentHash: common.Hash{0xff},
+		Number:     big.NewInt(1),
+	})
+	if err != ErrParentNotFound {
+		t.Fatalf("Fail: got error %v expected %v", err, ErrParentNotFound)
+	}
+
+	_, err = bt.AddBlock(&types.Header{
+		ParentHash: a[0],
+		Number:     big.NewInt(7),
+	})
+	if err == nil {
+		t.Fatal("Fail: expected error for invalid block number")
+	}
+
+	if !bt.Has(a[3]) {
+		t.Fatalf("Fail: block %x should be in tree", a[3])
+	}
+}
+
+func TestBlockTree_Leaves(t *testing.T) {
+	bt, _, a, b, c := createTestTree(t)
+
+	expected := []common.Hash{a[3], b[1], c[0]}
+	leaves := bt.Leaves()
+	if len(leaves) != len(expected) {
+		t.Fatalf("Fail: got %d leaves expected %d", len(leaves), len(expected))
+	}
+
+	for i, leaf := range leaves {
+		if leaf != expected[i] {
+			t.Errorf("Fail: got leaf %x expected %x", leaf, expected[i])
+		}
+	}
+}
+
+func TestBlockTree_BestBlockHash(t *testing.T) {
+	bt, _, a, b, _ := createTestTree(t)
+
+	if bt.BestBlockHash() != a[3] {
+		t.Fatalf("Fail: got best block %x expected %x", bt.BestBlockHash(), a[3])
+	}
+
+	// b becomes the same length as a, but a was seen first
+	b = append(b, addBlocks(t, bt, b[1], 1, 1)...)
+	if bt.BestBlockHash() != a[3] {
+		t.Fatalf("Fail: got best block %x expected %x", bt.BestBlockHash(), a[3])
+	}
+
+	b = append(b, addBlocks(t, bt, b[2], 1, 1)...)
+	if bt.BestBlockHash() != b[3] {
+		t.Fatalf("Fail: got best block %x expected %x", bt.BestBlockHash(), b[3])
+	}
+
+	// a custom fork choice rule which prefers the most recent block
+	latest := func(x, y *BlockInfo) bool {
+		return x.Arrival > y.Arrival
+	}
+
+	bt, err := NewBlockTreeFromRoot(testGenesisHeader, latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := addBlocks(t, bt, bt.FinalizedHash(), 3, 0)
+	short := addBlocks(t, bt, bt.FinalizedHash(), 1, 1)
+	if bt.BestBlockHash() != short[0] {
+		t.Fatalf("Fail: got best block %x expected %x", bt.BestBlockHash(), short[0])
+	}
+
+	if bt.BestBlockHash() == long[2] {
+		t.Fatal("Fail: fork choice rule was not used")
+	}
+}
+
+func TestBlockTree_IsDescendantOf(t *testing.T) {
+	bt, genesis, a, b, c := createTestTree(t)
+
+	tests := []struct {
+		ancestor   common.Hash
+		descendant common.Hash
+		expected   bool
+	}{
+		{genesis, a[3], true},
+		{a[0], c[0], true},
+		{b[0], c[0], true},
+		{a[1], c[0], false},
+		{c[0], b[0], false},
+		{b[1], b[1], true},
+		{a[3], genesis, false},
+	}
+
+	for _, test := range tests {
+		res, err := bt.IsDescendantOf(test.ancestor, test.descendant)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res != test.expected {
+			t.Errorf("Fail: IsDescendantOf(%x, %x) got %t expected %t", test.ancestor, test.descendant, res, test.expected)
+		}
+	}
+
+	_, err := bt.IsDescendantOf(genesis, common.Hash{0xff})
+	if err != ErrNodeNotFound {
+		t.Fatalf("Fail: got error %v expected %v", err, ErrNodeNotFound)
+	}
+}
+
+func TestBlockTree_HighestCommonAncestor(t *testing.T) {
+	bt, genesis, a, b, c := createTestTree(t)
+
+	tests := []struct {
+		a        common.Hash
+		b        common.Hash
+		expected common.Hash
+	}{
+		{a[3], b[1], a[0]},
+		{b[1], c[0], b[0]},
+		{a[3], a[1], a[1]},
+		{genesis, c[0], genesis},
+		{c[0], c[0], c[0]},
+	}
+
+	for _, test := range tests {
+		res, err := bt.HighestCommonAncestor(test.a, test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res != test.expected {
+			t.Errorf("Fail: HighestCommonAncestor(%x, %x) got %x expected %x", test.a, test.b, res, test.expected)
+		}
+	}
+}
+
+func TestBlockTree_Finalize(t *testing.T) {
+	bt, genesis, a, b, c := createTestTree(t)
+
+	pruned, err := bt.Finalize(b[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the abandoned fork after a[0] is pruned, not the finalized block's ancestors
+	expected := map[common.Hash]bool{a[1]: true, a[2]: true, a[3]: true}
+	if len(pruned) != len(expected) {
+		t.Fatalf("Fail: got %d pruned blocks expected %d", len(pruned), len(expected))
+	}
+
+	for _, hash := range pruned {
+		if !expected[hash] {
+			t.Errorf("Fail: block %x should not have been pruned", hash)
+		}
+
+		if bt.Has(hash) {
+			t.Errorf("Fail: pruned block %x is still in tree", hash)
+		}
+	}
+
+	if bt.FinalizedHash() != b[0] {
+		t.Fatalf("Fail: got finalized block %x expected %x", bt.FinalizedHash(), b[0])
+	}
+
+	// the ancestors are no longer in the tree either, since it's rooted at the finalized block
+	for _, hash := range []common.Hash{genesis, a[0]} {
+		if bt.Has(hash) {
+			t.Errorf("Fail: ancestor %x of finalized block is still in tree", hash)
+		}
+	}
+
+	leaves := bt.Leaves()
+	if len(leaves) != 2 || leaves[0] != b[1] || leaves[1] != c[0] {
+		t.Fatalf("Fail: got leaves %x expected %x", leaves, []common.Hash{b[1], c[0]})
+	}
+
+	if bt.BestBlockHash() != b[1] {
+		t.Fatalf("Fail: got best block %x expected %x", bt.BestBlockHash(), b[1])
+	}
+
+	_, err = bt.AddBlock(&types.Header{
+		ParentHash: a[3],
+		Number:     big.NewInt(5),
+	})
+	if err != ErrParentNotFound {
+		t.Fatalf("Fail: got error %v expected %v", err, ErrParentNotFound)
+	}
+}
+
+func TestBlockTree_Finalize_Ancestors(t *testing.T) {
+	bt, _, a, b, c := createTestTree(t)
+
+	// finalizing a block further down the fork with the most ancestors only prunes the other fork
+	pruned, err := bt.Finalize(a[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[common.Hash]bool{b[0]: true, b[1]: true, c[0]: true}
+	if len(pruned) != len(expected) {
+		t.Fatalf("Fail: got %d pruned blocks expected %d", len(pruned), len(expected))
+	}
+
+	for _, hash := range pruned {
+		if !expected[hash] {
+			t.Errorf("Fail: block %x should not have been pruned", hash)
+		}
+	}
+}
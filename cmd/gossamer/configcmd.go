@@ -25,13 +25,17 @@ import (
 
 	"github.com/ChainSafe/gossamer/cmd/utils"
 	cfg "github.com/ChainSafe/gossamer/config"
+	"github.com/ChainSafe/gossamer/core/types"
 	"github.com/ChainSafe/gossamer/dot"
+	"github.com/ChainSafe/gossamer/genesis"
 	"github.com/ChainSafe/gossamer/internal/api"
 	"github.com/ChainSafe/gossamer/internal/services"
 	"github.com/ChainSafe/gossamer/p2p"
 	"github.com/ChainSafe/gossamer/polkadb"
 	"github.com/ChainSafe/gossamer/rpc"
 	"github.com/ChainSafe/gossamer/rpc/json2"
+	"github.com/ChainSafe/gossamer/runtime"
+	"github.com/ChainSafe/gossamer/trie"
 	log "github.com/ChainSafe/log15"
 	"github.com/naoina/toml"
 	"github.com/urfave/cli"
@@ -64,8 +68,15 @@ func makeNode(ctx *cli.Context) (*dot.Dot, *cfg.Config, error) {
 
 	var srvcs []services.Service
 
+	// Genesis
+	gen, err := getGenesis(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// P2P
 	setBootstrapNodes(ctx, fig.P2pCfg)
+	setGenesisBootstrapNodes(ctx, fig.P2pCfg, gen)
 	p2pSrvc := createP2PService(fig.P2pCfg)
 	srvcs = append(srvcs, p2pSrvc)
 
@@ -77,8 +88,48 @@ func makeNode(ctx *cli.Context) (*dot.Dot, *cfg.Config, error) {
 	}
 	srvcs = append(srvcs, dbSrvc)
 
+	setPruning(ctx, fig.DbCfg)
+	tdb, err := createTrieDatabase(fig.DbCfg, dbSrvc)
+	if err != nil {
+		log.Error("error creating trie database", "err", err)
+		return nil, nil, err
+	}
+
+	var state *trie.Trie
+	if gen != nil {
+		var header *types.Header
+		state, header, err = genesis.Initialize(tdb, gen)
+		if err != nil {
+			log.Error("error loading state", "err", err)
+			return nil, nil, err
+		}
+		log.Info("loaded state", "chain", gen.Name, "block", header.Number, "state root", header.StateRoot)
+	} else {
+		// without a chain specification, the node carries on from the state stored by a previous run, if any
+		var header *types.Header
+		state, header, err = genesis.LoadBestState(tdb)
+		if err != nil && err != polkadb.ErrNotFound {
+			log.Error("error loading state", "err", err)
+			return nil, nil, err
+		}
+		if err == nil {
+			log.Info("loaded state", "block", header.Number, "state root", header.StateRoot)
+		}
+	}
+
+	// Runtime
+	var rt api.RuntimeApi
+	if state != nil {
+		manager, err := createRuntimeManager(fig.RuntimeCfg, state)
+		if err != nil {
+			log.Error("error loading runtime", "err", err)
+			return nil, nil, err
+		}
+		rt = manager
+	}
+
 	// API
-	apiSrvc := api.NewApiService(p2pSrvc, nil)
+	apiSrvc := api.NewApiService(p2pSrvc, rt)
 	srvcs = append(srvcs, apiSrvc)
 
 	// RPC
@@ -140,6 +191,21 @@ func loadConfig(file string) (*cfg.Config, error) {
 	return config, err
 }
 
+// getGenesis loads the chain specification if the --chain flag is specified
+func getGenesis(ctx *cli.Context) (*genesis.Genesis, error) {
+	file := ctx.GlobalString(utils.ChainFlag.Name)
+	if file == "" {
+		return nil, nil
+	}
+
+	gen, err := genesis.LoadGenesisJSONFile(file)
+	if err != nil {
+		log.Error("error loading chain specification", "file", file, "err", err)
+		return nil, err
+	}
+	return gen, nil
+}
+
 // getDatabaseDir initializes directory for BadgerService logs
 func getDatabaseDir(ctx *cli.Context, fig *cfg.Config) string {
 	if file := ctx.GlobalString(utils.DataDirFlag.Name); file != "" {
@@ -152,6 +218,48 @@ func getDatabaseDir(ctx *cli.Context, fig *cfg.Config) string {
 	}
 }
 
+// setPruning checks the context for a pruning mode and applies it to `fig`, unless one is already set
+func setPruning(ctx *cli.Context, fig *polkadb.Config) {
+	if mode := ctx.GlobalString(utils.PruningFlag.Name); mode != "" {
+		fig.Pruning = mode
+		return
+	} else if fig.Pruning != "" {
+		return
+	} else {
+		fig.Pruning = cfg.DefaultPruning
+	}
+}
+
+// createTrieDatabase returns the database state tries are stored in, pruning the nodes of old state roots as
+// configured
+func createTrieDatabase(fig *polkadb.Config, db polkadb.Database) (*trie.Database, error) {
+	retain, err := trie.ParseRetain(fig.Pruning)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := trie.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	tdb := &trie.Database{
+		Db:     db,
+		Hasher: hasher,
+	}
+
+	// without a pruner, nothing is ever removed from the database
+	if retain != trie.Archive {
+		tdb.Pruner, err = trie.NewPruner(db, retain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("state pruning", "mode", fig.Pruning)
+	return tdb, nil
+}
+
 // createP2PService starts a p2p network layer from provided config
 func createP2PService(fig *p2p.Config) *p2p.Service {
 	srvc, err := p2p.NewService(fig)
@@ -161,6 +269,22 @@ func createP2PService(fig *p2p.Config) *p2p.Service {
 	return srvc
 }
 
+// createRuntimeManager loads the runtime stored in the state with the configured executor
+func createRuntimeManager(fig *runtime.Config, state *trie.Trie) (*runtime.Manager, error) {
+	// the default executor is used if the config file has no runtime section
+	executor, err := runtime.NewExecutorFromConfig(fig)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := runtime.NewModuleCache(executor, runtime.DefaultModuleCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewManager(cache, state)
+}
+
 // setBootstrapNodes creates a list of bootstrap nodes from the command line
 // flags, reverting to pre-configured ones if none have been specified.
 func setBootstrapNodes(ctx *cli.Context, fig *p2p.Config) {
@@ -177,6 +301,15 @@ func setBootstrapNodes(ctx *cli.Context, fig *p2p.Config) {
 	}
 }
 
+// setGenesisBootstrapNodes uses the boot nodes from the chain specification, unless some have been specified
+// on the command line
+func setGenesisBootstrapNodes(ctx *cli.Context, fig *p2p.Config, gen *genesis.Genesis) {
+	if gen == nil || len(gen.Bootnodes) == 0 || ctx.GlobalString(utils.BootnodesFlag.Name) != "" {
+		return
+	}
+	fig.BootstrapNodes = gen.Bootnodes
+}
+
 // setRpcModules checks the context for rpc modes and applies them to `cfg`, unless some are already set
 func setRpcModules(ctx *cli.Context, fig *rpc.Config) {
 	var strs []string
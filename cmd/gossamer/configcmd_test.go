@@ -6,10 +6,12 @@ import (
 
 	cfg "github.com/ChainSafe/gossamer/config"
 	"github.com/ChainSafe/gossamer/dot"
+	"github.com/ChainSafe/gossamer/genesis"
 	"github.com/ChainSafe/gossamer/internal/api"
 	"github.com/ChainSafe/gossamer/internal/services"
 	"github.com/ChainSafe/gossamer/p2p"
 	"github.com/ChainSafe/gossamer/rpc"
+	"github.com/ChainSafe/gossamer/trie"
 
 	"flag"
 	"fmt"
@@ -175,6 +177,36 @@ func TestSetBootstrapNodes(t *testing.T) {
 	}
 }
 
+func TestSetGenesisBootstrapNodes(t *testing.T) {
+	gen := &genesis.Genesis{
+		Bootnodes: []string{"/ip4/127.0.0.1/tcp/7001/p2p/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ"},
+	}
+
+	tc := []struct {
+		name     string
+		value    string
+		usage    string
+		expected []string
+	}{
+		{"chain", "genesis.json", "JSON chain specification file used to initialize the genesis state", gen.Bootnodes},
+		{"bootnodes", "test1", "Comma separated enode URLs for P2P discovery bootstrap", []string{"test1"}},
+	}
+
+	for _, c := range tc {
+		fig := &p2p.Config{}
+		set := flag.NewFlagSet(c.name, 0)
+		set.String(c.name, c.value, c.usage)
+		context := cli.NewContext(nil, set, nil)
+
+		setBootstrapNodes(context, fig)
+		setGenesisBootstrapNodes(context, fig, gen)
+
+		if !reflect.DeepEqual(fig.BootstrapNodes, c.expected) {
+			t.Fatalf("test failed: %v, got %+v expected %+v", c.name, fig.BootstrapNodes, c.expected)
+		}
+	}
+}
+
 func TestSetRpcModules(t *testing.T) {
 	tempFile, cfgClone := createTempConfigFile()
 
@@ -236,6 +268,63 @@ func TestSetRpcHost(t *testing.T) {
 	}
 }
 
+func TestSetPruning(t *testing.T) {
+	tempFile, cfgClone := createTempConfigFile()
+	defer teardown(tempFile)
+
+	tc := []struct {
+		name     string
+		value    string
+		usage    string
+		expected string
+	}{
+		{"", "", "", cfg.DefaultPruning},
+		{"config", tempFile.Name(), "TOML configuration file", "64"},
+		{"pruning", "256", "Number of recent state roots to keep the trie nodes of", "256"},
+	}
+
+	for i, c := range tc {
+		set := flag.NewFlagSet(c.name, 0)
+		set.String(c.name, c.value, c.usage)
+		context := cli.NewContext(nil, set, nil)
+		if i == 0 {
+			cfgClone.DbCfg.Pruning = ""
+		} else {
+			cfgClone.DbCfg.Pruning = "64"
+		}
+		setPruning(context, cfgClone.DbCfg)
+
+		if cfgClone.DbCfg.Pruning != c.expected {
+			t.Fatalf("test failed: %v, got %+v expected %+v", c.name, cfgClone.DbCfg.Pruning, c.expected)
+		}
+	}
+}
+
+func TestCreateTrieDatabase(t *testing.T) {
+	db := polkadb.NewMemDatabase()
+
+	tdb, err := createTrieDatabase(&polkadb.Config{Pruning: trie.ArchiveMode}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tdb.Db != db || tdb.Hasher == nil || tdb.Pruner != nil {
+		t.Fatalf("test failed: archive mode should not prune, got %+v", tdb)
+	}
+
+	tdb, err = createTrieDatabase(&polkadb.Config{Pruning: "16"}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tdb.Pruner == nil || tdb.Pruner.Retain() != 16 {
+		t.Fatalf("test failed: expected pruner retaining 16 roots, got %+v", tdb.Pruner)
+	}
+
+	_, err = createTrieDatabase(&polkadb.Config{Pruning: "noot"}, db)
+	if err == nil {
+		t.Fatal("test failed: expected error for invalid pruning mode")
+	}
+}
+
 func TestStrToMods(t *testing.T) {
 	strs := []string{"test1", "test2"}
 	mods := strToMods(strs)
@@ -28,6 +28,8 @@ var (
 	app       = cli.NewApp()
 	nodeFlags = []cli.Flag{
 		utils.DataDirFlag,
+		utils.PruningFlag,
+		utils.ChainFlag,
 		configFileFlag,
 	}
 	rpcFlags = []cli.Flag{
@@ -49,6 +51,7 @@ func init() {
 	app.Version = "0.0.1"
 	app.Commands = []cli.Command{
 		dumpConfigCommand,
+		stateCommand,
 	}
 	app.Flags = append(app.Flags, nodeFlags...)
 	app.Flags = append(app.Flags, rpcFlags...)
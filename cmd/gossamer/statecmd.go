@@ -0,0 +1,228 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/polkadb"
+	"github.com/ChainSafe/gossamer/trie"
+	log "github.com/ChainSafe/log15"
+	"github.com/urfave/cli"
+)
+
+var (
+	stateCommand = cli.Command{
+		Name:        "state",
+		Usage:       "Inspect the state stored in the database",
+		Category:    "STATE DEBUGGING",
+		Description: `The state command contains subcommands for inspecting the state tries stored in the database.`,
+		Subcommands: []cli.Command{
+			stateDiffCommand,
+			stateExportCommand,
+			stateImportCommand,
+		},
+	}
+
+	stateDiffCommand = cli.Command{
+		Action:    stateDiff,
+		Name:      "diff",
+		Usage:     "Show the keys that differ between two state roots",
+		ArgsUsage: "<root> <root>",
+		Flags:     nodeFlags,
+		Description: `The state diff command compares the state tries with the two given roots and prints every key that was
+added (+), removed (-) or changed (~) going from the first root to the second.`,
+	}
+
+	stateExportCommand = cli.Command{
+		Action:    stateExport,
+		Name:      "export",
+		Usage:     "Export the state with the given root to a file",
+		ArgsUsage: "<root> <file>",
+		Flags:     nodeFlags,
+		Description: `The state export command writes every key-value pair in the state trie with the given root, along with
+the root itself, to a compressed snapshot file which can be loaded with the state import command.`,
+	}
+
+	stateImportCommand = cli.Command{
+		Action:    stateImport,
+		Name:      "import",
+		Usage:     "Import the state from a file",
+		ArgsUsage: "<file>",
+		Flags:     nodeFlags,
+		Description: `The state import command rebuilds the state trie from a snapshot file written by the state export command,
+checks that its root matches the root recorded in the file and writes it to the database.`,
+	}
+)
+
+// openTrieDatabase opens the database in the configured data directory for reading tries
+func openTrieDatabase(ctx *cli.Context) (*polkadb.BadgerService, *trie.Database, error) {
+	fig, err := getConfig(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := polkadb.NewBadgerService(getDatabaseDir(ctx, fig))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasher, err := trie.NewHasher()
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, &trie.Database{
+		Db:     db,
+		Hasher: hasher,
+	}, nil
+}
+
+// parseRoot parses a 0x prefixed state root
+func parseRoot(in string) (common.Hash, error) {
+	if !strings.HasPrefix(in, "0x") || len(in) != 66 {
+		return [32]byte{}, fmt.Errorf("invalid state root %s: expected 0x prefixed 32 byte hex string", in)
+	}
+	return common.HexToHash(in)
+}
+
+// stateDiff is the state diff command.
+func stateDiff(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("state diff requires two state roots")
+	}
+
+	a, err := parseRoot(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	b, err := parseRoot(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	db, tdb, err := openTrieDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	diffs, err := trie.DiffRoots(tdb, a, b)
+	if err != nil {
+		log.Error("could not diff state roots", "err", err)
+		return err
+	}
+
+	for _, d := range diffs {
+		switch {
+		case d.Added():
+			_, err = fmt.Fprintf(ctx.App.Writer, "+ 0x%x 0x%x\n", d.Key, d.NewValue)
+		case d.Removed():
+			_, err = fmt.Fprintf(ctx.App.Writer, "- 0x%x 0x%x\n", d.Key, d.OldValue)
+		default:
+			_, err = fmt.Fprintf(ctx.App.Writer, "~ 0x%x 0x%x -> 0x%x\n", d.Key, d.OldValue, d.NewValue)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stateExport is the state export command.
+func stateExport(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("state export requires a state root and a file")
+	}
+
+	root, err := parseRoot(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	db, tdb, err := openTrieDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	t, err := trie.LoadFromDB(tdb, root)
+	if err != nil {
+		log.Error("could not load state root", "root", root, "err", err)
+		return err
+	}
+
+	f, err := os.Create(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	err = t.Export(f)
+	if err != nil {
+		_ = f.Close()
+		log.Error("could not export state", "err", err)
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	log.Info("exported state", "root", root, "file", ctx.Args().Get(1))
+	return nil
+}
+
+// stateImport is the state import command.
+func stateImport(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("state import requires a file")
+	}
+
+	f, err := os.Open(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	db, tdb, err := openTrieDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	t, err := trie.Import(tdb, f)
+	if err != nil {
+		log.Error("could not import state", "err", err)
+		return err
+	}
+
+	root, err := t.Hash()
+	if err != nil {
+		return err
+	}
+
+	log.Info("imported state", "root", root, "file", ctx.Args().Get(0))
+	return nil
+}
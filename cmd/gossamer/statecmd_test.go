@@ -0,0 +1,205 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/polkadb"
+	"github.com/ChainSafe/gossamer/trie"
+	"github.com/urfave/cli"
+)
+
+// writeTrie writes the trie to the database and returns its root
+func writeTrie(t *testing.T, tr *trie.Trie) common.Hash {
+	err := tr.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tr.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := tr.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestStateDiff(t *testing.T) {
+	dataDir := "./state_diff_test"
+	defer func() {
+		if err := os.RemoveAll(dataDir); err != nil {
+			t.Log("removal of temp directory state_diff_test failed", err)
+		}
+	}()
+
+	db, err := polkadb.NewBadgerService(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasher, err := trie.NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := trie.NewEmptyTrie(&trie.Database{Db: db, Hasher: hasher})
+	for k, v := range map[string][]byte{"noot": []byte("was here"), "other": {1}} {
+		err = a.Put([]byte(k), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	aRoot := writeTrie(t, a)
+
+	b := a.Snapshot()
+	err = b.Put([]byte("noot"), []byte("is here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Delete([]byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Put([]byte("new"), []byte{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bRoot := writeTrie(t, b)
+
+	db.Close()
+
+	app := cli.NewApp()
+	out := new(bytes.Buffer)
+	app.Writer = out
+
+	set := flag.NewFlagSet("datadir", 0)
+	set.String("datadir", dataDir, "sets database directory")
+	err = set.Parse([]string{fmt.Sprintf("0x%x", aRoot), fmt.Sprintf("0x%x", bRoot)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := cli.NewContext(app, set, nil)
+	err = stateDiff(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintf("+ 0x%x 0x02\n~ 0x%x 0x%x -> 0x%x\n- 0x%x 0x01\n",
+		"new", "noot", "was here", "is here", "other")
+	if out.String() != expected {
+		t.Fatalf("Fail: got output\n%s\nexpected\n%s", out.String(), expected)
+	}
+
+	set = flag.NewFlagSet("datadir", 0)
+	set.String("datadir", dataDir, "sets database directory")
+	err = set.Parse([]string{"0x1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stateDiff(cli.NewContext(app, set, nil))
+	if err == nil {
+		t.Fatal("Fail: expected error for invalid arguments")
+	}
+}
+
+func TestStateExportImport(t *testing.T) {
+	dataDir := "./state_export_test"
+	importDir := "./state_import_test"
+	file := "./state_export_test.snapshot"
+	defer func() {
+		for _, p := range []string{dataDir, importDir, file} {
+			if err := os.RemoveAll(p); err != nil {
+				t.Log("removal of", p, "failed", err)
+			}
+		}
+	}()
+
+	db, err := polkadb.NewBadgerService(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasher, err := trie.NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := trie.NewEmptyTrie(&trie.Database{Db: db, Hasher: hasher})
+	err = tr.Put([]byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := writeTrie(t, tr)
+
+	db.Close()
+
+	app := cli.NewApp()
+	app.Writer = new(bytes.Buffer)
+
+	set := flag.NewFlagSet("datadir", 0)
+	set.String("datadir", dataDir, "sets database directory")
+	err = set.Parse([]string{fmt.Sprintf("0x%x", root), file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stateExport(cli.NewContext(app, set, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set = flag.NewFlagSet("datadir", 0)
+	set.String("datadir", importDir, "sets database directory")
+	err = set.Parse([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stateImport(cli.NewContext(app, set, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = polkadb.NewBadgerService(importDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	res, err := trie.LoadFromDB(&trie.Database{Db: db, Hasher: hasher}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := res.Get([]byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("was here")) {
+		t.Fatalf("Fail: got %x expected %x", val, []byte("was here"))
+	}
+}
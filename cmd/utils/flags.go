@@ -28,6 +28,18 @@ var (
 		Usage: "Data directory for the database",
 		Value: cfg.DefaultDataDir(),
 	}
+	// State pruning
+	PruningFlag = cli.StringFlag{
+		Name:  "pruning",
+		Usage: "Number of recent state roots to keep the trie nodes of, or \"archive\" to keep every node",
+		Value: "",
+	}
+	// Chain specification
+	ChainFlag = cli.StringFlag{
+		Name:  "chain",
+		Usage: "JSON chain specification file used to initialize the genesis state",
+		Value: "",
+	}
 	// RPC settings
 	RpcEnabledFlag = cli.BoolFlag{
 		Name:  "rpc",
@@ -197,8 +197,8 @@ func (se *Encoder) encodeBigInteger(i *big.Int) (bytesEncoded int, err error) {
 	// write byte which encodes mode and length
 	err = binary.Write(se.Writer, binary.LittleEndian, lengthByte)
 	if err == nil {
-		// write integer itself
-		err = binary.Write(se.Writer, binary.LittleEndian, i.Bytes())
+		// write integer itself, least significant byte first
+		err = binary.Write(se.Writer, binary.LittleEndian, reverseBytes(i.Bytes()))
 	}
 
 	return numBytes + 1, err
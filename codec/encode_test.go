@@ -58,6 +58,8 @@ var encodeTests = []encodeTest{
 	{val: big.NewInt(16384), output: []byte{0x02, 0x00, 0x01, 0x00}, bytesEncoded: 4},
 	{val: big.NewInt(1073741823), output: []byte{0xfe, 0xff, 0xff, 0xff}, bytesEncoded: 4},
 	{val: big.NewInt(1<<32 - 1), output: []byte{0x03, 0xff, 0xff, 0xff, 0xff}, bytesEncoded: 5},
+	{val: big.NewInt(1073741824), output: []byte{0x03, 0x00, 0x00, 0x00, 0x40}, bytesEncoded: 5},
+	{val: big.NewInt(1<<32 + 2), output: []byte{0x07, 0x02, 0x00, 0x00, 0x00, 0x01}, bytesEncoded: 6},
 
 	// byte arrays
 	{val: []byte{0x01}, output: []byte{0x04, 0x01}, bytesEncoded: 2},
@@ -26,6 +26,8 @@ import (
 	"github.com/ChainSafe/gossamer/p2p"
 	"github.com/ChainSafe/gossamer/polkadb"
 	"github.com/ChainSafe/gossamer/rpc"
+	gossamerruntime "github.com/ChainSafe/gossamer/runtime"
+	"github.com/ChainSafe/gossamer/trie"
 )
 
 const (
@@ -35,6 +37,9 @@ const (
 	// P2P
 	DefaultP2PPort     = 7001
 	DefaultP2PRandSeed = int64(33)
+
+	// DB
+	DefaultPruning = trie.ArchiveMode // Keep every state trie node by default
 )
 
 var DefaultP2PBootstrap = []string{
@@ -55,6 +60,7 @@ var (
 	// DB
 	DefaultDBConfig = &polkadb.Config{
 		DataDir: DefaultDataDir(),
+		Pruning: DefaultPruning,
 	}
 
 	// RPC
@@ -63,13 +69,20 @@ var (
 		Port:    DefaultRpcHttpPort,
 		Modules: DefaultRpcModules,
 	}
+
+	// Runtime
+	DefaultRuntimeConfig = &gossamerruntime.Config{
+		Executor:  gossamerruntime.DefaultExecutor(),
+		HeapPages: gossamerruntime.DefaultHeapPages,
+	}
 )
 
 // DefaultConfig is the default settings used when a config.toml file is not passed in during instantiation
 var DefaultConfig = &Config{
-	P2pCfg: DefaultP2PConfig,
-	DbCfg:  DefaultDBConfig,
-	RpcCfg: DefaultRpcConfig,
+	P2pCfg:     DefaultP2PConfig,
+	DbCfg:      DefaultDBConfig,
+	RpcCfg:     DefaultRpcConfig,
+	RuntimeCfg: DefaultRuntimeConfig,
 }
 
 // DefaultDataDir is the default data directory to use for the databases and other
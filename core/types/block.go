@@ -0,0 +1,111 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"io"
+	"math/big"
+
+	scale "github.com/ChainSafe/gossamer/codec"
+)
+
+// Extrinsic is a SCALE encoded extrinsic, including its length prefix
+type Extrinsic []byte
+
+// Body is the list of extrinsics in a block
+type Body []Extrinsic
+
+// Encode returns the SCALE encoding of the body
+// since each extrinsic is already encoded along with its length, they are concatenated after the number of extrinsics
+func (b Body) Encode() ([]byte, error) {
+	enc, err := scale.Encode(big.NewInt(int64(len(b))))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ext := range b {
+		enc = append(enc, ext...)
+	}
+
+	return enc, nil
+}
+
+// DecodeBody reads a SCALE encoded body from r
+// the length prefix comes from untrusted input, so the body is grown as its extrinsics are decoded rather than
+// allocated up front
+func DecodeBody(r io.Reader) (Body, error) {
+	length, err := decodeLength(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := Body{}
+	for i := int64(0); i < length; i++ {
+		ext, err := decodeByteArray(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// keep the length prefix as part of the extrinsic
+		prefix, err := scale.Encode(big.NewInt(int64(len(ext))))
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, append(prefix, ext...))
+	}
+
+	return b, nil
+}
+
+// Block is a Polkadot block
+type Block struct {
+	Header *Header
+	Body   Body
+}
+
+// Encode returns the SCALE encoding of the block, its header followed by its body
+func (b *Block) Encode() ([]byte, error) {
+	header, err := b.Header.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := b.Body.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, body...), nil
+}
+
+// DecodeBlock reads a SCALE encoded block from r
+func DecodeBlock(r io.Reader) (*Block, error) {
+	header, err := DecodeHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := DecodeBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Block{
+		Header: header,
+		Body:   body,
+	}, nil
+}
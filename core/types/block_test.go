@@ -0,0 +1,82 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+)
+
+func TestBlock_EncodeDecode(t *testing.T) {
+	block := &Block{
+		Header: &Header{
+			ParentHash: common.Hash{0x01},
+			Number:     big.NewInt(1),
+			Digest:     Digest{},
+		},
+		Body: Body{
+			Extrinsic{0x08, 0x01, 0x02},
+			Extrinsic{0x04, 0x03},
+		},
+	}
+
+	enc, err := block.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := block.Header.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := append(header, 0x08, 0x08, 0x01, 0x02, 0x04, 0x03)
+	if !bytes.Equal(enc, expected) {
+		t.Fatalf("Fail: got %x expected %x", enc, expected)
+	}
+
+	res, err := DecodeBlock(bytes.NewReader(enc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, block) {
+		t.Fatalf("Fail: got %v expected %v", res, block)
+	}
+}
+
+func TestDecodeBody_InvalidLength(t *testing.T) {
+	tests := [][]byte{
+		// 2^32-1 extrinsics
+		{0x03, 0xff, 0xff, 0xff, 0xff},
+		// one extrinsic of 2^32-1 bytes
+		{0x04, 0x03, 0xff, 0xff, 0xff, 0xff, 0x01},
+		// a length which overflows int64
+		{0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+
+	for _, test := range tests {
+		_, err := DecodeBody(bytes.NewReader(test))
+		if err == nil {
+			t.Errorf("Fail: expected error decoding body %x", test)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+
+	scale "github.com/ChainSafe/gossamer/codec"
+	"github.com/ChainSafe/gossamer/common"
+)
+
+// types of digest items, as they are encoded in the first byte of the item
+const (
+	OtherDigestType           = byte(0)
+	ChangesTrieRootDigestType = byte(2)
+	ConsensusDigestType       = byte(4)
+	SealDigestType            = byte(5)
+	PreRuntimeDigestType      = byte(6)
+)
+
+// ConsensusEngineID identifies the consensus engine a digest item is meant for, eg. "BABE"
+type ConsensusEngineID [4]byte
+
+// DigestItem is an item in the digest of a block header
+type DigestItem interface {
+	// Type returns the type byte of the digest item
+	Type() byte
+	// Encode returns the SCALE encoding of the digest item, including its type byte
+	Encode() ([]byte, error)
+	// decode reads the SCALE encoded digest item, after its type byte, from r
+	decode(r io.Reader) error
+}
+
+// Digest is the list of digest items in a block header
+type Digest []DigestItem
+
+// Encode returns the SCALE encoding of the digest
+func (d Digest) Encode() ([]byte, error) {
+	enc, err := scale.Encode(big.NewInt(int64(len(d))))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range d {
+		encItem, err := item.Encode()
+		if err != nil {
+			return nil, err
+		}
+		enc = append(enc, encItem...)
+	}
+
+	return enc, nil
+}
+
+// DecodeDigest reads a SCALE encoded digest from r
+// the length prefix comes from untrusted input, so the digest is grown as its items are decoded rather than
+// allocated up front
+func DecodeDigest(r io.Reader) (Digest, error) {
+	length, err := decodeLength(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := Digest{}
+	for i := int64(0); i < length; i++ {
+		item, err := DecodeDigestItem(r)
+		if err != nil {
+			return nil, err
+		}
+		d = append(d, item)
+	}
+
+	return d, nil
+}
+
+// DecodeDigestItem reads a SCALE encoded digest item from r
+func DecodeDigestItem(r io.Reader) (DigestItem, error) {
+	typ := make([]byte, 1)
+	_, err := io.ReadFull(r, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	var item DigestItem
+	switch typ[0] {
+	case OtherDigestType:
+		item = new(OtherDigest)
+	case ChangesTrieRootDigestType:
+		item = new(ChangesTrieRootDigest)
+	case ConsensusDigestType:
+		item = new(ConsensusDigest)
+	case SealDigestType:
+		item = new(SealDigest)
+	case PreRuntimeDigestType:
+		item = new(PreRuntimeDigest)
+	default:
+		return nil, fmt.Errorf("invalid digest item type %d", typ[0])
+	}
+
+	return item, item.decode(r)
+}
+
+// OtherDigest is a digest item which isn't interpreted by the node
+type OtherDigest struct {
+	Data []byte
+}
+
+// Type returns OtherDigestType
+func (d *OtherDigest) Type() byte {
+	return OtherDigestType
+}
+
+// Encode returns the SCALE encoding of the digest item
+func (d *OtherDigest) Encode() ([]byte, error) {
+	enc, err := scale.Encode(d.Data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{OtherDigestType}, enc...), nil
+}
+
+func (d *OtherDigest) decode(r io.Reader) (err error) {
+	d.Data, err = decodeByteArray(r)
+	return err
+}
+
+// ChangesTrieRootDigest holds the root of the changes trie of the block
+type ChangesTrieRootDigest struct {
+	Hash common.Hash
+}
+
+// Type returns ChangesTrieRootDigestType
+func (d *ChangesTrieRootDigest) Type() byte {
+	return ChangesTrieRootDigestType
+}
+
+// Encode returns the SCALE encoding of the digest item
+func (d *ChangesTrieRootDigest) Encode() ([]byte, error) {
+	return append([]byte{ChangesTrieRootDigestType}, d.Hash[:]...), nil
+}
+
+func (d *ChangesTrieRootDigest) decode(r io.Reader) error {
+	_, err := io.ReadFull(r, d.Hash[:])
+	return err
+}
+
+// ConsensusDigest is a message from the runtime to the consensus engine, such as a change of authorities
+type ConsensusDigest struct {
+	ConsensusEngineID ConsensusEngineID
+	Data              []byte
+}
+
+// Type returns ConsensusDigestType
+func (d *ConsensusDigest) Type() byte {
+	return ConsensusDigestType
+}
+
+// Encode returns the SCALE encoding of the digest item
+func (d *ConsensusDigest) Encode() ([]byte, error) {
+	return encodeEngineDigest(ConsensusDigestType, d.ConsensusEngineID, d.Data)
+}
+
+func (d *ConsensusDigest) decode(r io.Reader) (err error) {
+	d.ConsensusEngineID, d.Data, err = decodeEngineDigest(r)
+	return err
+}
+
+// SealDigest is a seal of the block by the consensus engine, such as a signature by the block author
+type SealDigest struct {
+	ConsensusEngineID ConsensusEngineID
+	Data              []byte
+}
+
+// Type returns SealDigestType
+func (d *SealDigest) Type() byte {
+	return SealDigestType
+}
+
+// Encode returns the SCALE encoding of the digest item
+func (d *SealDigest) Encode() ([]byte, error) {
+	return encodeEngineDigest(SealDigestType, d.ConsensusEngineID, d.Data)
+}
+
+func (d *SealDigest) decode(r io.Reader) (err error) {
+	d.ConsensusEngineID, d.Data, err = decodeEngineDigest(r)
+	return err
+}
+
+// PreRuntimeDigest is a message from the consensus engine to the runtime, added before the block is executed
+type PreRuntimeDigest struct {
+	ConsensusEngineID ConsensusEngineID
+	Data              []byte
+}
+
+// Type returns PreRuntimeDigestType
+func (d *PreRuntimeDigest) Type() byte {
+	return PreRuntimeDigestType
+}
+
+// Encode returns the SCALE encoding of the digest item
+func (d *PreRuntimeDigest) Encode() ([]byte, error) {
+	return encodeEngineDigest(PreRuntimeDigestType, d.ConsensusEngineID, d.Data)
+}
+
+func (d *PreRuntimeDigest) decode(r io.Reader) (err error) {
+	d.ConsensusEngineID, d.Data, err = decodeEngineDigest(r)
+	return err
+}
+
+// encodeEngineDigest encodes a digest item consisting of a consensus engine ID followed by a byte array
+func encodeEngineDigest(typ byte, id ConsensusEngineID, data []byte) ([]byte, error) {
+	enc, err := scale.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer([]byte{typ})
+	buf.Write(id[:])
+	buf.Write(enc)
+	return buf.Bytes(), nil
+}
+
+// decodeEngineDigest decodes a digest item consisting of a consensus engine ID followed by a byte array
+func decodeEngineDigest(r io.Reader) (ConsensusEngineID, []byte, error) {
+	id := ConsensusEngineID{}
+	_, err := io.ReadFull(r, id[:])
+	if err != nil {
+		return id, nil, err
+	}
+
+	data, err := decodeByteArray(r)
+	return id, data, err
+}
+
+// decodeByteArray reads a SCALE encoded byte array from r
+// unlike scale.Decoder.DecodeByteArray, the array is only grown as its bytes are read, so a length prefix larger
+// than the input returns an error instead of allocating the whole length
+func decodeByteArray(r io.Reader) ([]byte, error) {
+	length, err := decodeLength(r)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	_, err = io.CopyN(buf, r, length)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode byte array of length %d: %s", length, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeLength reads a SCALE encoded length prefix from r
+func decodeLength(r io.Reader) (int64, error) {
+	length, err := (&scale.Decoder{Reader: r}).DecodeInteger()
+	if err != nil {
+		return 0, err
+	} else if length < 0 {
+		return 0, fmt.Errorf("invalid length %d", uint64(length))
+	}
+
+	return length, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+)
+
+func TestDigest_EncodeDecode(t *testing.T) {
+	babe := ConsensusEngineID{'B', 'A', 'B', 'E'}
+
+	tests := []struct {
+		item     DigestItem
+		expected []byte
+	}{
+		{&OtherDigest{Data: []byte{0xde, 0xad}}, []byte{0x00, 0x08, 0xde, 0xad}},
+		{&ChangesTrieRootDigest{Hash: common.Hash{0xff}}, append([]byte{0x02, 0xff}, make([]byte, 31)...)},
+		{&ConsensusDigest{ConsensusEngineID: babe, Data: []byte{0x01}}, []byte{0x04, 'B', 'A', 'B', 'E', 0x04, 0x01}},
+		{&SealDigest{ConsensusEngineID: babe, Data: []byte{0x02}}, []byte{0x05, 'B', 'A', 'B', 'E', 0x04, 0x02}},
+		{&PreRuntimeDigest{ConsensusEngineID: babe, Data: []byte{0x03}}, []byte{0x06, 'B', 'A', 'B', 'E', 0x04, 0x03}},
+	}
+
+	digest := Digest{}
+	expected := []byte{byte(len(tests)) << 2}
+	for _, test := range tests {
+		enc, err := test.item.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(enc, test.expected) {
+			t.Errorf("Fail: got %x expected %x", enc, test.expected)
+		}
+
+		if enc[0] != test.item.Type() {
+			t.Errorf("Fail: got type %d expected %d", enc[0], test.item.Type())
+		}
+
+		digest = append(digest, test.item)
+		expected = append(expected, test.expected...)
+	}
+
+	enc, err := digest.Encode()
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(enc, expected) {
+		t.Fatalf("Fail: got %x expected %x", enc, expected)
+	}
+
+	res, err := DecodeDigest(bytes.NewReader(enc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, digest) {
+		t.Fatalf("Fail: got %v expected %v", res, digest)
+	}
+
+	_, err = DecodeDigestItem(bytes.NewReader([]byte{0x01}))
+	if err == nil {
+		t.Fatal("Fail: expected error for invalid digest item type")
+	}
+}
+
+func TestDecodeDigestItem_InvalidLength(t *testing.T) {
+	// an other digest item whose data claims to be 2^32-1 bytes
+	_, err := DecodeDigestItem(bytes.NewReader([]byte{OtherDigestType, 0x03, 0xff, 0xff, 0xff, 0xff, 0x01}))
+	if err == nil {
+		t.Fatal("Fail: expected error decoding digest item with invalid length")
+	}
+}
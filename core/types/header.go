@@ -0,0 +1,103 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+
+	scale "github.com/ChainSafe/gossamer/codec"
+	"github.com/ChainSafe/gossamer/common"
+)
+
+// Header is the header of a Polkadot block
+type Header struct {
+	ParentHash     common.Hash // the block hash of the block's parent
+	Number         *big.Int    // block number
+	StateRoot      common.Hash // the root of the state trie
+	ExtrinsicsRoot common.Hash // the root of the extrinsics trie
+	Digest         Digest      // any additional block info eg. logs, seal
+}
+
+// Encode returns the SCALE encoding of the header
+func (h *Header) Encode() ([]byte, error) {
+	if h.Number == nil || h.Number.Sign() < 0 {
+		return nil, errors.New("cannot encode header: invalid block number")
+	}
+
+	number, err := scale.Encode(h.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := h.Digest.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(h.ParentHash[:])
+	buf.Write(number)
+	buf.Write(h.StateRoot[:])
+	buf.Write(h.ExtrinsicsRoot[:])
+	buf.Write(digest)
+	return buf.Bytes(), nil
+}
+
+// DecodeHeader reads a SCALE encoded header from r
+func DecodeHeader(r io.Reader) (*Header, error) {
+	h := new(Header)
+
+	_, err := io.ReadFull(r, h.ParentHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	h.Number, err = (&scale.Decoder{Reader: r}).DecodeBigInt()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.ReadFull(r, h.StateRoot[:])
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.ReadFull(r, h.ExtrinsicsRoot[:])
+	if err != nil {
+		return nil, err
+	}
+
+	h.Digest, err = DecodeDigest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Hash returns the hash of the header, the blake2b hash of its SCALE encoding
+func (h *Header) Hash() (common.Hash, error) {
+	enc, err := h.Encode()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return common.Blake2bHash(enc)
+}
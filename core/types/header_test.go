@@ -0,0 +1,113 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+)
+
+func TestHeader_EncodeDecode(t *testing.T) {
+	header := &Header{
+		ParentHash:     common.Hash{0x01},
+		Number:         big.NewInt(69),
+		StateRoot:      common.Hash{0x02},
+		ExtrinsicsRoot: common.Hash{0x03},
+		Digest: Digest{
+			&PreRuntimeDigest{ConsensusEngineID: ConsensusEngineID{'B', 'A', 'B', 'E'}, Data: []byte{0x01}},
+		},
+	}
+
+	enc, err := header.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{}
+	expected = append(expected, header.ParentHash[:]...)
+	expected = append(expected, 0x15, 0x01) // compact 69
+	expected = append(expected, header.StateRoot[:]...)
+	expected = append(expected, header.ExtrinsicsRoot[:]...)
+	expected = append(expected, 0x04, 0x06, 'B', 'A', 'B', 'E', 0x04, 0x01)
+
+	if !bytes.Equal(enc, expected) {
+		t.Fatalf("Fail: got %x expected %x", enc, expected)
+	}
+
+	res, err := DecodeHeader(bytes.NewReader(enc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, header) {
+		t.Fatalf("Fail: got %v expected %v", res, header)
+	}
+}
+
+func TestHeader_Hash(t *testing.T) {
+	// the genesis header of the polkadot relay chain, whose hash is the chain's genesis hash
+	header := &Header{
+		Number:         big.NewInt(0),
+		StateRoot:      mustHexToHash(t, "0x29d0d972cd27cbc511e9589fcb7a4506d5eb6a9e8df205f00472e5ab354a4e17"),
+		ExtrinsicsRoot: mustHexToHash(t, "0x03170a2e7597b7b7e3d84c05391d139a62b157e78786d8c082f29dcf4c111314"),
+		Digest:         Digest{},
+	}
+
+	hash, err := header.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := mustHexToHash(t, "0x91b171bb158e2d3848fa23a9f1c25182fb8e20313b2c1eb49219da7a70ce90c3")
+	if hash != expected {
+		t.Fatalf("Fail: got hash %x expected %x", hash, expected)
+	}
+}
+
+func mustHexToHash(t *testing.T, in string) common.Hash {
+	hash, err := common.HexToHash(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestHeader_Encode_InvalidNumber(t *testing.T) {
+	header := &Header{}
+	_, err := header.Encode()
+	if err == nil {
+		t.Fatal("Fail: expected error for header without number")
+	}
+}
+
+func TestDecodeHeader_InvalidDigestLength(t *testing.T) {
+	// a header whose digest claims to have 2^32-1 items
+	enc := make([]byte, 97)
+	enc = append(enc, 0x03, 0xff, 0xff, 0xff, 0xff)
+	if len(enc) != 102 {
+		t.Fatalf("Fail: got %d bytes expected 102", len(enc))
+	}
+
+	_, err := DecodeHeader(bytes.NewReader(enc))
+	if err == nil {
+		t.Fatal("Fail: expected error decoding header with invalid digest length")
+	}
+}
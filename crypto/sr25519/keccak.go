@@ -0,0 +1,78 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package sr25519
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccakRoundConstants are the round constants of the iota step of keccak-f[1600]
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakF1600 applies the keccak-f[1600] permutation to the state, whose lanes are stored little endian
+func keccakF1600(state *[200]byte) {
+	var a [25]uint64
+	for i := range a {
+		a[i] = binary.LittleEndian.Uint64(state[i*8:])
+	}
+
+	for _, rc := range keccakRoundConstants {
+		// theta
+		var c [5]uint64
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[x+y] ^= d
+			}
+		}
+
+		// rho and pi
+		x, y := 1, 0
+		current := a[1]
+		for t := 0; t < 24; t++ {
+			x, y = y, (2*x+3*y)%5
+			current, a[x+5*y] = a[x+5*y], bits.RotateLeft64(current, ((t+1)*(t+2)/2)%64)
+		}
+
+		// chi
+		for y := 0; y < 25; y += 5 {
+			var row [5]uint64
+			copy(row[:], a[y:y+5])
+			for x := 0; x < 5; x++ {
+				a[x+y] = row[x] ^ (^row[(x+1)%5] & row[(x+2)%5])
+			}
+		}
+
+		// iota
+		a[0] ^= rc
+	}
+
+	for i := range a {
+		binary.LittleEndian.PutUint64(state[i*8:], a[i])
+	}
+}
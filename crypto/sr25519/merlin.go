@@ -0,0 +1,142 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package sr25519
+
+import (
+	"encoding/binary"
+)
+
+// the transcript is an implementation of merlin, which builds fiat-shamir transcripts on top of STROBE-128.
+// only the subset of STROBE operations used by merlin is implemented
+const (
+	strobeRate = 166
+
+	flagI = 1
+	flagA = 1 << 1
+	flagC = 1 << 2
+	flagM = 1 << 4
+	flagK = 1 << 5
+)
+
+// strobe is the state of a STROBE-128 duplex
+type strobe struct {
+	state    [200]byte
+	pos      byte
+	posBegin byte
+}
+
+func newStrobe(protocolLabel []byte) *strobe {
+	s := &strobe{}
+	copy(s.state[:], []byte{1, strobeRate + 2, 1, 0, 1, 96})
+	copy(s.state[6:], "STROBEv1.0.2")
+	keccakF1600(&s.state)
+	s.metaAD(protocolLabel, false)
+	return s
+}
+
+func (s *strobe) metaAD(data []byte, more bool) {
+	s.beginOp(flagM|flagA, more)
+	s.absorb(data)
+}
+
+func (s *strobe) ad(data []byte, more bool) {
+	s.beginOp(flagA, more)
+	s.absorb(data)
+}
+
+func (s *strobe) prf(data []byte, more bool) {
+	s.beginOp(flagI|flagA|flagC, more)
+	s.squeeze(data)
+}
+
+func (s *strobe) runF() {
+	s.state[s.pos] ^= s.posBegin
+	s.state[s.pos+1] ^= 0x04
+	s.state[strobeRate+1] ^= 0x80
+	keccakF1600(&s.state)
+	s.pos = 0
+	s.posBegin = 0
+}
+
+func (s *strobe) absorb(data []byte) {
+	for _, b := range data {
+		s.state[s.pos] ^= b
+		s.pos++
+		if s.pos == strobeRate {
+			s.runF()
+		}
+	}
+}
+
+func (s *strobe) squeeze(data []byte) {
+	for i := range data {
+		data[i] = s.state[s.pos]
+		s.state[s.pos] = 0
+		s.pos++
+		if s.pos == strobeRate {
+			s.runF()
+		}
+	}
+}
+
+func (s *strobe) beginOp(flags byte, more bool) {
+	// a continued operation keeps appending to the current one
+	if more {
+		return
+	}
+
+	oldBegin := s.posBegin
+	s.posBegin = s.pos + 1
+	s.absorb([]byte{oldBegin, flags})
+
+	if flags&(flagC|flagK) != 0 && s.pos != 0 {
+		s.runF()
+	}
+}
+
+// Transcript is a merlin transcript, which is used to derive the challenge of a signature from the context,
+// message, public key and commitment
+type Transcript struct {
+	strobe *strobe
+}
+
+// NewTranscript returns a transcript for the protocol with the given label
+func NewTranscript(label []byte) *Transcript {
+	t := &Transcript{
+		strobe: newStrobe([]byte("Merlin v1.0")),
+	}
+	t.AppendMessage([]byte("dom-sep"), label)
+	return t
+}
+
+// AppendMessage appends the labelled message to the transcript
+func (t *Transcript) AppendMessage(label, message []byte) {
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(message)))
+	t.strobe.metaAD(label, false)
+	t.strobe.metaAD(length, true)
+	t.strobe.ad(message, false)
+}
+
+// ChallengeBytes fills dest with challenge bytes derived from the transcript
+func (t *Transcript) ChallengeBytes(label []byte, dest []byte) {
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(dest)))
+	t.strobe.metaAD(label, false)
+	t.strobe.metaAD(length, true)
+	t.strobe.prf(dest, false)
+}
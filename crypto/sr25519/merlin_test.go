@@ -0,0 +1,64 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package sr25519
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// keccak-f[1600] is checked by building SHA3-256 on top of it
+func TestKeccakF1600(t *testing.T) {
+	for _, msg := range [][]byte{{}, []byte("noot"), bytes.Repeat([]byte{0xab}, 300)} {
+		const rate = 136
+		var state [200]byte
+		padded := append(append([]byte{}, msg...), 0x06)
+		for len(padded)%rate != 0 {
+			padded = append(padded, 0)
+		}
+		padded[len(padded)-1] |= 0x80
+
+		for i := 0; i < len(padded); i += rate {
+			for j := 0; j < rate; j++ {
+				state[j] ^= padded[i+j]
+			}
+			keccakF1600(&state)
+		}
+
+		expected := sha3.Sum256(msg)
+		if !bytes.Equal(state[:32], expected[:]) {
+			t.Fatalf("Fail: got %x expected %x", state[:32], expected)
+		}
+	}
+}
+
+// test vector from the merlin crate
+func TestTranscript(t *testing.T) {
+	tr := NewTranscript([]byte("test protocol"))
+	tr.AppendMessage([]byte("some label"), []byte("some data"))
+
+	challenge := make([]byte, 32)
+	tr.ChallengeBytes([]byte("challenge"), challenge)
+
+	expected, _ := hex.DecodeString("d5a21972d0d5fe320c0d263fac7fffb8145aa640af6e9bca177c03c7efcf0615")
+	if !bytes.Equal(challenge, expected) {
+		t.Fatalf("Fail: got %x expected %x", challenge, expected)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package sr25519
+
+import (
+	"errors"
+	"math/big"
+)
+
+// the ristretto255 group is built on the edwards25519 curve -x^2 + y^2 = 1 + d*x^2*y^2 over the field of
+// integers modulo p = 2^255 - 19. field elements are big.Ints in [0, p); this is simple rather than fast,
+// and isn't constant time, which is fine for verification since it only handles public data
+var (
+	// fieldOrder is p = 2^255 - 19
+	fieldOrder = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	// groupOrder is l = 2^252 + 27742317777372353535851937790883648493, the order of the ristretto255 group
+	groupOrder, _ = new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
+
+	feOne = big.NewInt(1)
+	// edwardsD is d = -121665/121666
+	edwardsD = feMul(feNeg(big.NewInt(121665)), feInvert(big.NewInt(121666)))
+	// edwardsD2 is 2*d
+	edwardsD2 = feAdd(edwardsD, edwardsD)
+	// sqrtM1 is a square root of -1, 2^((p-1)/4)
+	sqrtM1 = new(big.Int).Exp(big.NewInt(2), new(big.Int).Rsh(new(big.Int).Sub(fieldOrder, feOne), 2), fieldOrder)
+	// invSqrtAMinusD is 1/sqrt(a-d), where a = -1
+	_, invSqrtAMinusD = sqrtRatioM1(feOne, feSub(feNeg(feOne), edwardsD))
+
+	basepoint = affinePoint(
+		fromDecimal("15112221349535400772501151409588531511454012693041857206046113283949847762202"),
+		fromDecimal("46316835694926478169428394003475163141307993866256225615783033603165251855960"),
+	)
+)
+
+var errInvalidEncoding = errors.New("invalid ristretto255 encoding")
+
+func fromDecimal(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+func feAdd(a, b *big.Int) *big.Int {
+	r := new(big.Int).Add(a, b)
+	return r.Mod(r, fieldOrder)
+}
+
+func feSub(a, b *big.Int) *big.Int {
+	r := new(big.Int).Sub(a, b)
+	return r.Mod(r, fieldOrder)
+}
+
+func feMul(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, fieldOrder)
+}
+
+func feNeg(a *big.Int) *big.Int {
+	r := new(big.Int).Neg(a)
+	return r.Mod(r, fieldOrder)
+}
+
+func feInvert(a *big.Int) *big.Int {
+	return new(big.Int).ModInverse(a, fieldOrder)
+}
+
+// feIsNegative returns whether the field element is negative, which by convention means odd
+func feIsNegative(a *big.Int) bool {
+	return a.Bit(0) == 1
+}
+
+// feAbs returns the non-negative one of a and -a
+func feAbs(a *big.Int) *big.Int {
+	if feIsNegative(a) {
+		return feNeg(a)
+	}
+	return new(big.Int).Set(a)
+}
+
+// sqrtRatioM1 returns whether u/v is square, and the non-negative square root of u/v if it is, or of
+// sqrt(-1)*u/v if it isn't
+func sqrtRatioM1(u, v *big.Int) (bool, *big.Int) {
+	v3 := feMul(feMul(v, v), v)
+	v7 := feMul(feMul(v3, v3), v)
+	exp := new(big.Int).Rsh(new(big.Int).Sub(fieldOrder, big.NewInt(5)), 3)
+	r := feMul(feMul(u, v3), new(big.Int).Exp(feMul(u, v7), exp, fieldOrder))
+
+	check := feMul(v, feMul(r, r))
+	correctSign := check.Cmp(u) == 0
+	flippedSign := check.Cmp(feNeg(u)) == 0
+	flippedSignI := check.Cmp(feMul(feNeg(u), sqrtM1)) == 0
+
+	if flippedSign || flippedSignI {
+		r = feMul(r, sqrtM1)
+	}
+
+	return correctSign || flippedSign, feAbs(r)
+}
+
+// point is a point on the edwards curve in extended coordinates, where x = X/Z, y = Y/Z and x*y = T/Z
+type point struct {
+	x, y, z, t *big.Int
+}
+
+// affinePoint returns the point with the affine coordinates (x, y)
+func affinePoint(x, y *big.Int) *point {
+	return &point{x: x, y: y, z: big.NewInt(1), t: feMul(x, y)}
+}
+
+func identity() *point {
+	return affinePoint(big.NewInt(0), big.NewInt(1))
+}
+
+// add returns p + q
+func (p *point) add(q *point) *point {
+	a := feMul(feSub(p.y, p.x), feSub(q.y, q.x))
+	b := feMul(feAdd(p.y, p.x), feAdd(q.y, q.x))
+	c := feMul(feMul(p.t, edwardsD2), q.t)
+	d := feMul(feAdd(p.z, p.z), q.z)
+	e, f, g, h := feSub(b, a), feSub(d, c), feAdd(d, c), feAdd(b, a)
+
+	return &point{
+		x: feMul(e, f),
+		y: feMul(g, h),
+		z: feMul(f, g),
+		t: feMul(e, h),
+	}
+}
+
+// neg returns -p
+func (p *point) neg() *point {
+	return &point{
+		x: feNeg(p.x),
+		y: new(big.Int).Set(p.y),
+		z: new(big.Int).Set(p.z),
+		t: feNeg(p.t),
+	}
+}
+
+// mul returns k*p
+func (p *point) mul(k *big.Int) *point {
+	res := identity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		res = res.add(res)
+		if k.Bit(i) == 1 {
+			res = res.add(p)
+		}
+	}
+	return res
+}
+
+// encode returns the canonical 32 byte ristretto255 encoding of the point
+func (p *point) encode() []byte {
+	u1 := feMul(feAdd(p.z, p.y), feSub(p.z, p.y))
+	u2 := feMul(p.x, p.y)
+	_, invSqrt := sqrtRatioM1(feOne, feMul(u1, feMul(u2, u2)))
+	den1 := feMul(invSqrt, u1)
+	den2 := feMul(invSqrt, u2)
+	zInv := feMul(feMul(den1, den2), p.t)
+
+	x, y, denInv := p.x, p.y, den2
+	if feIsNegative(feMul(p.t, zInv)) {
+		x = feMul(p.y, sqrtM1)
+		y = feMul(p.x, sqrtM1)
+		denInv = feMul(den1, invSqrtAMinusD)
+	}
+
+	if feIsNegative(feMul(x, zInv)) {
+		y = feNeg(y)
+	}
+
+	s := feAbs(feMul(denInv, feSub(p.z, y)))
+	return toLittleEndian(s)
+}
+
+// decodePoint decodes a ristretto255 encoded point, returning an error if the encoding isn't canonical
+func decodePoint(in []byte) (*point, error) {
+	if len(in) != 32 {
+		return nil, errInvalidEncoding
+	}
+
+	s := fromLittleEndian(in)
+	if s.Cmp(fieldOrder) >= 0 || feIsNegative(s) {
+		return nil, errInvalidEncoding
+	}
+
+	ss := feMul(s, s)
+	u1 := feSub(feOne, ss)
+	u2 := feAdd(feOne, ss)
+	u2Sqr := feMul(u2, u2)
+	v := feSub(feNeg(feMul(edwardsD, feMul(u1, u1))), u2Sqr)
+
+	wasSquare, invSqrt := sqrtRatioM1(feOne, feMul(v, u2Sqr))
+	denX := feMul(invSqrt, u2)
+	denY := feMul(feMul(invSqrt, denX), v)
+
+	x := feAbs(feMul(feAdd(s, s), denX))
+	y := feMul(u1, denY)
+
+	if !wasSquare || feIsNegative(feMul(x, y)) || y.Sign() == 0 {
+		return nil, errInvalidEncoding
+	}
+
+	return affinePoint(x, y), nil
+}
+
+// fromLittleEndian decodes a little endian integer
+func fromLittleEndian(in []byte) *big.Int {
+	be := make([]byte, len(in))
+	for i, b := range in {
+		be[len(in)-1-i] = b
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// toLittleEndian encodes an integer less than 2^256 as 32 little endian bytes
+func toLittleEndian(n *big.Int) []byte {
+	be := n.Bytes()
+	out := make([]byte, 32)
+	for i, b := range be {
+		out[len(be)-1-i] = b
+	}
+	return out
+}
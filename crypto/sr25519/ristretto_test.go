@@ -0,0 +1,85 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package sr25519
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// test vectors from the ristretto255 specification: the encodings of multiples of the basepoint
+var basepointMultiples = []string{
+	"0000000000000000000000000000000000000000000000000000000000000000",
+	"e2f2ae0a6abc4e71a884a961c500515f58e30b6aa582dd8db6a65945e08d2d76",
+	"6a493210f7499cd17fecb510ae0cea23a110e8d5b901f8acadd3095c73a3b919",
+	"94741f5d5d52755ece4f23f044ee27d5d1ea1e2bd196b462166b16152a9d0259",
+	"da80862773358b466ffadfe0b3293ab3d9fd53c5ea6c955358f568322daf6a57",
+}
+
+func TestPoint_Encode(t *testing.T) {
+	p := identity()
+	for i, enc := range basepointMultiples {
+		expected, _ := hex.DecodeString(enc)
+		if !bytes.Equal(p.encode(), expected) {
+			t.Errorf("Fail: %dB: got %x expected %x", i, p.encode(), expected)
+		}
+
+		if !bytes.Equal(basepoint.mul(big.NewInt(int64(i))).encode(), expected) {
+			t.Errorf("Fail: %dB by multiplication: got %x expected %x", i, basepoint.mul(big.NewInt(int64(i))).encode(), expected)
+		}
+
+		p = p.add(basepoint)
+	}
+
+	// l*B is the identity
+	if !bytes.Equal(basepoint.mul(groupOrder).encode(), make([]byte, 32)) {
+		t.Error("Fail: l*B is not the identity")
+	}
+}
+
+func TestDecodePoint(t *testing.T) {
+	for _, enc := range basepointMultiples {
+		in, _ := hex.DecodeString(enc)
+		p, err := decodePoint(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(p.encode(), in) {
+			t.Fatalf("Fail: got %x expected %x", p.encode(), in)
+		}
+	}
+
+	invalid := []string{
+		// non-canonical field element
+		"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		// negative field element
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		// non-square x^2
+		"26948d35ca62e643e26a83177332e6b6afeb9d08e4268b650f1f5bbd8d81d371",
+	}
+
+	for _, enc := range invalid {
+		in, _ := hex.DecodeString(enc)
+		_, err := decodePoint(in)
+		if err == nil {
+			t.Errorf("Fail: expected error decoding %s", enc)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package sr25519
+
+import (
+	"errors"
+	"math/big"
+)
+
+const (
+	// PublicKeyLength is the length of an encoded public key
+	PublicKeyLength = 32
+	// SignatureLength is the length of an encoded signature
+	SignatureLength = 64
+)
+
+// SigningContext is the context substrate signs messages in
+var SigningContext = []byte("substrate")
+
+var (
+	// ErrInvalidPublicKey is returned when a public key isn't a valid ristretto255 point
+	ErrInvalidPublicKey = errors.New("invalid sr25519 public key")
+	// ErrInvalidSignature is returned when a signature can't be decoded
+	ErrInvalidSignature = errors.New("invalid sr25519 signature")
+)
+
+// PublicKey is an sr25519 public key, which is a point in the ristretto255 group
+type PublicKey struct {
+	key     *point
+	encoded []byte
+}
+
+// NewPublicKey decodes a 32 byte public key
+func NewPublicKey(in []byte) (*PublicKey, error) {
+	if len(in) != PublicKeyLength {
+		return nil, ErrInvalidPublicKey
+	}
+
+	key, err := decodePoint(in)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return &PublicKey{
+		key:     key,
+		encoded: append([]byte{}, in...),
+	}, nil
+}
+
+// Encode returns the 32 byte encoding of the public key
+func (k *PublicKey) Encode() []byte {
+	return append([]byte{}, k.encoded...)
+}
+
+// Signature is a schnorrkel signature, which consists of the commitment R and the response s
+type Signature struct {
+	// r is the encoding of R; it's only ever compared to an encoding, so it isn't decoded
+	r []byte
+	s *big.Int
+}
+
+// NewSignature decodes a 64 byte signature. schnorrkel marks its signatures by setting the highest bit of
+// the last byte, so that they can't be confused with ed25519 signatures; unmarked signatures are rejected
+func NewSignature(in []byte) (*Signature, error) {
+	if len(in) != SignatureLength {
+		return nil, ErrInvalidSignature
+	}
+
+	if in[63]&0x80 == 0 {
+		return nil, ErrInvalidSignature
+	}
+
+	sBytes := append([]byte{}, in[32:]...)
+	sBytes[31] &= 0x7f
+	s := fromLittleEndian(sBytes)
+
+	// scalars with any of the top 4 bits set must be reduced, smaller ones are accepted as they are
+	if sBytes[31]&0xf0 != 0 && s.Cmp(groupOrder) >= 0 {
+		return nil, ErrInvalidSignature
+	}
+
+	return &Signature{
+		r: append([]byte{}, in[:32]...),
+		s: s,
+	}, nil
+}
+
+// Verify returns whether the signature is a valid signature of the message by the key, in the substrate
+// signing context
+func (k *PublicKey) Verify(msg []byte, sig *Signature) bool {
+	t := NewTranscript([]byte("SigningContext"))
+	t.AppendMessage([]byte{}, SigningContext)
+	t.AppendMessage([]byte("sign-bytes"), msg)
+
+	t.AppendMessage([]byte("proto-name"), []byte("Schnorr-sig"))
+	t.AppendMessage([]byte("sign:pk"), k.encoded)
+	t.AppendMessage([]byte("sign:R"), sig.r)
+
+	challenge := make([]byte, 64)
+	t.ChallengeBytes([]byte("sign:c"), challenge)
+	c := fromLittleEndian(challenge)
+	c.Mod(c, groupOrder)
+
+	// the signature is valid if R = s*B - c*A
+	r := basepoint.mul(sig.s).add(k.key.neg().mul(c))
+	return string(r.encode()) == string(sig.r)
+}
+
+// Verify returns whether sig is a valid signature of the message by the public key pub; invalid keys and
+// signatures fail to verify
+func Verify(pub, msg, sig []byte) bool {
+	key, err := NewPublicKey(pub)
+	if err != nil {
+		return false
+	}
+
+	signature, err := NewSignature(sig)
+	if err != nil {
+		return false
+	}
+
+	return key.Verify(msg, signature)
+}
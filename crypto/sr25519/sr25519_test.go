@@ -0,0 +1,114 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package sr25519
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// test vector from sr25519-crust, the C bindings of schnorrkel, signed in the substrate signing context
+var (
+	testPublicKey = "46ebddef8cd9bb167dc30878d7113b7e168e6f0646beffd77d69d39bad76b47a"
+	testSignature = "4e172314444b8f820bb54c22e95076f220ed25373e5c178234aa6c211d29271244b947e3ff3418ff6b45fd1df1140c8cbff69fc58ee6dc96df70936a2bb74b82"
+	testMessage   = []byte("this is a message")
+)
+
+func TestVerify(t *testing.T) {
+	pub, _ := hex.DecodeString(testPublicKey)
+	sig, _ := hex.DecodeString(testSignature)
+
+	if !Verify(pub, testMessage, sig) {
+		t.Fatal("Fail: did not verify valid signature")
+	}
+
+	if Verify(pub, []byte("this is another message"), sig) {
+		t.Error("Fail: verified signature of a different message")
+	}
+
+	for _, i := range []int{0, 32, 62} {
+		tampered := append([]byte{}, sig...)
+		tampered[i] ^= 1
+		if Verify(pub, testMessage, tampered) {
+			t.Errorf("Fail: verified signature with byte %d modified", i)
+		}
+	}
+
+	otherPub := append([]byte{}, pub...)
+	otherPub[0] ^= 2
+	if Verify(otherPub, testMessage, sig) {
+		t.Error("Fail: verified signature with a different public key")
+	}
+}
+
+func TestNewSignature(t *testing.T) {
+	sig, _ := hex.DecodeString(testSignature)
+
+	_, err := NewSignature(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewSignature(sig[:63])
+	if err != ErrInvalidSignature {
+		t.Errorf("Fail: got %v expected %v for short signature", err, ErrInvalidSignature)
+	}
+
+	// signatures must have the schnorrkel marker bit set
+	unmarked := append([]byte{}, sig...)
+	unmarked[63] &= 0x7f
+	_, err = NewSignature(unmarked)
+	if err != ErrInvalidSignature {
+		t.Errorf("Fail: got %v expected %v for unmarked signature", err, ErrInvalidSignature)
+	}
+
+	// s must be reduced
+	unreduced := append([]byte{}, sig...)
+	for i := 32; i < 64; i++ {
+		unreduced[i] = 0xff
+	}
+	_, err = NewSignature(unreduced)
+	if err != ErrInvalidSignature {
+		t.Errorf("Fail: got %v expected %v for unreduced scalar", err, ErrInvalidSignature)
+	}
+}
+
+func TestNewPublicKey(t *testing.T) {
+	pub, _ := hex.DecodeString(testPublicKey)
+
+	key, err := NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hex.EncodeToString(key.Encode()) != testPublicKey {
+		t.Fatalf("Fail: got %x expected %s", key.Encode(), testPublicKey)
+	}
+
+	_, err = NewPublicKey(pub[:31])
+	if err != ErrInvalidPublicKey {
+		t.Errorf("Fail: got %v expected %v for short key", err, ErrInvalidPublicKey)
+	}
+
+	// 1 is a negative field element, so isn't a valid encoding
+	invalid := make([]byte, 32)
+	invalid[0] = 1
+	_, err = NewPublicKey(invalid)
+	if err != ErrInvalidPublicKey {
+		t.Errorf("Fail: got %v expected %v for invalid key", err, ErrInvalidPublicKey)
+	}
+}
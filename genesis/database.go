@@ -0,0 +1,176 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package genesis
+
+import (
+	"fmt"
+	"math/big"
+
+	scale "github.com/ChainSafe/gossamer/codec"
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/core/types"
+	"github.com/ChainSafe/gossamer/polkadb"
+	"github.com/ChainSafe/gossamer/trie"
+)
+
+// NewGenesisHeader returns the header of the genesis block with the given state root
+func NewGenesisHeader(stateRoot common.Hash) (*types.Header, error) {
+	// the genesis block has no extrinsics
+	extrinsicsRoot, err := trie.NewEmptyTrie(nil).Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Header{
+		ParentHash:     common.Hash{},
+		Number:         big.NewInt(0),
+		StateRoot:      stateRoot,
+		ExtrinsicsRoot: extrinsicsRoot,
+		Digest:         types.Digest{},
+	}, nil
+}
+
+// genesisPrefix is the prefix of the table the chain specification a database was initialized with is recorded in
+const genesisPrefix = "gen"
+
+// storageHashKey is the key of the hash of the genesis storage, see storageHash
+var storageHashKey = []byte("storage_hash")
+
+// storageHash returns the blake2b hash of the SCALE encoded keys and values of the genesis storage, in ascending
+// order of key. it identifies the genesis state without building its trie
+func storageHash(storage map[string][]byte) (common.Hash, error) {
+	enc := []byte{}
+	for _, k := range sortedKeys(storage) {
+		key, err := scale.Encode([]byte(k))
+		if err != nil {
+			return [32]byte{}, err
+		}
+
+		value, err := scale.Encode(storage[k])
+		if err != nil {
+			return [32]byte{}, err
+		}
+
+		enc = append(enc, key...)
+		enc = append(enc, value...)
+	}
+
+	return common.Blake2bHash(enc)
+}
+
+// Initialize returns the state trie and header of the best block stored in the database
+// the first time it's called with a database, the genesis state trie and block are built from the chain
+// specification and written to the database. afterwards the state of the best block is loaded from the database,
+// and the chain specification is only checked against the one the database was initialized with, so a block must
+// only be made the best block once its state is committed. the blocks are stored in the trie database's
+// underlying database, and the state is written through the trie database, so that its pruner tracks it
+func Initialize(tdb *trie.Database, g *Genesis) (*trie.Trie, *types.Header, error) {
+	storage, err := g.Storage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	specHash, err := storageHash(storage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bdb := polkadb.NewBlockDB(tdb.Db)
+	meta := polkadb.NewTable(tdb.Db, genesisPrefix)
+
+	t, header, err := LoadBestState(tdb)
+	if err != nil && err != polkadb.ErrNotFound {
+		return nil, nil, err
+	}
+
+	if err == nil {
+		stored, err := meta.Get(storageHashKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if common.NewHash(stored) != specHash {
+			return nil, nil, fmt.Errorf("database was initialized with genesis storage %x, chain specification has genesis storage %x", stored, specHash)
+		}
+
+		return t, header, nil
+	}
+
+	t, err = NewGenesisTrie(tdb, g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err := t.Hash()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header, err = NewGenesisHeader(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash, err := header.Hash()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = t.WriteToDB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = meta.Put(storageHashKey, specHash[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the genesis block is written last, so the database is only considered initialized once the state is stored
+	_, err = bdb.AddBlock(&types.Block{Header: header, Body: types.Body{}})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = bdb.SetBestBlock(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return t, header, nil
+}
+
+// LoadBestState returns the state trie and header of the best block stored in the trie database's underlying
+// database, or polkadb.ErrNotFound if the database hasn't been initialized
+func LoadBestState(tdb *trie.Database) (*trie.Trie, *types.Header, error) {
+	best, err := polkadb.NewBlockDB(tdb.Db).BestBlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t, err := trie.LoadFromDB(tdb, best.Header.StateRoot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot load state of best block %d: %s", best.Header.Number, err)
+	}
+
+	return t, best.Header, nil
+}
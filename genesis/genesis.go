@@ -0,0 +1,122 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/trie"
+)
+
+// Genesis is a chain specification, describing the chain and its genesis state
+type Genesis struct {
+	Name       string        `json:"name"`
+	Id         string        `json:"id"`
+	Bootnodes  []string      `json:"bootNodes"`
+	ProtocolId string        `json:"protocolId"`
+	Genesis    GenesisFields `json:"genesis"`
+}
+
+// GenesisFields holds the genesis state of the chain
+type GenesisFields struct {
+	// Raw maps the 0x prefixed hex encoded storage keys of the genesis state, including :code, to their values
+	Raw map[string]string `json:"raw"`
+}
+
+// LoadGenesisJSONFile reads the chain specification in the given JSON file
+func LoadGenesisJSONFile(file string) (*Genesis, error) {
+	fp, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	/* #nosec */
+	data, err := ioutil.ReadFile(filepath.Clean(fp))
+	if err != nil {
+		return nil, err
+	}
+
+	g := new(Genesis)
+	err = json.Unmarshal(data, g)
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Storage returns the decoded key-value pairs of the genesis state
+func (g *Genesis) Storage() (map[string][]byte, error) {
+	storage := make(map[string][]byte)
+	for k, v := range g.Genesis.Raw {
+		key, err := decodeHex(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid genesis storage key %s: %s", k, err)
+		}
+
+		value, err := decodeHex(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid genesis storage value for key %s: %s", k, err)
+		}
+
+		storage[string(key)] = value
+	}
+
+	return storage, nil
+}
+
+// decodeHex decodes a 0x prefixed hex string
+func decodeHex(in string) ([]byte, error) {
+	if !strings.HasPrefix(in, "0x") {
+		return nil, fmt.Errorf("expected 0x prefixed hex string")
+	}
+	return common.HexToBytes(in)
+}
+
+// NewGenesisTrie returns a trie with the given database containing the genesis state
+func NewGenesisTrie(db *trie.Database, g *Genesis) (*trie.Trie, error) {
+	storage, err := g.Storage()
+	if err != nil {
+		return nil, err
+	}
+
+	t := trie.NewEmptyTrie(db)
+	for _, k := range sortedKeys(storage) {
+		err = t.Put([]byte(k), storage[k])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// sortedKeys returns the keys of the genesis storage in ascending order
+func sortedKeys(storage map[string][]byte) []string {
+	keys := make([]string, 0, len(storage))
+	for k := range storage {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package genesis
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/core/types"
+	"github.com/ChainSafe/gossamer/polkadb"
+	"github.com/ChainSafe/gossamer/trie"
+)
+
+var testGenesis = []byte(`{
+	"name": "gossamer",
+	"id": "gossamer",
+	"bootNodes": ["/ip4/127.0.0.1/tcp/7001/p2p/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ"],
+	"protocolId": "/gossamer/dot/0",
+	"genesis": {
+		"raw": {
+			"0x3a636f6465": "0x0061736d01000000",
+			"0x6e6f6f74": "0x7761732068657265"
+		}
+	}
+}`)
+
+func createTempGenesisFile(t *testing.T, data []byte) string {
+	f, err := ioutil.TempFile(os.TempDir(), "genesis-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f.Name()
+}
+
+func TestLoadGenesisJSONFile(t *testing.T) {
+	file := createTempGenesisFile(t, testGenesis)
+	defer os.Remove(file)
+
+	g, err := LoadGenesisJSONFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Name != "gossamer" || g.Id != "gossamer" || g.ProtocolId != "/gossamer/dot/0" {
+		t.Fatalf("Fail: got %v", g)
+	}
+
+	if len(g.Bootnodes) != 1 {
+		t.Fatalf("Fail: got %d boot nodes expected 1", len(g.Bootnodes))
+	}
+
+	storage, err := g.Storage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(storage[":code"], []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}) {
+		t.Fatalf("Fail: got code %x", storage[":code"])
+	}
+
+	if !bytes.Equal(storage["noot"], []byte("was here")) {
+		t.Fatalf("Fail: got value %x", storage["noot"])
+	}
+
+	g.Genesis.Raw["noot"] = "0x00"
+	_, err = g.Storage()
+	if err == nil {
+		t.Fatal("Fail: expected error for key without 0x prefix")
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	file := createTempGenesisFile(t, testGenesis)
+	defer os.Remove(file)
+
+	g, err := LoadGenesisJSONFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := polkadb.NewMemDatabase()
+	hasher, err := trie.NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tdb := &trie.Database{Db: db, Hasher: hasher}
+
+	gt, header, err := Initialize(tdb, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := gt.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.StateRoot != root {
+		t.Fatalf("Fail: got state root %x expected %x", header.StateRoot, root)
+	}
+
+	if header.Number.Cmp(big.NewInt(0)) != 0 {
+		t.Fatalf("Fail: got block number %d expected 0", header.Number)
+	}
+
+	// the genesis state should be in the database
+	loaded, err := trie.LoadFromDB(tdb, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := loaded.Get([]byte(":code"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(code, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}) {
+		t.Fatalf("Fail: got code %x", code)
+	}
+
+	expected, err := header.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	best, err := polkadb.NewBlockDB(db).BestBlockHash()
+	if err != nil {
+		t.Fatal(err)
+	} else if best != expected {
+		t.Fatalf("Fail: got best block %x expected genesis %x", best, expected)
+	}
+
+	// initializing again with the same genesis is fine
+	_, _, err = Initialize(tdb, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// once there's a new best block, its state is loaded rather than the genesis state
+	err = loaded.Put([]byte("noot"), []byte("was here too"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = loaded.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = loaded.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err = loaded.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bdb := polkadb.NewBlockDB(db)
+	next := &types.Header{
+		ParentHash: expected,
+		Number:     big.NewInt(1),
+		StateRoot:  root,
+		Digest:     types.Digest{},
+	}
+	hash, err := bdb.AddBlock(&types.Block{Header: next, Body: types.Body{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = bdb.SetBestBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bt, header, err := Initialize(tdb, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Number.Cmp(big.NewInt(1)) != 0 || header.StateRoot != root {
+		t.Fatalf("Fail: got block %d with state root %x expected block 1 with state root %x", header.Number, header.StateRoot, root)
+	}
+
+	val, err := bt.Get([]byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("was here too")) {
+		t.Fatalf("Fail: got value %s expected %s", val, "was here too")
+	}
+
+	// but a different genesis isn't
+	g.Genesis.Raw["0x6e6f6f74"] = "0x01"
+	_, _, err = Initialize(tdb, g)
+	if err == nil {
+		t.Fatal("Fail: expected error for different genesis")
+	}
+}
+
+func TestLoadBestState_Empty(t *testing.T) {
+	hasher, err := trie.NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = LoadBestState(&trie.Database{Db: polkadb.NewMemDatabase(), Hasher: hasher})
+	if err != polkadb.ErrNotFound {
+		t.Fatalf("Fail: got %v expected %v", err, polkadb.ErrNotFound)
+	}
+}
+
+func TestInitialize_Pruner(t *testing.T) {
+	g := new(Genesis)
+	g.Genesis.Raw = map[string]string{"0x3a636f6465": "0x0061736d01000000"}
+
+	db := polkadb.NewMemDatabase()
+	hasher, err := trie.NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruner, err := trie.NewPruner(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the genesis state is written through the pruner, so its root is retained
+	_, header, err := Initialize(&trie.Database{Db: db, Hasher: hasher, Pruner: pruner}, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := pruner.Roots()
+	if len(roots) != 1 || roots[0] != header.StateRoot {
+		t.Fatalf("Fail: got retained roots %x expected %x", roots, header.StateRoot)
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package polkadb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/core/types"
+)
+
+// prefixes of the tables in the block database
+const (
+	headerPrefix        = "hdr" // header hash -> encoded header
+	bodyPrefix          = "blk" // header hash -> block body
+	hashPrefix          = "hsh" // big endian block number -> hash of the block in the best chain
+	justificationPrefix = "jst" // header hash -> justification
+	metaPrefix          = "mta" // metadata, such as the best block
+)
+
+var bestBlockKey = []byte("best_block")
+
+// ErrNotFound is returned when the requested block data is not in the database
+var ErrNotFound = errors.New("block data not found")
+
+// BlockDB stores block headers, bodies and justifications, along with the best chain
+type BlockDB struct {
+	db             Database
+	headers        Database
+	bodies         Database
+	hashes         Database
+	justifications Database
+	meta           Database
+	lock           sync.RWMutex
+}
+
+// NewBlockDB returns a BlockDB which stores its tables in the given database
+func NewBlockDB(db Database) *BlockDB {
+	return &BlockDB{
+		db:             db,
+		headers:        NewTable(db, headerPrefix),
+		bodies:         NewTable(db, bodyPrefix),
+		hashes:         NewTable(db, hashPrefix),
+		justifications: NewTable(db, justificationPrefix),
+		meta:           NewTable(db, metaPrefix),
+	}
+}
+
+// numberKey returns the key of the block number in the hash-by-number table
+func numberKey(n *big.Int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, n.Uint64())
+	return key
+}
+
+// get returns the value stored under key in the table, or ErrNotFound if there isn't one
+func get(table Database, key []byte) ([]byte, error) {
+	has, err := table.Has(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !has {
+		return nil, ErrNotFound
+	}
+
+	return table.Get(key)
+}
+
+// HasHeader returns true if the header with the given hash is in the database
+func (bdb *BlockDB) HasHeader(hash common.Hash) (bool, error) {
+	return bdb.headers.Has(hash[:])
+}
+
+// GetHeader returns the header with the given hash
+func (bdb *BlockDB) GetHeader(hash common.Hash) (*types.Header, error) {
+	enc, err := get(bdb.headers, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return types.DecodeHeader(bytes.NewReader(enc))
+}
+
+// GetBlockBody returns the body of the block with the given hash
+func (bdb *BlockDB) GetBlockBody(hash common.Hash) (types.Body, error) {
+	enc, err := get(bdb.bodies, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return types.DecodeBody(bytes.NewReader(enc))
+}
+
+// GetBlock returns the block with the given hash
+func (bdb *BlockDB) GetBlock(hash common.Hash) (*types.Block, error) {
+	header, err := bdb.GetHeader(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := bdb.GetBlockBody(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Block{
+		Header: header,
+		Body:   body,
+	}, nil
+}
+
+// GetJustification returns the justification of the block with the given hash
+func (bdb *BlockDB) GetJustification(hash common.Hash) ([]byte, error) {
+	return get(bdb.justifications, hash[:])
+}
+
+// GetHashByNumber returns the hash of the block in the best chain with the given number
+func (bdb *BlockDB) GetHashByNumber(n *big.Int) (common.Hash, error) {
+	hash, err := get(bdb.hashes, numberKey(n))
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return common.NewHash(hash), nil
+}
+
+// GetHeaderByNumber returns the header of the block in the best chain with the given number
+func (bdb *BlockDB) GetHeaderByNumber(n *big.Int) (*types.Header, error) {
+	hash, err := bdb.GetHashByNumber(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return bdb.GetHeader(hash)
+}
+
+// GetBlockByNumber returns the block in the best chain with the given number
+func (bdb *BlockDB) GetBlockByNumber(n *big.Int) (*types.Block, error) {
+	hash, err := bdb.GetHashByNumber(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return bdb.GetBlock(hash)
+}
+
+// SetHeader writes the header to the database
+func (bdb *BlockDB) SetHeader(header *types.Header) error {
+	batch := bdb.NewBatch()
+	_, err := batch.PutHeader(header)
+	if err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// SetBlockBody writes the body of the block with the given hash to the database
+func (bdb *BlockDB) SetBlockBody(hash common.Hash, body types.Body) error {
+	enc, err := body.Encode()
+	if err != nil {
+		return err
+	}
+
+	return bdb.bodies.Put(hash[:], enc)
+}
+
+// SetJustification writes the justification of the block with the given hash to the database
+func (bdb *BlockDB) SetJustification(hash common.Hash, justification []byte) error {
+	return bdb.justifications.Put(hash[:], justification)
+}
+
+// AddBlock atomically writes the header and body of the block to the database, returning the block's hash
+func (bdb *BlockDB) AddBlock(block *types.Block) (common.Hash, error) {
+	batch := bdb.NewBatch()
+
+	hash, err := batch.PutHeader(block.Header)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	err = batch.PutBlockBody(hash, block.Body)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return hash, batch.Write()
+}
+
+// BestBlockHash returns the hash of the head of the best chain
+func (bdb *BlockDB) BestBlockHash() (common.Hash, error) {
+	hash, err := get(bdb.meta, bestBlockKey)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return common.NewHash(hash), nil
+}
+
+// BestBlock returns the head of the best chain
+func (bdb *BlockDB) BestBlock() (*types.Block, error) {
+	hash, err := bdb.BestBlockHash()
+	if err != nil {
+		return nil, err
+	}
+
+	return bdb.GetBlock(hash)
+}
+
+// SetBestBlock makes the block with the given hash the head of the best chain
+// the hash-by-number table is updated with the new best chain, going back through the block's ancestors until it
+// meets the previous best chain; the header of every block in the chain must already be in the database
+func (bdb *BlockDB) SetBestBlock(hash common.Hash) error {
+	bdb.lock.Lock()
+	defer bdb.lock.Unlock()
+
+	header, err := bdb.GetHeader(hash)
+	if err != nil {
+		return err
+	}
+
+	batch := bdb.NewBatch()
+
+	// remove the blocks of the previous best chain which are higher than the new best block
+	prev, err := bdb.BestBlockHash()
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	if err == nil {
+		prevHeader, err := bdb.GetHeader(prev)
+		if err != nil {
+			return err
+		}
+
+		for n := new(big.Int).Add(header.Number, big.NewInt(1)); n.Cmp(prevHeader.Number) <= 0; n.Add(n, big.NewInt(1)) {
+			err = batch.batch.Delete(common.Concat([]byte(hashPrefix), numberKey(n)...))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	curr, currHash := header, hash
+	for {
+		canonical, err := bdb.GetHashByNumber(curr.Number)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+
+		if err == nil && canonical == currHash {
+			break
+		}
+
+		err = batch.PutHashByNumber(curr.Number, currHash)
+		if err != nil {
+			return err
+		}
+
+		if curr.Number.Sign() == 0 {
+			break
+		}
+
+		currHash = curr.ParentHash
+		curr, err = bdb.GetHeader(currHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = batch.batch.Put(common.Concat([]byte(metaPrefix), bestBlockKey...), hash[:])
+	if err != nil {
+		return err
+	}
+
+	return batch.Write()
+}
+
+// BlockBatch writes block data to several of the block database's tables atomically
+type BlockBatch struct {
+	batch Batch
+}
+
+// NewBatch returns a BlockBatch for the database
+func (bdb *BlockDB) NewBatch() *BlockBatch {
+	return &BlockBatch{
+		batch: bdb.db.NewBatch(),
+	}
+}
+
+// put adds the key with the table's prefix to the batch
+func (bb *BlockBatch) put(prefix string, key, value []byte) error {
+	return bb.batch.Put(common.Concat([]byte(prefix), key...), value)
+}
+
+// PutHeader adds the header to the batch, returning its hash
+func (bb *BlockBatch) PutHeader(header *types.Header) (common.Hash, error) {
+	enc, err := header.Encode()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	hash, err := common.Blake2bHash(enc)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return hash, bb.put(headerPrefix, hash[:], enc)
+}
+
+// PutBlockBody adds the body of the block with the given hash to the batch
+func (bb *BlockBatch) PutBlockBody(hash common.Hash, body types.Body) error {
+	enc, err := body.Encode()
+	if err != nil {
+		return err
+	}
+
+	return bb.put(bodyPrefix, hash[:], enc)
+}
+
+// PutJustification adds the justification of the block with the given hash to the batch
+func (bb *BlockBatch) PutJustification(hash common.Hash, justification []byte) error {
+	return bb.put(justificationPrefix, hash[:], justification)
+}
+
+// PutHashByNumber adds the hash of the block in the best chain with the given number to the batch
+func (bb *BlockBatch) PutHashByNumber(n *big.Int, hash common.Hash) error {
+	return bb.put(hashPrefix, numberKey(n), hash[:])
+}
+
+// Write writes the contents of the batch to the database
+func (bb *BlockBatch) Write() error {
+	return bb.batch.Write()
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package polkadb
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/core/types"
+)
+
+// addTestBlock adds a block with the given parent to the database, returning its hash
+func addTestBlock(t *testing.T, bdb *BlockDB, parent common.Hash, number int64, body types.Extrinsic) common.Hash {
+	hash, err := bdb.AddBlock(&types.Block{
+		Header: &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(number),
+			StateRoot:  common.Hash{byte(number)},
+			Digest:     types.Digest{&types.OtherDigest{Data: body}},
+		},
+		Body: types.Body{body},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestBlockDB_AddBlock(t *testing.T) {
+	bdb := NewBlockDB(NewMemDatabase())
+
+	header := &types.Header{
+		ParentHash: common.Hash{},
+		Number:     big.NewInt(0),
+		StateRoot:  common.Hash{1, 2, 3},
+		Digest:     types.Digest{},
+	}
+
+	body := types.Body{types.Extrinsic{0x10, 'n', 'o', 'o', 't'}}
+	hash, err := bdb.AddBlock(&types.Block{Header: header, Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := header.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if hash != expected {
+		t.Fatalf("Fail: got hash %x expected %x", hash, expected)
+	}
+
+	res, err := bdb.GetBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Header.StateRoot != header.StateRoot || res.Header.Number.Cmp(header.Number) != 0 {
+		t.Fatalf("Fail: got header %v expected %v", res.Header, header)
+	}
+
+	if !reflect.DeepEqual(res.Body, body) {
+		t.Fatalf("Fail: got body %x expected %x", res.Body, body)
+	}
+
+	has, err := bdb.HasHeader(hash)
+	if err != nil {
+		t.Fatal(err)
+	} else if !has {
+		t.Fatal("Fail: header should be in database")
+	}
+
+	err = bdb.SetJustification(hash, []byte("justified"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := bdb.GetJustification(hash)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(j, []byte("justified")) {
+		t.Fatalf("Fail: got justification %x", j)
+	}
+
+	_, err = bdb.GetHeader(common.Hash{0xff})
+	if err != ErrNotFound {
+		t.Fatalf("Fail: got error %v expected %v", err, ErrNotFound)
+	}
+
+	_, err = bdb.BestBlockHash()
+	if err != ErrNotFound {
+		t.Fatalf("Fail: got error %v expected %v", err, ErrNotFound)
+	}
+}
+
+func testBestBlock(t *testing.T, db Database) {
+	bdb := NewBlockDB(db)
+
+	genesis := addTestBlock(t, bdb, common.Hash{}, 0, types.Extrinsic{0x04, 0})
+	one := addTestBlock(t, bdb, genesis, 1, types.Extrinsic{0x04, 1})
+	two := addTestBlock(t, bdb, one, 2, types.Extrinsic{0x04, 2})
+	three := addTestBlock(t, bdb, two, 3, types.Extrinsic{0x04, 3})
+	fork := addTestBlock(t, bdb, one, 2, types.Extrinsic{0x04, 4})
+
+	checkChain := func(chain []common.Hash) {
+		for i, hash := range chain {
+			res, err := bdb.GetHashByNumber(big.NewInt(int64(i)))
+			if err != nil {
+				t.Fatal(err)
+			} else if res != hash {
+				t.Fatalf("Fail: got hash %x for block %d expected %x", res, i, hash)
+			}
+		}
+
+		_, err := bdb.GetHashByNumber(big.NewInt(int64(len(chain))))
+		if err != ErrNotFound {
+			t.Fatalf("Fail: got error %v for block %d expected %v", err, len(chain), ErrNotFound)
+		}
+
+		best, err := bdb.BestBlockHash()
+		if err != nil {
+			t.Fatal(err)
+		} else if best != chain[len(chain)-1] {
+			t.Fatalf("Fail: got best block %x expected %x", best, chain[len(chain)-1])
+		}
+	}
+
+	err := bdb.SetBestBlock(three)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChain([]common.Hash{genesis, one, two, three})
+
+	err = bdb.SetBestBlock(fork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChain([]common.Hash{genesis, one, fork})
+
+	block, err := bdb.GetBlockByNumber(big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(block.Body[0], types.Extrinsic{0x04, 4}) {
+		t.Fatalf("Fail: got body %x expected %x", block.Body, types.Extrinsic{0x04, 4})
+	}
+
+	best, err := bdb.BestBlock()
+	if err != nil {
+		t.Fatal(err)
+	} else if best.Header.Number.Cmp(big.NewInt(2)) != 0 {
+		t.Fatalf("Fail: got best block number %d expected 2", best.Header.Number)
+	}
+}
+
+func TestBlockDB_BestBlock(t *testing.T) {
+	testBestBlock(t, NewMemDatabase())
+}
+
+func TestBadgerDB_BlockDB_BestBlock(t *testing.T) {
+	db, remove := newTestBadgerDB()
+	defer remove()
+	testBestBlock(t, db)
+}
@@ -34,6 +34,9 @@ type BadgerService struct {
 //Config defines configurations for BadgerService instance
 type Config struct {
 	DataDir string
+	// Pruning is the number of recently committed state roots whose trie nodes are kept, or "archive" to keep
+	// every node, see trie.ParseRetain
+	Pruning string
 }
 
 // Iterable struct contains a transaction, iterator and context fields released, initialized
@@ -48,6 +51,7 @@ type Iterable struct {
 type batchWriter struct {
 	db   *BadgerService
 	b    map[string][]byte
+	del  map[string]bool
 	size int
 }
 
@@ -102,8 +106,9 @@ func (db *BadgerService) Path() string {
 // NewBatch returns batchWriter with a badgerDB instance and an initialized mapping
 func (db *BadgerService) NewBatch() Batch {
 	return &batchWriter{
-		db: db,
-		b:  make(map[string][]byte),
+		db:  db,
+		b:   make(map[string][]byte),
+		del: make(map[string]bool),
 	}
 }
 
@@ -238,11 +243,13 @@ func (b *batchWriter) Put(key, value []byte) error {
 	encodedKey := snappy.Encode(nil, key)
 	encodedVal := snappy.Encode(nil, value)
 	b.b[string(encodedKey)] = encodedVal
+	delete(b.del, string(encodedKey))
 	b.size += len(value)
 	return nil
 }
 
 // Write performs batched writes
+// if any of the writes fails, the first error is returned and the rest of the batch is discarded
 func (b *batchWriter) Write() error {
 	wb := b.db.db.NewWriteBatch()
 	defer wb.Cancel()
@@ -250,11 +257,17 @@ func (b *batchWriter) Write() error {
 	for k, v := range b.b {
 		err := wb.Set([]byte(k), v)
 		if err != nil {
-			log.Printf("%+v", errors.Wrap(err, "error writing batch txs"))
+			return errors.Wrap(err, "error writing batch txs")
+		}
+	}
+	for k := range b.del {
+		err := wb.Delete([]byte(k))
+		if err != nil {
+			return errors.Wrap(err, "error batch deleting key")
 		}
 	}
 	if err := wb.Flush(); err != nil {
-		log.Printf("%+v", errors.Wrap(err, "error stored by writeBatch"))
+		return errors.Wrap(err, "error stored by writeBatch")
 	}
 	return nil
 }
@@ -264,12 +277,11 @@ func (b *batchWriter) ValueSize() int {
 	return b.size
 }
 
-// Delete removes the key from the batch and database
+// Delete removes the key from the batch and queues it to be removed from the database on Write
 func (b *batchWriter) Delete(key []byte) error {
-	err := b.db.db.NewWriteBatch().Delete(key)
-	if err != nil {
-		log.Printf("%+v", errors.Wrap(err, "error batch deleting key"))
-	}
+	encodedKey := snappy.Encode(nil, key)
+	delete(b.b, string(encodedKey))
+	b.del[string(encodedKey)] = true
 	b.size++
 	return nil
 }
@@ -277,6 +289,7 @@ func (b *batchWriter) Delete(key []byte) error {
 // Reset clears batch key-values and resets the size to zero
 func (b *batchWriter) Reset() {
 	b.b = make(map[string][]byte)
+	b.del = make(map[string]bool)
 	b.size = 0
 }
 
@@ -342,7 +355,7 @@ func (tb *tableBatch) Reset() {
 
 // Delete removes the key from the batch and database
 func (tb *tableBatch) Delete(k []byte) error {
-	err := tb.batch.Delete(k)
+	err := tb.batch.Delete(append([]byte(tb.prefix), k...))
 	if err != nil {
 		return err
 	}
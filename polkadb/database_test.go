@@ -18,6 +18,7 @@ package polkadb
 
 import (
 	"bytes"
+	"crypto/rand"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -160,6 +161,42 @@ func TestBadgerDB_Batch(t *testing.T) {
 	testBatchPut(db, t)
 }
 
+func TestBadgerDB_BatchError(t *testing.T) {
+	db, remove := newTestBadgerDB()
+	defer remove()
+
+	b := db.NewBatch()
+	err := b.Put([]byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the key is longer than badger allows, so writing it fails
+	key := make([]byte, 1<<17)
+	_, err = rand.Read(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.Put(key, []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.Write()
+	if err == nil {
+		t.Fatal("Fail: expected error writing batch")
+	}
+
+	// none of the batch is written
+	has, err := db.Has([]byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	} else if has {
+		t.Fatal("Fail: batch was partly written")
+	}
+}
+
 func batchTestSetup(db *BadgerService) (func(i int) []byte, func(i int) []byte, Batch) {
 	testKey := func(i int) []byte {
 		return []byte(fmt.Sprintf("%04d", i))
@@ -89,7 +89,61 @@ func (db *MemDatabase) Close() {
 	// do nothing
 }
 
-// NewBatch ...
+// NewBatch returns a memBatch which writes to the mapping when Write is called
 func (db *MemDatabase) NewBatch() Batch {
+	return &memBatch{
+		db:      db,
+		writes:  make(map[string][]byte),
+		deletes: make(map[string]bool),
+	}
+}
+
+// memBatch holds the key-values to write to a MemDatabase
+type memBatch struct {
+	db      *MemDatabase
+	writes  map[string][]byte
+	deletes map[string]bool
+	size    int
+}
+
+// Put adds the key-value to the batch
+func (b *memBatch) Put(key, value []byte) error {
+	b.writes[string(key)] = append([]byte{}, value...)
+	delete(b.deletes, string(key))
+	b.size += len(value)
+	return nil
+}
+
+// Delete adds the removal of the key to the batch
+func (b *memBatch) Delete(key []byte) error {
+	b.deletes[string(key)] = true
+	delete(b.writes, string(key))
+	b.size++
+	return nil
+}
+
+// Write performs the batched writes and deletes
+func (b *memBatch) Write() error {
+	b.db.lock.Lock()
+	defer b.db.lock.Unlock()
+
+	for k, v := range b.writes {
+		b.db.db[k] = v
+	}
+	for k := range b.deletes {
+		delete(b.db.db, k)
+	}
 	return nil
 }
+
+// ValueSize returns the amount of data in the batch
+func (b *memBatch) ValueSize() int {
+	return b.size
+}
+
+// Reset clears batch key-values and resets the size to zero
+func (b *memBatch) Reset() {
+	b.writes = make(map[string][]byte)
+	b.deletes = make(map[string]bool)
+	b.size = 0
+}
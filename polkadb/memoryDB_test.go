@@ -105,3 +105,56 @@ func testDelGet(db *MemDatabase, t *testing.T) {
 		}
 	}
 }
+
+func TestMemoryDB_Batch(t *testing.T) {
+	memDB := NewMemDatabase()
+	tests := testData()
+
+	b := memDB.NewBatch()
+	for _, v := range tests {
+		err := b.Put([]byte(v.input), []byte(v.input))
+		if err != nil {
+			t.Fatalf("batch put failed: %v", err)
+		}
+	}
+
+	if len(memDB.Keys()) != 0 {
+		t.Fatalf("batch wrote to db before Write was called")
+	}
+
+	err := b.Write()
+	if err != nil {
+		t.Fatalf("batch write failed: %v", err)
+	}
+
+	for _, v := range tests {
+		data, err := memDB.Get([]byte(v.input))
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if !bytes.Equal(data, []byte(v.expected)) {
+			t.Fatalf("get returned wrong result, got %q expected %q", string(data), v.expected)
+		}
+	}
+
+	b.Reset()
+	if b.ValueSize() != 0 {
+		t.Fatalf("failed to reset batch size, got %d", b.ValueSize())
+	}
+
+	for _, v := range tests {
+		err = b.Delete([]byte(v.input))
+		if err != nil {
+			t.Fatalf("batch delete failed: %v", err)
+		}
+	}
+
+	err = b.Write()
+	if err != nil {
+		t.Fatalf("batch write failed: %v", err)
+	}
+
+	if len(memDB.Keys()) != 0 {
+		t.Fatalf("failed to batch delete keys, got %d keys", len(memDB.Keys()))
+	}
+}
@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// the allocator is a port of substrate's FreeingBumpHeapAllocator: memory is handed out in blocks whose size is
+// a power of two, from 8 bytes up to 16MiB. each block is preceded by an 8 byte header. freed blocks are kept in
+// one linked list per size, and are reused before any new memory is bumped off the end of the heap
+const (
+	// pageSize is the size of a page of wasm linear memory
+	pageSize = 65536
+	// alignment is the alignment of the heap and of every allocation
+	alignment = 8
+	// headerSize is the size of the header in front of each block
+	headerSize = 8
+	// minAllocation is the size of the smallest block; smaller allocations are rounded up to it
+	minAllocation = 8
+	// maxAllocation is the size of the largest block that can be allocated
+	maxAllocation = 16 * 1024 * 1024
+	// numOrders is the number of block sizes, from minAllocation to maxAllocation
+	numOrders = 22
+	// maxPages is the largest number of pages a wasm32 memory can have
+	maxPages = 65536
+)
+
+const (
+	// nilLink marks the end of a free list
+	nilLink = 0xffffffff
+	// occupiedFlag is set in the header of blocks which are in use
+	occupiedFlag = 1 << 32
+)
+
+// ErrOutOfMemory is returned when an allocation can't be made because the memory is full and can't be grown
+var ErrOutOfMemory = errors.New("cannot allocate memory: out of memory")
+
+// Memory is the linear memory of a wasm instance
+type Memory interface {
+	// Data returns the contents of the memory; the slice may change when the memory grows
+	Data() []byte
+	// Length returns the size of the memory in bytes
+	Length() uint32
+	// Grow grows the memory by the given number of pages
+	Grow(pages uint32) error
+}
+
+// Allocator manages the heap of a wasm instance, which is the memory after the module's stack and static data
+type Allocator struct {
+	memory Memory
+	// heapBase is the address the heap starts at, aligned to 8 bytes
+	heapBase uint32
+	// bumper is the offset from heapBase of the first byte that has never been allocated
+	bumper uint32
+	// heads holds the offset of the first free block of each order, or nilLink if there are none
+	heads [numOrders]uint32
+}
+
+// NewAllocator returns an allocator for the memory whose heap starts at heapBase
+func NewAllocator(memory Memory, heapBase uint32) *Allocator {
+	a := &Allocator{
+		memory:   memory,
+		heapBase: (heapBase + alignment - 1) / alignment * alignment,
+	}
+	a.Clear()
+	return a
+}
+
+// Clear frees every allocation, so that the whole heap is available again
+func (a *Allocator) Clear() {
+	a.bumper = 0
+	for i := range a.heads {
+		a.heads[i] = nilLink
+	}
+}
+
+// Allocate reserves size bytes of memory and returns the address of the first byte
+func (a *Allocator) Allocate(size uint32) (uint32, error) {
+	if size > maxAllocation {
+		return 0, fmt.Errorf("cannot allocate memory: requested %d bytes, more than the maximum of %d", size, maxAllocation)
+	}
+
+	order := orderOf(size)
+
+	var offset uint32
+	if a.heads[order] != nilLink {
+		// reuse the first free block of the right size
+		offset = a.heads[order]
+		header, err := a.readHeader(offset)
+		if err != nil {
+			return 0, err
+		}
+		if header&occupiedFlag != 0 {
+			return 0, fmt.Errorf("cannot allocate memory: free list contains occupied block at %d", a.heapBase+offset)
+		}
+		a.heads[order] = uint32(header)
+	} else {
+		var err error
+		offset, err = a.bump(headerSize + minAllocation<<order)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err := a.writeHeader(offset, occupiedFlag|uint64(order))
+	if err != nil {
+		return 0, err
+	}
+
+	return a.heapBase + offset + headerSize, nil
+}
+
+// Deallocate frees the memory at the address returned by an earlier call to Allocate
+func (a *Allocator) Deallocate(ptr uint32) error {
+	if ptr < a.heapBase+headerSize {
+		return fmt.Errorf("cannot free memory: invalid pointer %d", ptr)
+	}
+
+	offset := ptr - a.heapBase - headerSize
+	header, err := a.readHeader(offset)
+	if err != nil {
+		return err
+	}
+
+	order := uint32(header)
+	if header&occupiedFlag == 0 || order >= numOrders {
+		return fmt.Errorf("cannot free memory: pointer %d is not allocated", ptr)
+	}
+
+	// link the block in at the front of the free list of its size
+	err = a.writeHeader(offset, uint64(a.heads[order]))
+	if err != nil {
+		return err
+	}
+	a.heads[order] = offset
+	return nil
+}
+
+// bump reserves size bytes at the end of the heap, growing the memory if needed
+func (a *Allocator) bump(size uint32) (uint32, error) {
+	end := uint64(a.heapBase) + uint64(a.bumper) + uint64(size)
+	if end > uint64(a.memory.Length()) {
+		err := a.grow(end)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	offset := a.bumper
+	a.bumper += size
+	return offset, nil
+}
+
+// grow grows the memory so that it's at least size bytes long
+func (a *Allocator) grow(size uint64) error {
+	length := uint64(a.memory.Length())
+	pages := (size - length + pageSize - 1) / pageSize
+	if (length+pages*pageSize)/pageSize > maxPages {
+		return ErrOutOfMemory
+	}
+
+	err := a.memory.Grow(uint32(pages))
+	if err != nil {
+		return fmt.Errorf("%s: %s", ErrOutOfMemory, err)
+	}
+
+	if uint64(a.memory.Length()) < size {
+		return ErrOutOfMemory
+	}
+	return nil
+}
+
+// readHeader reads the header of the block at the offset from the heap base
+func (a *Allocator) readHeader(offset uint32) (uint64, error) {
+	ptr := uint64(a.heapBase) + uint64(offset)
+	mem := a.memory.Data()
+	if ptr+headerSize > uint64(len(mem)) {
+		return 0, fmt.Errorf("cannot read allocation header: address %d out of bounds", ptr)
+	}
+	return binary.LittleEndian.Uint64(mem[ptr : ptr+headerSize]), nil
+}
+
+// writeHeader writes the header of the block at the offset from the heap base
+func (a *Allocator) writeHeader(offset uint32, header uint64) error {
+	ptr := uint64(a.heapBase) + uint64(offset)
+	mem := a.memory.Data()
+	if ptr+headerSize > uint64(len(mem)) {
+		return fmt.Errorf("cannot write allocation header: address %d out of bounds", ptr)
+	}
+	binary.LittleEndian.PutUint64(mem[ptr:ptr+headerSize], header)
+	return nil
+}
+
+// orderOf returns the order of the smallest block that can hold size bytes; a block of order n
+// holds minAllocation << n bytes
+func orderOf(size uint32) uint32 {
+	order := uint32(0)
+	for minAllocation<<order < size {
+		order++
+	}
+	return order
+}
@@ -0,0 +1,190 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+)
+
+// mockMemory is a Memory backed by a byte slice, which can grow up to maxPages pages
+type mockMemory struct {
+	data     []byte
+	maxPages uint32
+}
+
+func newMockMemory(pages, maxPages uint32) *mockMemory {
+	return &mockMemory{
+		data:     make([]byte, pages*pageSize),
+		maxPages: maxPages,
+	}
+}
+
+func (m *mockMemory) Data() []byte {
+	return m.data
+}
+
+func (m *mockMemory) Length() uint32 {
+	return uint32(len(m.data))
+}
+
+func (m *mockMemory) Grow(pages uint32) error {
+	if uint32(len(m.data))/pageSize+pages > m.maxPages {
+		return errors.New("maximum memory size exceeded")
+	}
+	m.data = append(m.data, make([]byte, pages*pageSize)...)
+	return nil
+}
+
+func TestAllocator_Allocate(t *testing.T) {
+	mem := newMockMemory(1, 1)
+	a := NewAllocator(mem, 13)
+
+	// the heap base is aligned to 8 bytes, and the first block starts after its header
+	ptr, err := a.Allocate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr != 16+headerSize {
+		t.Fatalf("Fail: got %d expected %d", ptr, 16+headerSize)
+	}
+
+	// the first block holds 8 bytes, so the next one starts 16 bytes later
+	ptr, err = a.Allocate(9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr != 16+2*headerSize+minAllocation {
+		t.Fatalf("Fail: got %d expected %d", ptr, 16+2*headerSize+minAllocation)
+	}
+
+	// the second block was rounded up to 16 bytes
+	ptr2, err := a.Allocate(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr2 != ptr+16+headerSize {
+		t.Fatalf("Fail: got %d expected %d", ptr2, ptr+16+headerSize)
+	}
+
+	_, err = a.Allocate(maxAllocation + 1)
+	if err == nil {
+		t.Fatal("Fail: expected error for allocation larger than the maximum")
+	}
+}
+
+func TestAllocator_Deallocate(t *testing.T) {
+	mem := newMockMemory(1, 1)
+	a := NewAllocator(mem, 0)
+
+	ptr1, err := a.Allocate(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptr2, err := a.Allocate(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.Deallocate(ptr1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Deallocate(ptr2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// freed blocks are reused, most recently freed first
+	ptr, err := a.Allocate(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr != ptr2 {
+		t.Fatalf("Fail: got %d expected %d", ptr, ptr2)
+	}
+	ptr, err = a.Allocate(65)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr != ptr1 {
+		t.Fatalf("Fail: got %d expected %d", ptr, ptr1)
+	}
+
+	// a block of a different size isn't reused
+	err = a.Deallocate(ptr1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptr, err = a.Allocate(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr == ptr1 {
+		t.Fatal("Fail: reused block of a different size")
+	}
+
+	// freeing twice is an error
+	err = a.Deallocate(ptr1)
+	if err == nil {
+		t.Fatal("Fail: expected error for double free")
+	}
+
+	err = a.Deallocate(4)
+	if err == nil {
+		t.Fatal("Fail: expected error for invalid pointer")
+	}
+}
+
+func TestAllocator_Grow(t *testing.T) {
+	mem := newMockMemory(1, 2)
+	a := NewAllocator(mem, pageSize-100)
+
+	ptr, err := a.Allocate(pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem.Length() != 2*pageSize {
+		t.Fatalf("Fail: got memory length %d expected %d", mem.Length(), 2*pageSize)
+	}
+
+	// the memory can be written to after it grows
+	mem.Data()[ptr+pageSize-1] = 1
+
+	// growing past the memory's maximum fails
+	_, err = a.Allocate(pageSize)
+	if err == nil {
+		t.Fatal("Fail: expected error when memory is exhausted")
+	}
+
+	// the memory which was already allocated is still usable
+	err = a.Deallocate(ptr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptr2, err := a.Allocate(pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr2 != ptr {
+		t.Fatalf("Fail: got %d expected %d", ptr2, ptr)
+	}
+}
+
+func TestAllocator_Clear(t *testing.T) {
+	mem := newMockMemory(1, 1)
+	a := NewAllocator(mem, 0)
+
+	ptr, err := a.Allocate(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.Clear()
+
+	ptr2, err := a.Allocate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr2 != ptr {
+		t.Fatalf("Fail: got %d expected %d", ptr2, ptr)
+	}
+}
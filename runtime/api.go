@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ChainSafe/gossamer/core/types"
+)
+
+// names of the runtime API functions exported by the runtime
+const (
+	CoreVersion                = "Core_version"
+	CoreInitializeBlock        = "Core_initialize_block"
+	CoreExecuteBlock           = "Core_execute_block"
+	BlockBuilderApplyExtrinsic = "BlockBuilder_apply_extrinsic"
+	BlockBuilderFinalizeBlock  = "BlockBuilder_finalize_block"
+)
+
+// ErrDispatchFailed is returned by ApplyExtrinsic when the extrinsic was included in the block, but the call it
+// dispatched failed
+var ErrDispatchFailed = errors.New("extrinsic dispatch failed")
+
+// ApplyError is returned by ApplyExtrinsic when the runtime refuses to include the extrinsic in the block
+type ApplyError byte
+
+// reasons the runtime can refuse to include an extrinsic
+const (
+	BadSignature ApplyError = iota
+	Stale
+	Future
+	CantPay
+	FullBlock
+)
+
+var applyErrorMessages = map[ApplyError]string{
+	BadSignature: "bad signature",
+	Stale:        "nonce too low",
+	Future:       "nonce too high",
+	CantPay:      "cannot pay fees",
+	FullBlock:    "block is full",
+}
+
+func (e ApplyError) Error() string {
+	if msg, ok := applyErrorMessages[e]; ok {
+		return "cannot apply extrinsic: " + msg
+	}
+	return fmt.Sprintf("cannot apply extrinsic: unknown error %d", byte(e))
+}
+
+// Version calls Core_version, returning the version of the runtime
+func (r *Runtime) Version(ctx context.Context) (*Version, error) {
+	ret, err := r.exec(ctx, CoreVersion, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := decodeToInterface(ret, &Version{})
+	if err != nil {
+		return nil, err
+	}
+
+	return version.(*Version), nil
+}
+
+// InitializeBlock calls Core_initialize_block, starting the execution of the block with the given header
+func (r *Runtime) InitializeBlock(ctx context.Context, header *types.Header) error {
+	enc, err := header.Encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.exec(ctx, CoreInitializeBlock, enc)
+	return err
+}
+
+// ApplyExtrinsic calls BlockBuilder_apply_extrinsic, applying the extrinsic to the block being built
+// if the runtime refuses the extrinsic an ApplyError is returned; if the extrinsic is included but its call fails,
+// ErrDispatchFailed is returned
+func (r *Runtime) ApplyExtrinsic(ctx context.Context, ext types.Extrinsic) error {
+	ret, err := r.exec(ctx, BlockBuilderApplyExtrinsic, ext)
+	if err != nil {
+		return err
+	}
+
+	return decodeApplyResult(ret)
+}
+
+// decodeApplyResult decodes the result of BlockBuilder_apply_extrinsic, which is a
+// Result<ApplyOutcome, ApplyError> where ApplyOutcome is 0 for success and 1 for failure
+func decodeApplyResult(ret []byte) error {
+	if len(ret) != 2 {
+		return fmt.Errorf("invalid apply extrinsic result %x", ret)
+	}
+
+	switch ret[0] {
+	case 0:
+		if ret[1] == 0 {
+			return nil
+		}
+		return ErrDispatchFailed
+	case 1:
+		return ApplyError(ret[1])
+	default:
+		return fmt.Errorf("invalid apply extrinsic result %x", ret)
+	}
+}
+
+// FinalizeBlock calls BlockBuilder_finalize_block, finishing the block being built and returning its header
+func (r *Runtime) FinalizeBlock(ctx context.Context) (*types.Header, error) {
+	ret, err := r.exec(ctx, BlockBuilderFinalizeBlock, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	return types.DecodeHeader(bytes.NewReader(ret))
+}
+
+// ExecuteBlock calls Core_execute_block, executing every extrinsic in the block and checking the resulting state
+// against the block's header
+func (r *Runtime) ExecuteBlock(ctx context.Context, block *types.Block) error {
+	enc, err := block.Encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.exec(ctx, CoreExecuteBlock, enc)
+	return err
+}
+
+// exec writes the input to the runtime's memory and calls the exported function with it, returning its output.
+// the call is stopped if the context is done before it returns, see Exec
+func (r *Runtime) exec(ctx context.Context, function string, input []byte) ([]byte, error) {
+	// an abandoned call may still be using the memory
+	if r.Abandoned() {
+		return nil, ErrAbandoned
+	}
+
+	// memory allocated during the previous call is no longer needed, so each call starts with an empty heap
+	r.allocator.Clear()
+
+	ptr, err := r.allocator.Allocate(uint32(len(input)))
+	if err != nil {
+		return nil, err
+	}
+
+	mem := r.instance.Memory().Data()
+	copy(mem[ptr:ptr+uint32(len(input))], input)
+
+	return r.Exec(ctx, function, int32(ptr), int32(len(input)))
+}
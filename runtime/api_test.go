@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeApplyResult(t *testing.T) {
+	tests := []struct {
+		ret      []byte
+		expected error
+	}{
+		{[]byte{0, 0}, nil},
+		{[]byte{0, 1}, ErrDispatchFailed},
+		{[]byte{1, 0}, BadSignature},
+		{[]byte{1, 4}, FullBlock},
+	}
+
+	for _, test := range tests {
+		err := decodeApplyResult(test.ret)
+		if err != test.expected {
+			t.Errorf("Fail: got %v expected %v for result %x", err, test.expected, test.ret)
+		}
+	}
+
+	err := decodeApplyResult([]byte{2, 0})
+	if err == nil {
+		t.Fatal("Fail: expected error for invalid result")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	expected := &Version{
+		Spec_name:         []byte("polkadot"),
+		Impl_name:         []byte("parity-polkadot"),
+		Authoring_version: 1,
+		Spec_version:      1000,
+		Impl_version:      0,
+	}
+
+	for _, executor := range Executors() {
+		t.Run(executor, func(t *testing.T) {
+			r, err := newRuntime(t, executor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			version, err := r.Version(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(version, expected) {
+				t.Errorf("Fail: got %v expected %v\n", version, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package runtime
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/common"
+	trie "github.com/ChainSafe/gossamer/trie"
+	log "github.com/ChainSafe/log15"
+)
+
+// DefaultModuleCacheSize is the number of compiled runtimes a ModuleCache keeps by default
+const DefaultModuleCacheSize = 4
+
+// compiledModule is a compiled runtime, which runtimes can be instantiated from
+type compiledModule struct {
+	// hash is the blake2b hash of the code the module was compiled from
+	hash   common.Hash
+	module Module
+	// heapBase is the address the module's heap starts at, or 0 if the module doesn't say and doesn't declare
+	// a memory
+	heapBase uint32
+}
+
+// compile compiles the runtime code, whose blake2b hash is given, with the executor
+func compile(executor Executor, code []byte, hash common.Hash) (*compiledModule, error) {
+	// the host functions are bound to each instance's context when it's instantiated, so a context
+	// without any state is enough to tell the executor what the runtime can import
+	module, err := executor.Compile(code, (&hostContext{}).imports())
+	if err != nil {
+		return nil, err
+	}
+
+	// the heap starts after the module's stack and static data; if the module doesn't say where that is, the
+	// heap is started at the end of the memory the module declares. executors may give instances more memory
+	// than that up front, so the length of an instance's memory is only used if the module doesn't declare one
+	base, err := moduleHeapBase(code)
+	if err != nil {
+		log.Warn("cannot find heap base of runtime", "code", hash, "err", err)
+	}
+
+	return &compiledModule{
+		hash:     hash,
+		module:   module,
+		heapBase: base,
+	}, nil
+}
+
+// moduleHeapBase returns the address the heap of the module starts at: the value of its __heap_base, or the end of
+// the memory it declares if it doesn't export one
+func moduleHeapBase(code []byte) (uint32, error) {
+	m, err := decodeModule(code)
+	if err != nil {
+		return 0, err
+	}
+
+	base, err := heapBase(m)
+	if err == nil {
+		return base, nil
+	}
+
+	pages, perr := memoryPages(m)
+	if perr != nil {
+		return 0, err
+	}
+
+	return pages * pageSize, nil
+}
+
+// instantiate returns a new instance of the module, with the trie as its storage
+func (m *compiledModule) instantiate(t *trie.Trie) (*Runtime, error) {
+	h := &hostContext{
+		storage: NewStorage(t),
+	}
+
+	instance, err := m.module.Instantiate(h.imports())
+	if err != nil {
+		return nil, err
+	}
+
+	base := m.heapBase
+	if base == 0 {
+		base = instance.Memory().Length()
+	}
+
+	h.memory = instance.Memory()
+	h.allocator = NewAllocator(h.memory, base)
+
+	return &Runtime{
+		instance:  instance,
+		trie:      t,
+		storage:   h.storage,
+		allocator: h.allocator,
+		host:      h,
+		codeHash:  m.hash,
+	}, nil
+}
+
+// ModuleCache compiles runtime code, keeping the most recently used modules so that each version of the runtime
+// is compiled once, rather than every time it's instantiated. modules are identified by the blake2b hash of
+// their code, and the least recently used module is evicted when the cache is full. wagon compiles the code
+// when each instance is created, so with wagon the cache only saves parsing the module
+type ModuleCache struct {
+	executor Executor
+	size     int
+
+	lock sync.Mutex
+	// modules maps the hash of the code to its element in lru
+	modules map[common.Hash]*list.Element
+	// lru holds the *compiledModules, most recently used first
+	lru *list.List
+}
+
+// NewModuleCache returns a cache which compiles code with the executor, and holds up to size modules
+func NewModuleCache(executor Executor, size int) (*ModuleCache, error) {
+	if size < 1 {
+		return nil, errors.New("cannot create module cache: size must be at least 1")
+	}
+
+	return &ModuleCache{
+		executor: executor,
+		size:     size,
+		modules:  make(map[common.Hash]*list.Element),
+		lru:      list.New(),
+	}, nil
+}
+
+// NewRuntime returns a new instance of the runtime code, with the trie as its storage. the code is only
+// compiled if it isn't already in the cache
+func (c *ModuleCache) NewRuntime(code []byte, t *trie.Trie) (*Runtime, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m, err := c.get(code)
+	if err != nil {
+		return nil, err
+	}
+
+	// instantiating while holding the lock makes sure the module isn't evicted and closed underneath us
+	return m.instantiate(t)
+}
+
+// Contains returns true if the module compiled from the code with the given hash is in the cache
+func (c *ModuleCache) Contains(hash common.Hash) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, ok := c.modules[hash]
+	return ok
+}
+
+// Len returns the number of modules in the cache
+func (c *ModuleCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lru.Len()
+}
+
+// Close closes and removes every module in the cache. runtimes which were already instantiated can still be used
+func (c *ModuleCache) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*compiledModule).module.Close()
+	}
+	c.modules = make(map[common.Hash]*list.Element)
+	c.lru.Init()
+}
+
+// get returns the module compiled from the code, compiling and adding it to the cache if it isn't there
+func (c *ModuleCache) get(code []byte) (*compiledModule, error) {
+	hash, err := common.Blake2bHash(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := c.modules[hash]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*compiledModule), nil
+	}
+
+	m, err := compile(c.executor, code, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("[ModuleCache] compiled runtime", "code", hash)
+	c.modules[hash] = c.lru.PushFront(m)
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		evicted := c.lru.Remove(oldest).(*compiledModule)
+		delete(c.modules, evicted.hash)
+		evicted.module.Close()
+		log.Debug("[ModuleCache] evicted runtime", "code", evicted.hash)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,216 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/trie"
+)
+
+// mockExecutor counts the modules it compiles, which are instantiated with a mockMemory
+type mockExecutor struct {
+	compiled int
+}
+
+func (e *mockExecutor) Compile(code []byte, imports Imports) (Module, error) {
+	e.compiled++
+	return &mockModule{}, nil
+}
+
+type mockModule struct {
+	closed bool
+}
+
+func (m *mockModule) Instantiate(imports Imports) (Instance, error) {
+	return &mockInstance{memory: newMockMemory(1, 1)}, nil
+}
+
+func (m *mockModule) Close() {
+	m.closed = true
+}
+
+type mockInstance struct {
+	memory *mockMemory
+	// stopped is set to 1 by Stop, which is called by another goroutine once an abandoned call returns
+	stopped int32
+	// call is called by Call if it's set
+	call func(function string, args ...int32) (int64, error)
+	// interrupt is called by Interrupt if it's set
+	interrupt func() bool
+}
+
+func (i *mockInstance) Call(function string, args ...int32) (res int64, err error) {
+	// a fault aborts the call and is returned as an error, as it is by wagon
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("exec: %v", r)
+		}
+	}()
+
+	if i.call != nil {
+		return i.call(function, args...)
+	}
+	return 0, nil
+}
+
+func (i *mockInstance) Memory() Memory {
+	return i.memory
+}
+
+func (i *mockInstance) Interrupt() bool {
+	if i.interrupt != nil {
+		return i.interrupt()
+	}
+	return false
+}
+
+func (i *mockInstance) Stop() {
+	atomic.StoreInt32(&i.stopped, 1)
+}
+
+func (i *mockInstance) isStopped() bool {
+	return atomic.LoadInt32(&i.stopped) != 0
+}
+
+func TestModuleCache(t *testing.T) {
+	e := &mockExecutor{}
+	c, err := NewModuleCache(e, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codeA, codeB, codeC := []byte("noot"), []byte("gossamer"), []byte("polkadot")
+	hashA, err := common.Blake2bHash(codeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashC, err := common.Blake2bHash(codeC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same code is only compiled once
+	for i := 0; i < 2; i++ {
+		_, err = c.NewRuntime(codeA, &trie.Trie{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.compiled != 1 {
+		t.Fatalf("Fail: compiled %d modules, expected 1", e.compiled)
+	}
+	moduleA := c.modules[hashA].Value.(*compiledModule).module.(*mockModule)
+
+	_, err = c.NewRuntime(codeB, &trie.Trie{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.NewRuntime(codeC, &trie.Trie{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.compiled != 3 {
+		t.Fatalf("Fail: compiled %d modules, expected 3", e.compiled)
+	}
+
+	// the least recently used module is evicted and closed
+	if c.Len() != 2 {
+		t.Fatalf("Fail: cache holds %d modules, expected 2", c.Len())
+	}
+	if c.Contains(hashA) || !moduleA.closed {
+		t.Fatal("Fail: least recently used module was not evicted")
+	}
+
+	// using a module moves it to the front, so codeC is evicted next
+	_, err = c.NewRuntime(codeB, &trie.Trie{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.NewRuntime(codeA, &trie.Trie{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.compiled != 4 {
+		t.Fatalf("Fail: compiled %d modules, expected 4", e.compiled)
+	}
+	if c.Contains(hashC) || !c.Contains(hashA) {
+		t.Fatal("Fail: expected module compiled from codeC to be evicted")
+	}
+
+	c.Close()
+	if c.Len() != 0 {
+		t.Fatalf("Fail: cache holds %d modules after closing, expected 0", c.Len())
+	}
+
+	_, err = NewModuleCache(e, 0)
+	if err == nil {
+		t.Fatal("Fail: expected error for empty cache")
+	}
+}
+
+func TestModuleCache_NewRuntime(t *testing.T) {
+	_, err := getRuntimeBlob()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := ioutil.ReadFile(POLKADOT_RUNTIME_FP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, executor := range Executors() {
+		t.Run(executor, func(t *testing.T) {
+			e, err := NewExecutor(executor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := NewModuleCache(e, DefaultModuleCacheSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			// instances created from the same module don't share memory or storage
+			r1, err := c.NewRuntime(code, &trie.Trie{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r1.Stop()
+
+			r2, err := c.NewRuntime(code, &trie.Trie{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r2.Stop()
+
+			if c.Len() != 1 {
+				t.Fatalf("Fail: cache holds %d modules, expected 1", c.Len())
+			}
+
+			ptr, err := r1.allocator.Allocate(4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			copy(r1.instance.Memory().Data()[ptr:], []byte{1, 2, 3, 4})
+			if r2.instance.Memory().Data()[ptr] == 1 {
+				t.Fatal("Fail: runtimes share memory")
+			}
+
+			for _, r := range []*Runtime{r1, r2} {
+				version, err := r.Version(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if version.Spec_version != 1000 {
+					t.Fatalf("Fail: got spec version %d expected 1000", version.Spec_version)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"errors"
+
+	common "github.com/ChainSafe/gossamer/common"
+	trie "github.com/ChainSafe/gossamer/trie"
+)
+
+// ExtrinsicIndexKey is the storage key under which the runtime stores the index of the extrinsic being applied
+var ExtrinsicIndexKey = []byte(":extrinsic_index")
+
+// ChangesTrieConfigKey is the storage key of the changes trie configuration; changes tries are only built
+// if it's set
+var ChangesTrieConfigKey = []byte(":changes_trie")
+
+// StartBlock clears the recorded changes so that the changes made while building or executing the block
+// with the given number can be recorded. it can't be called while a transaction is open
+func (s *Storage) StartBlock(number uint64) error {
+	if len(s.overlays) != 0 {
+		return errors.New("cannot start block: transaction open")
+	}
+
+	s.block = number
+	s.changes = []*trie.ChangeSet{trie.NewChangeSet()}
+	return nil
+}
+
+// Changes returns the keys modified in the current block by committed changes
+func (s *Storage) Changes() *trie.ChangeSet {
+	return s.changes[0]
+}
+
+// record records that the key was modified by the extrinsic currently being applied
+func (s *Storage) record(key []byte) error {
+	extrinsic, err := s.extrinsicIndex()
+	if err != nil {
+		return err
+	}
+
+	s.changes[len(s.changes)-1].Record(key, extrinsic)
+	return nil
+}
+
+// extrinsicIndex returns the index of the extrinsic currently being applied, as stored by the runtime,
+// or trie.NoExtrinsicIndex if no extrinsic is being applied
+func (s *Storage) extrinsicIndex() (uint32, error) {
+	enc, err := s.current().Get(ExtrinsicIndexKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(enc) < 4 {
+		return trie.NoExtrinsicIndex, nil
+	}
+
+	return binary.LittleEndian.Uint32(enc), nil
+}
+
+// ChangesTrieRoot returns the root of the changes trie of the current block, including uncommitted changes
+// if changes tries aren't enabled by the runtime, it returns false
+func (s *Storage) ChangesTrieRoot() (common.Hash, bool, error) {
+	config, err := s.current().Get(ChangesTrieConfigKey)
+	if err != nil || config == nil {
+		return [32]byte{}, false, err
+	}
+
+	changes := trie.NewChangeSet()
+	for _, c := range s.changes {
+		changes.Merge(c)
+	}
+
+	t, err := trie.BuildChangesTrie(nil, s.block, changes)
+	if err != nil {
+		return [32]byte{}, false, err
+	}
+
+	root, err := t.Hash()
+	if err != nil {
+		return [32]byte{}, false, err
+	}
+
+	return root, true, nil
+}
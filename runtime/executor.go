@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+)
+
+// names of the executors that can run the runtime
+const (
+	// WasmerExecutor compiles the runtime with wasmer, which needs cgo
+	WasmerExecutor = "wasmer"
+	// WagonExecutor interprets the runtime with wagon, which is written in pure Go
+	WagonExecutor = "wagon"
+)
+
+// DefaultHeapPages is the number of pages of memory reserved for the heap of each instance when none is
+// configured. it's the default heap size of substrate's executor
+const DefaultHeapPages = 1024
+
+// Config is the configuration of the runtime
+type Config struct {
+	// Executor is the name of the executor the runtime is run with
+	Executor string
+	// HeapPages is the number of pages of memory reserved for the heap of each instance, by executors which
+	// can't grow memory when the allocator runs out, which is only wagon. if it's 0, DefaultHeapPages is used
+	HeapPages uint32
+}
+
+// Imports maps the names of the host functions a module can import to their implementations. the implementations
+// are funcs which take int32 and int64 parameters and return at most one int32 or int64
+type Imports map[string]interface{}
+
+// Executor compiles wasm modules
+type Executor interface {
+	// Compile compiles the code. the module may only import the host functions in imports; only their signatures
+	// are used, the implementations are given to each instance by Module.Instantiate
+	Compile(code []byte, imports Imports) (Module, error)
+}
+
+// Module is a compiled wasm module, which can be instantiated any number of times
+type Module interface {
+	// Instantiate instantiates the module, resolving its imports to the host functions
+	Instantiate(imports Imports) (Instance, error)
+	// Close releases the resources held by the module; instances which were already created can still be used
+	Close()
+}
+
+// Instance is an instantiated wasm module
+type Instance interface {
+	// Call calls the exported function with the arguments, returning its result, or 0 if it doesn't return anything
+	Call(function string, args ...int32) (int64, error)
+	// Memory returns the memory exported by the module
+	Memory() Memory
+	// Interrupt stops the call that's running, and can be called from another goroutine. it returns false if the
+	// executor can't interrupt calls, in which case the call keeps running
+	Interrupt() bool
+	// Stop releases the resources held by the instance
+	Stop()
+}
+
+// executors holds the constructors of the executors built into the binary, by name
+var executors = make(map[string]func(cfg *Config) Executor)
+
+// registerExecutor makes the executor available to NewExecutor
+func registerExecutor(name string, newExecutor func(cfg *Config) Executor) {
+	executors[name] = newExecutor
+}
+
+// Executors returns the names of the executors built into the binary; wasmer is only available when
+// building with cgo
+func Executors() []string {
+	names := []string{}
+	for name := range executors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultExecutor returns the name of the executor used when none is configured, which is wasmer if it's built
+// into the binary, since it's much faster, or wagon otherwise
+func DefaultExecutor() string {
+	if _, ok := executors[WasmerExecutor]; ok {
+		return WasmerExecutor
+	}
+	return WagonExecutor
+}
+
+// NewExecutor returns the executor with the given name, or the default executor if the name is empty
+func NewExecutor(name string) (Executor, error) {
+	return NewExecutorFromConfig(&Config{Executor: name})
+}
+
+// NewExecutorFromConfig returns the executor configured by cfg, or the default executor if cfg is nil or
+// doesn't name one
+func NewExecutorFromConfig(cfg *Config) (Executor, error) {
+	fig := Config{}
+	if cfg != nil {
+		fig = *cfg
+	}
+	if fig.Executor == "" {
+		fig.Executor = DefaultExecutor()
+	}
+	if fig.HeapPages == 0 {
+		fig.HeapPages = DefaultHeapPages
+	}
+
+	newExecutor, ok := executors[fig.Executor]
+	if !ok {
+		return nil, fmt.Errorf("unknown runtime executor %q, expected one of %v", fig.Executor, Executors())
+	}
+
+	return newExecutor(&fig), nil
+}
@@ -0,0 +1,25 @@
+//go:build !cgo
+// +build !cgo
+
+package runtime
+
+import (
+	"testing"
+)
+
+func TestDefaultExecutor_NoCgo(t *testing.T) {
+	// wasmer needs cgo, so the default executor must be one which is built without it
+	if DefaultExecutor() != WagonExecutor {
+		t.Fatalf("Fail: got default executor %s expected %s", DefaultExecutor(), WagonExecutor)
+	}
+
+	_, err := NewExecutor("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewExecutor(WasmerExecutor)
+	if err == nil {
+		t.Fatal("Fail: expected error for wasmer without cgo")
+	}
+}
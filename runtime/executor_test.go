@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"testing"
+)
+
+func TestNewExecutor(t *testing.T) {
+	for _, name := range Executors() {
+		_, err := NewExecutor(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := NewExecutor("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewExecutor("noot")
+	if err == nil {
+		t.Fatal("Fail: expected error for unknown executor")
+	}
+}
+
+func TestDefaultExecutor(t *testing.T) {
+	// wasmer is preferred when it's built in
+	expected := WagonExecutor
+	for _, name := range Executors() {
+		if name == WasmerExecutor {
+			expected = WasmerExecutor
+		}
+	}
+
+	if DefaultExecutor() != expected {
+		t.Fatalf("Fail: got default executor %s expected %s", DefaultExecutor(), expected)
+	}
+}
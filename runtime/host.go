@@ -0,0 +1,494 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	common "github.com/ChainSafe/gossamer/common"
+	sr25519 "github.com/ChainSafe/gossamer/crypto/sr25519"
+	trie "github.com/ChainSafe/gossamer/trie"
+	log "github.com/ChainSafe/log15"
+	xxhash "github.com/OneOfOne/xxhash"
+	ed25519 "golang.org/x/crypto/ed25519"
+)
+
+// hostContext is the state the host functions of a runtime instance operate on
+type hostContext struct {
+	memory    Memory
+	storage   *Storage
+	allocator *Allocator
+	// fault is the first out of bounds memory access made by a host function during the current call, see trap
+	fault *MemoryAccessError
+	// interrupted is set when the current call is interrupted; host functions can't access memory after that
+	interrupted int32
+}
+
+// imports returns the host functions bound to the context, by the names the runtime imports them as
+func (h *hostContext) imports() Imports {
+	return Imports{
+		"ext_print_num":                       h.ext_print_num,
+		"ext_malloc":                          h.ext_malloc,
+		"ext_free":                            h.ext_free,
+		"ext_print_utf8":                      h.ext_print_utf8,
+		"ext_print_hex":                       h.ext_print_hex,
+		"ext_get_storage_into":                h.ext_get_storage_into,
+		"ext_set_storage":                     h.ext_set_storage,
+		"ext_set_child_storage":               h.ext_set_child_storage,
+		"ext_get_child_storage_into":          h.ext_get_child_storage_into,
+		"ext_get_allocated_child_storage":     h.ext_get_allocated_child_storage,
+		"ext_exists_child_storage":            h.ext_exists_child_storage,
+		"ext_clear_child_storage":             h.ext_clear_child_storage,
+		"ext_kill_child_storage":              h.ext_kill_child_storage,
+		"ext_child_storage_root":              h.ext_child_storage_root,
+		"ext_storage_root":                    h.ext_storage_root,
+		"ext_storage_changes_root":            h.ext_storage_changes_root,
+		"ext_get_allocated_storage":           h.ext_get_allocated_storage,
+		"ext_clear_storage":                   h.ext_clear_storage,
+		"ext_clear_prefix":                    h.ext_clear_prefix,
+		"ext_blake2_256_enumerated_trie_root": h.ext_blake2_256_enumerated_trie_root,
+		"ext_blake2_256":                      h.ext_blake2_256,
+		"ext_twox_128":                        h.ext_twox_128,
+		"ext_sr25519_verify":                  h.ext_sr25519_verify,
+		"ext_ed25519_verify":                  h.ext_ed25519_verify,
+	}
+}
+
+func (h *hostContext) ext_print_num(data int64) {
+	log.Debug("[ext_print_num] executing...")
+	log.Debug("[ext_print_num]", "message", fmt.Sprintf("%d", data))
+}
+
+// allocates `size` bytes on the heap and returns their location, or 0 if the memory can't be allocated
+func (h *hostContext) ext_malloc(size int32) int32 {
+	log.Debug("[ext_malloc] executing...")
+	defer h.trap("ext_malloc")
+
+	log.Debug("[ext_malloc]", "size", size)
+	h.checkAccess()
+
+	ptr, err := h.allocator.Allocate(uint32(size))
+	if err != nil {
+		log.Error("[ext_malloc]", "error", err)
+		return 0
+	}
+	return int32(ptr)
+}
+
+// frees the memory at `addr`, which was allocated by ext_malloc
+func (h *hostContext) ext_free(addr int32) {
+	log.Debug("[ext_free] executing...")
+	defer h.trap("ext_free")
+
+	log.Debug("[ext_free]", "addr", addr)
+
+	// the allocator reads the block's header, which is in front of the address
+	h.memoryRange(uint32(addr)-headerSize, headerSize)
+
+	err := h.allocator.Deallocate(uint32(addr))
+	if err != nil {
+		log.Error("[ext_free]", "error", err)
+	}
+}
+
+// prints string located in memory at location `offset` with length `size`
+func (h *hostContext) ext_print_utf8(utf8_data, utf8_len int32) {
+	log.Debug("[ext_print_utf8] executing...")
+	defer h.trap("ext_print_utf8")
+
+	log.Debug("[ext_print_utf8]", "message", fmt.Sprintf("%s", h.slice(utf8_data, utf8_len)))
+}
+
+// prints hex formatted bytes located in memory at location `offset` with length `size`
+func (h *hostContext) ext_print_hex(offset, size int32) {
+	log.Debug("[ext_print_hex] executing...")
+	defer h.trap("ext_print_hex")
+
+	log.Debug("[ext_print_hex]", "message", fmt.Sprintf("%x", h.slice(offset, size)))
+}
+
+// gets the key stored at memory location `keyData` with length `keyLen` and stores the value in memory at
+// location `valueData`. the value can have up to value `valueLen` and the returned value starts at value[valueOffset:]
+func (h *hostContext) ext_get_storage_into(keyData, keyLen, valueData, valueLen, valueOffset int32) int32 {
+	log.Debug("[ext_get_storage_into] executing...")
+	defer h.trap("ext_get_storage_into")
+	s := h.storage
+
+	key := h.slice(keyData, keyLen)
+	val, err := s.Get(key)
+	if err != nil || val == nil {
+		ret := 1<<32 - 1
+		return int32(ret)
+	}
+
+	return h.writeValue(val, valueData, valueLen, valueOffset)
+}
+
+// writeValue writes the value, starting at value[valueOffset:], into the buffer at `valueData` with length
+// `valueLen` and returns the number of bytes written, or 0 if the value doesn't fit in the buffer
+func (h *hostContext) writeValue(val []byte, valueData, valueLen, valueOffset int32) int32 {
+	if uint64(len(val)) > uint64(uint32(valueLen)) {
+		log.Error("[writeValue]", "error", "value exceeds allocated buffer length")
+		return 0
+	}
+
+	if uint64(uint32(valueOffset)) > uint64(len(val)) {
+		valueOffset = int32(len(val))
+	}
+
+	n := copy(h.slice(valueData, valueLen), val[uint32(valueOffset):])
+	return int32(n)
+}
+
+// puts the key at memory location `keyData` with length `keyLen` and value at memory location `valueData`
+// with length `valueLen` into the storage trie
+func (h *hostContext) ext_set_storage(keyData, keyLen, valueData, valueLen int32) {
+	log.Debug("[ext_set_storage] executing...")
+	defer h.trap("ext_set_storage")
+	s := h.storage
+
+	key := h.read(keyData, keyLen)
+	val := h.read(valueData, valueLen)
+	log.Debug("[ext_set_storage]", "key", key, "val", val)
+	err := s.Put(key, val)
+	if err != nil {
+		log.Error("[ext_set_storage]", "error", err)
+	}
+}
+
+// puts the key at memory location `keyData` with length `keyLen` and value at memory location `valueData`
+// with length `valueLen` into the child trie with the storage key at `storageKeyData`
+func (h *hostContext) ext_set_child_storage(storageKeyData, storageKeyLen, keyData, keyLen, valueData, valueLen int32) {
+	log.Debug("[ext_set_child_storage] executing...")
+	defer h.trap("ext_set_child_storage")
+	s := h.storage
+
+	storageKey := h.read(storageKeyData, storageKeyLen)
+	key := h.read(keyData, keyLen)
+	val := h.read(valueData, valueLen)
+	err := s.SetChildStorage(storageKey, key, val)
+	if err != nil {
+		log.Error("[ext_set_child_storage]", "error", err)
+	}
+}
+
+// gets the key stored at memory location `keyData` with length `keyLen` from the child trie with the storage key
+// at `storageKeyData` and stores the value in memory at location `valueData`, as ext_get_storage_into
+func (h *hostContext) ext_get_child_storage_into(storageKeyData, storageKeyLen, keyData, keyLen, valueData, valueLen, valueOffset int32) int32 {
+	log.Debug("[ext_get_child_storage_into] executing...")
+	defer h.trap("ext_get_child_storage_into")
+	s := h.storage
+
+	storageKey := h.slice(storageKeyData, storageKeyLen)
+	key := h.slice(keyData, keyLen)
+	val, err := s.GetChildStorage(storageKey, key)
+	if err != nil || val == nil {
+		ret := 1<<32 - 1
+		return int32(ret)
+	}
+
+	return h.writeValue(val, valueData, valueLen, valueOffset)
+}
+
+// gets the value stored at key at memory location `keyData` with length `keyLen` from the child trie with the storage
+// key at `storageKeyData`, and returns the location in memory where it's stored and stores its length in `writtenOut`
+func (h *hostContext) ext_get_allocated_child_storage(storageKeyData, storageKeyLen, keyData, keyLen, writtenOut int32) int32 {
+	log.Debug("[ext_get_allocated_child_storage] executing...")
+	defer h.trap("ext_get_allocated_child_storage")
+	s := h.storage
+
+	storageKey := h.slice(storageKeyData, storageKeyLen)
+	key := h.slice(keyData, keyLen)
+	val, err := s.GetChildStorage(storageKey, key)
+	if err != nil {
+		log.Error("[ext_get_allocated_child_storage]", "error", err)
+		return 0
+	}
+
+	return h.writeAllocated(val, writtenOut)
+}
+
+// returns 1 if the key at memory location `keyData` with length `keyLen` exists in the child trie with the
+// storage key at `storageKeyData`, 0 otherwise
+func (h *hostContext) ext_exists_child_storage(storageKeyData, storageKeyLen, keyData, keyLen int32) int32 {
+	log.Debug("[ext_exists_child_storage] executing...")
+	defer h.trap("ext_exists_child_storage")
+	s := h.storage
+
+	storageKey := h.slice(storageKeyData, storageKeyLen)
+	key := h.slice(keyData, keyLen)
+	val, err := s.GetChildStorage(storageKey, key)
+	if err != nil {
+		log.Error("[ext_exists_child_storage]", "error", err)
+		return 0
+	}
+
+	if val == nil {
+		return 0
+	}
+	return 1
+}
+
+// deletes the entry with key at memory location `keyData` with length `keyLen` from the child trie with the
+// storage key at `storageKeyData`
+func (h *hostContext) ext_clear_child_storage(storageKeyData, storageKeyLen, keyData, keyLen int32) {
+	log.Debug("[ext_clear_child_storage] executing...")
+	defer h.trap("ext_clear_child_storage")
+	s := h.storage
+
+	storageKey := h.read(storageKeyData, storageKeyLen)
+	key := h.read(keyData, keyLen)
+	err := s.ClearChildStorage(storageKey, key)
+	if err != nil {
+		log.Error("[ext_clear_child_storage]", "error", err)
+	}
+}
+
+// deletes the child trie with the storage key at memory location `storageKeyData` with length `storageKeyLen`
+func (h *hostContext) ext_kill_child_storage(storageKeyData, storageKeyLen int32) {
+	log.Debug("[ext_kill_child_storage] executing...")
+	defer h.trap("ext_kill_child_storage")
+	s := h.storage
+
+	storageKey := h.read(storageKeyData, storageKeyLen)
+	err := s.KillChildStorage(storageKey)
+	if err != nil {
+		log.Error("[ext_kill_child_storage]", "error", err)
+	}
+}
+
+// returns the location in memory of the root of the child trie with the storage key at `storageKeyData`
+// and stores its length in `writtenOut`
+func (h *hostContext) ext_child_storage_root(storageKeyData, storageKeyLen, writtenOut int32) int32 {
+	log.Debug("[ext_child_storage_root] executing...")
+	defer h.trap("ext_child_storage_root")
+	s := h.storage
+
+	storageKey := h.slice(storageKeyData, storageKeyLen)
+	root, err := s.ChildStorageRoot(storageKey)
+	if err != nil {
+		log.Error("[ext_child_storage_root]", "error", err)
+		return 0
+	}
+
+	return h.writeAllocated(root[:], writtenOut)
+}
+
+// returns the trie root in the memory location `resultPtr`
+func (h *hostContext) ext_storage_root(resultPtr int32) {
+	log.Debug("[ext_storage_root] executing...")
+	defer h.trap("ext_storage_root")
+	s := h.storage
+
+	root, err := s.Root()
+	if err != nil {
+		log.Error("[ext_storage_root]", "error", err)
+	}
+
+	h.write(resultPtr, root[:])
+}
+
+// stores the root of the changes trie of the current block in the memory location `result`
+// returns 1 if the root was stored, or 0 if changes tries aren't enabled
+func (h *hostContext) ext_storage_changes_root(parentHashData, parentHashLen, result int32) int32 {
+	log.Debug("[ext_storage_changes_root] executing...")
+	defer h.trap("ext_storage_changes_root")
+	s := h.storage
+
+	root, ok, err := s.ChangesTrieRoot()
+	if err != nil {
+		log.Error("[ext_storage_changes_root]", "error", err)
+		return 0
+	}
+
+	if !ok {
+		return 0
+	}
+
+	h.write(result, root[:])
+	return 1
+}
+
+// gets value stored at key at memory location `keyData` with length `keyLen` and returns the location
+// in memory where it's stored and stores its length in `writtenOut`
+func (h *hostContext) ext_get_allocated_storage(keyData, keyLen, writtenOut int32) int32 {
+	log.Debug("[ext_get_allocated_storage] executing...")
+	defer h.trap("ext_get_allocated_storage")
+	s := h.storage
+
+	key := h.slice(keyData, keyLen)
+	val, err := s.Get(key)
+	if err == nil && len(val) >= (1<<32) {
+		err = errors.New("retrieved value length exceeds 2^32")
+	}
+
+	if err != nil {
+		log.Error("[ext_get_allocated_storage]", "error", err)
+		return 0
+	}
+
+	return h.writeAllocated(val, writtenOut)
+}
+
+// allocates memory for the value, writes the value into it and its length into the 4 bytes at `writtenOut`, and
+// returns the location of the value; a nil value is written with length 2^32 - 1
+func (h *hostContext) writeAllocated(val []byte, writtenOut int32) int32 {
+	length := uint32(len(val))
+	if val == nil {
+		length = 1<<32 - 1
+	}
+
+	var ptr uint32
+	if val != nil {
+		var err error
+		ptr, err = h.allocator.Allocate(length)
+		if err != nil {
+			log.Error("[writeAllocated]", "error", err)
+			return 0
+		}
+	}
+
+	h.write(int32(ptr), val)
+	binary.LittleEndian.PutUint32(h.slice(writtenOut, 4), length)
+
+	// return ptr to value
+	return int32(ptr)
+}
+
+// deletes the trie entry with key at memory location `keyData` with length `keyLen`
+func (h *hostContext) ext_clear_storage(keyData, keyLen int32) {
+	log.Debug("[ext_sr25519_verify] executing...")
+	defer h.trap("ext_clear_storage")
+	s := h.storage
+
+	key := h.read(keyData, keyLen)
+	err := s.Delete(key)
+	if err != nil {
+		log.Error("[ext_storage_root]", "error", err)
+	}
+}
+
+// deletes all entries in the trie that have a key beginning with the prefix stored at `prefixData`
+func (h *hostContext) ext_clear_prefix(prefixData, prefixLen int32) {
+	log.Debug("[ext_clear_prefix] executing...")
+	defer h.trap("ext_clear_prefix")
+	s := h.storage
+
+	prefix := h.slice(prefixData, prefixLen)
+	err := s.ClearPrefix(prefix)
+	if err != nil {
+		log.Error("[ext_clear_prefix]", "err", err)
+	}
+}
+
+// accepts an array of values, puts them into a trie, and returns the root
+// the keys to the values are their position in the array
+func (h *hostContext) ext_blake2_256_enumerated_trie_root(valuesData, lensData, lensLen, result int32) {
+	log.Debug("[ext_blake2_256_enumerated_trie_root] executing...")
+	defer h.trap("ext_blake2_256_enumerated_trie_root")
+	t := &trie.Trie{}
+
+	// the lengths are read at once, so a huge count faults before anything is hashed
+	lens := h.memoryRange(uint32(lensData), uint64(uint32(lensLen))*4)
+
+	var i int32
+	var pos uint32 = 0
+	for i = 0; i < lensLen; i++ {
+		valueLen := binary.LittleEndian.Uint32(lens[4*int(i):])
+		value := append([]byte{}, h.memoryRange(uint32(valuesData)+pos, uint64(valueLen))...)
+		log.Debug("[ext_blake2_256_enumerated_trie_root]", "key", i, "value", fmt.Sprintf("%x", value), "valueLen", valueLen)
+		pos += valueLen
+
+		err := t.Put([]byte{byte(i)}, value)
+		if err != nil {
+			log.Error("[ext_blake2_256_enumerated_trie_root]", "error", err)
+		}
+	}
+
+	root, err := t.Hash()
+	if err != nil {
+		log.Error("[ext_blake2_256_enumerated_trie_root]", "error", err)
+	}
+
+	h.write(result, root[:])
+}
+
+// performs blake2b 256-bit hash of the byte array at memory location `data` with length `length` and saves the
+// hash at memory location `out`
+func (h *hostContext) ext_blake2_256(data, length, out int32) {
+	log.Debug("[ext_blake2_256] executing...")
+	defer h.trap("ext_blake2_256")
+
+	hash, err := common.Blake2bHash(h.slice(data, length))
+	if err != nil {
+		log.Error("[ext_blake2_256]", "error", err)
+	}
+
+	h.write(out, hash[:])
+}
+
+func (h *hostContext) ext_twox_128(data, len, out int32) {
+	log.Debug("[ext_twox_128] executing...")
+	defer h.trap("ext_twox_128")
+
+	value := h.slice(data, len)
+	log.Debug("[ext_twox_128]", "value", value)
+
+	// compute xxHash64 twice with seeds 0 and 1 applied on given byte array
+	h0 := xxhash.NewS64(0) // create xxHash with 0 seed
+	_, err := h0.Write(value)
+	if err != nil {
+		log.Error("[ext_twox_128]", "error", err)
+	}
+	res0 := h0.Sum64()
+	log.Debug("[ext_twox_128]", "xxH64(0) of value", res0)
+	hash0 := make([]byte, 8)
+	binary.LittleEndian.PutUint64(hash0, uint64(res0))
+
+	h1 := xxhash.NewS64(1) // create xxHash with 1 seed
+	_, err = h1.Write(value)
+	if err != nil {
+		log.Error("[ext_twox_128]", "error", err)
+	}
+	res1 := h1.Sum64()
+	log.Debug("[ext_twox_128]", "xxH64(1) of value", res1)
+	hash1 := make([]byte, 8)
+	binary.LittleEndian.PutUint64(hash1, uint64(res1))
+
+	//concatenaded result
+	both := append(hash0, hash1...)
+
+	h.write(out, both)
+}
+
+// verifies the sr25519 signature at memory location `sigData` of the message at `msgData` with length `msgLen`
+// by the public key at `pubkeyData`; returns 0 if the signature is valid, 1 otherwise
+func (h *hostContext) ext_sr25519_verify(msgData, msgLen, sigData, pubkeyData int32) int32 {
+	log.Debug("[ext_sr25519_verify] executing...")
+	defer h.trap("ext_sr25519_verify")
+
+	msg := h.slice(msgData, msgLen)
+	sig := h.slice(sigData, sr25519.SignatureLength)
+	pubkey := h.slice(pubkeyData, sr25519.PublicKeyLength)
+
+	if sr25519.Verify(pubkey, msg, sig) {
+		return 0
+	}
+
+	return 1
+}
+
+func (h *hostContext) ext_ed25519_verify(msgData, msgLen, sigData, pubkeyData int32) int32 {
+	log.Debug("[ext_ed25519_verify] executing...")
+	defer h.trap("ext_ed25519_verify")
+
+	msg := h.slice(msgData, msgLen)
+	sig := h.slice(sigData, 64)
+	pubkey := ed25519.PublicKey(h.slice(pubkeyData, 32))
+
+	if ed25519.Verify(pubkey, msg, sig) {
+		return 0
+	}
+
+	return 1
+}
@@ -0,0 +1,168 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/common"
+	trie "github.com/ChainSafe/gossamer/trie"
+	log "github.com/ChainSafe/log15"
+)
+
+// CodeKey is the storage key the runtime's wasm code is stored at
+var CodeKey = []byte(":code")
+
+// ErrNoCode is returned when loading the runtime from state which doesn't have any code stored at CodeKey
+var ErrNoCode = errors.New("cannot load runtime: no code stored at :code")
+
+// CodeUpgraded returns true if the changes recorded since StartBlock replaced the runtime's code with different
+// code. blocks built on top of the block must then be executed by the new runtime, see Manager
+func (r *Runtime) CodeUpgraded() (bool, error) {
+	if !r.storage.Changes().Contains(CodeKey) {
+		return false, nil
+	}
+
+	code, err := r.storage.Get(CodeKey)
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := common.Blake2bHash(code)
+	if err != nil {
+		return false, err
+	}
+
+	return hash != r.codeHash, nil
+}
+
+// Manager loads the runtime from the code stored in state. the code can be changed by any block, so a block is
+// executed by the runtime stored in its parent's state, from RuntimeAt. the runtime of the best block is kept
+// as the current runtime, and is swapped for the new code when the best block upgrades it. the compiled code
+// of recent runtimes is kept in the module cache, so blocks on forks which haven't upgraded can still be
+// executed by the old runtime without compiling it again
+type Manager struct {
+	cache *ModuleCache
+
+	// lock is held while the current runtime is being used, so it isn't swapped out underneath the caller
+	lock    sync.Mutex
+	current *Runtime
+}
+
+// NewManager returns a manager whose current runtime is loaded from the state of the best block
+func NewManager(cache *ModuleCache, state *trie.Trie) (*Manager, error) {
+	m := &Manager{
+		cache: cache,
+	}
+
+	r, err := m.RuntimeAt(state)
+	if err != nil {
+		return nil, err
+	}
+
+	m.current = r
+	return m, nil
+}
+
+// RuntimeAt returns a new instance of the runtime stored in the state, with the state as its storage
+func (m *Manager) RuntimeAt(state *trie.Trie) (*Runtime, error) {
+	code, err := state.Get(CodeKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return nil, ErrNoCode
+	}
+
+	return m.cache.NewRuntime(code, state)
+}
+
+// SetBestState makes the current runtime run on the state of the new best block. if the block changed the code,
+// the current runtime is replaced by the new one, and true is returned
+func (m *Manager) SetBestState(state *trie.Trie) (bool, error) {
+	code, err := state.Get(CodeKey)
+	if err != nil {
+		return false, err
+	}
+	if len(code) == 0 {
+		return false, ErrNoCode
+	}
+
+	hash, err := common.Blake2bHash(code)
+	if err != nil {
+		return false, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if hash == m.current.CodeHash() {
+		return false, m.current.SetTrie(state)
+	}
+
+	r, err := m.cache.NewRuntime(code, state)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info("[runtime] upgraded runtime", "previous", m.current.CodeHash(), "code", hash)
+	m.current.Stop()
+	m.current = r
+	return true, nil
+}
+
+// CurrentCodeHash returns the blake2b hash of the code of the current runtime
+func (m *Manager) CurrentCodeHash() common.Hash {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.current.CodeHash()
+}
+
+// WithCurrent calls f with the current runtime. the runtime must not be used after f returns, since it may be
+// replaced and stopped by SetBestState. if a call made by f timed out and the runtime was abandoned, it's
+// replaced by a new instance of the same code on the same state; the abandoned call only has a snapshot of the
+// state, so it can't change the state the new instance runs on
+func (m *Manager) WithCurrent(f func(r *Runtime) error) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	err := f(m.current)
+	if m.current.Abandoned() {
+		r, rerr := m.RuntimeAt(m.current.trie)
+		if rerr != nil {
+			log.Error("[runtime] cannot replace abandoned runtime", "err", rerr)
+			return err
+		}
+		m.current = r
+	}
+
+	return err
+}
+
+// Version returns the version of the current runtime, formatted by Version.String. it satisfies the
+// RuntimeApi of the api service. the call is stopped after DefaultCallTimeout
+func (m *Manager) Version() string {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+	defer cancel()
+
+	var version *Version
+	err := m.WithCurrent(func(r *Runtime) (err error) {
+		version, err = r.Version(ctx)
+		return err
+	})
+	if err != nil {
+		log.Error("[runtime] cannot get runtime version", "err", err)
+		return ""
+	}
+
+	return version.String()
+}
+
+// Stop stops the current runtime
+func (m *Manager) Stop() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.current.Stop()
+}
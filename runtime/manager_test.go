@@ -0,0 +1,228 @@
+package runtime
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/trie"
+)
+
+// newCodeTrie returns a trie with the code stored at :code
+func newCodeTrie(t *testing.T, code []byte) *trie.Trie {
+	tt := &trie.Trie{}
+	err := tt.Put(CodeKey, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tt
+}
+
+func TestManager_SetBestState(t *testing.T) {
+	e := &mockExecutor{}
+	c, err := NewModuleCache(e, DefaultModuleCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis := newCodeTrie(t, []byte("noot"))
+	m, err := NewManager(c, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := m.current
+
+	// a block which doesn't change the code keeps the current runtime, on top of the block's state
+	state := newCodeTrie(t, []byte("noot"))
+	upgraded, err := m.SetBestState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded || m.current != first || m.current.trie != state {
+		t.Fatal("Fail: runtime was replaced when the code didn't change")
+	}
+
+	// a block which changes the code replaces the current runtime, and the old one is stopped
+	upgrade := newCodeTrie(t, []byte("gossamer"))
+	upgraded, err = m.SetBestState(upgrade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !upgraded || m.current == first || m.current.trie != upgrade {
+		t.Fatal("Fail: runtime was not upgraded")
+	}
+	if !first.instance.(*mockInstance).isStopped() {
+		t.Fatal("Fail: old runtime was not stopped")
+	}
+	if m.CurrentCodeHash() == first.CodeHash() {
+		t.Fatal("Fail: current code hash was not updated")
+	}
+
+	// blocks on a fork which wasn't upgraded are still executed by the old code, without compiling it again
+	fork, err := m.RuntimeAt(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.CodeHash() != first.CodeHash() || e.compiled != 2 {
+		t.Fatalf("Fail: got code %x after compiling %d modules", fork.CodeHash(), e.compiled)
+	}
+
+	_, err = m.SetBestState(&trie.Trie{})
+	if err != ErrNoCode {
+		t.Fatalf("Fail: got %v expected %v", err, ErrNoCode)
+	}
+
+	_, err = NewManager(c, &trie.Trie{})
+	if err != ErrNoCode {
+		t.Fatalf("Fail: got %v expected %v", err, ErrNoCode)
+	}
+}
+
+func TestRuntime_CodeUpgraded(t *testing.T) {
+	c, err := NewModuleCache(&mockExecutor{}, DefaultModuleCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewManager(c, newCodeTrie(t, []byte("noot")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := m.RuntimeAt(newCodeTrie(t, []byte("noot")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		code     []byte
+		expected bool
+	}{
+		{nil, false},
+		{[]byte("noot"), false},
+		{[]byte("gossamer"), true},
+	}
+
+	for _, test := range tests {
+		err = r.StartBlock(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.code != nil {
+			err = r.storage.Put(CodeKey, test.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		upgraded, err := r.CodeUpgraded()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if upgraded != test.expected {
+			t.Errorf("Fail: got %t expected %t for code %s", upgraded, test.expected, test.code)
+		}
+	}
+}
+
+func TestManager_WithCurrent(t *testing.T) {
+	e := &mockExecutor{}
+	c, err := NewModuleCache(e, DefaultModuleCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewManager(c, newCodeTrie(t, []byte("noot")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := m.current
+
+	block := make(chan struct{})
+	first.instance.(*mockInstance).call = func(function string, args ...int32) (int64, error) {
+		<-block
+		return 0, first.storage.Put([]byte("noot"), []byte("was here"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = m.WithCurrent(func(r *Runtime) error {
+		_, err := r.Exec(ctx, "noot", 0, 0)
+		return err
+	})
+	if _, ok := err.(*TimeoutError); !ok {
+		t.Fatalf("Fail: got %v expected timeout", err)
+	}
+
+	// the abandoned runtime is replaced by a new instance of the cached module, on top of the same state
+	if m.current == first || m.current.trie != first.trie || e.compiled != 1 {
+		t.Fatal("Fail: abandoned runtime was not replaced")
+	}
+	if first.instance.(*mockInstance).isStopped() {
+		t.Fatal("Fail: abandoned instance was stopped")
+	}
+
+	// the new runtime writes to the state while the abandoned call finishes, which only wrote to its own snapshot
+	close(block)
+	err = m.WithCurrent(func(r *Runtime) error {
+		err := r.storage.Put([]byte("gossamer"), []byte("was here"))
+		if err != nil {
+			return err
+		}
+		_, err = r.storage.Root()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitStopped(t, first.instance.(*mockInstance))
+
+	val, err := m.current.trie.Get([]byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil {
+		t.Fatal("Fail: abandoned call wrote to the state")
+	}
+}
+
+func TestManager_Version(t *testing.T) {
+	_, err := getRuntimeBlob()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := ioutil.ReadFile(POLKADOT_RUNTIME_FP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, executor := range Executors() {
+		t.Run(executor, func(t *testing.T) {
+			e, err := NewExecutor(executor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := NewModuleCache(e, DefaultModuleCacheSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			m, err := NewManager(c, newCodeTrie(t, code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Stop()
+
+			expected := "polkadot-1000:parity-polkadot-0"
+			if m.Version() != expected {
+				t.Fatalf("Fail: got %s expected %s", m.Version(), expected)
+			}
+		})
+	}
+}
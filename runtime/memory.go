@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	log "github.com/ChainSafe/log15"
+)
+
+// MemoryAccessError is returned by Exec when the runtime passed a host function a range which isn't inside
+// the runtime's memory
+type MemoryAccessError struct {
+	// Function is the host function the range was passed to
+	Function string
+	Ptr      uint32
+	Length   uint64
+	// Size is the size of the memory when it was accessed
+	Size uint32
+}
+
+func (e *MemoryAccessError) Error() string {
+	return fmt.Sprintf("%s: out of bounds memory access: %d bytes at %d, memory size is %d", e.Function, e.Length, e.Ptr, e.Size)
+}
+
+// slice returns the length bytes of memory at ptr. pointers and lengths passed by the runtime are unsigned, so
+// they're converted before the range is checked
+func (h *hostContext) slice(ptr, length int32) []byte {
+	return h.memoryRange(uint32(ptr), uint64(uint32(length)))
+}
+
+// read returns a copy of the length bytes of memory at ptr. slices of memory change when the runtime writes to
+// them, so anything that's kept after the host function returns, like the keys and values put into storage, must
+// be read rather than sliced
+func (h *hostContext) read(ptr, length int32) []byte {
+	return append([]byte{}, h.slice(ptr, length)...)
+}
+
+// write copies the data into memory at ptr
+func (h *hostContext) write(ptr int32, data []byte) {
+	copy(h.memoryRange(uint32(ptr), uint64(len(data))), data)
+}
+
+// memoryRange returns the length bytes of memory at ptr. if the range isn't inside the memory it panics with a
+// *MemoryAccessError, which the host function's deferred call to trap recovers from. the memory is fetched on
+// every access, since allocating may have grown it
+func (h *hostContext) memoryRange(ptr uint32, length uint64) []byte {
+	h.checkAccess()
+
+	memory := h.memory.Data()
+	end := uint64(ptr) + length
+	if end > uint64(len(memory)) {
+		panic(&MemoryAccessError{
+			Ptr:    ptr,
+			Length: length,
+			Size:   uint32(len(memory)),
+		})
+	}
+
+	return memory[ptr:end:end]
+}
+
+// checkAccess panics if the call was interrupted, with errInterrupted, or if a previous access during the call
+// faulted, with that fault. host functions which access memory through the allocator call it directly
+func (h *hostContext) checkAccess() {
+	if atomic.LoadInt32(&h.interrupted) != 0 {
+		panic(errInterrupted)
+	}
+	if h.fault != nil {
+		panic(h.fault)
+	}
+}
+
+// errInterrupted is the panic that stops a host function called after the call was interrupted
+var errInterrupted = errors.New("runtime call interrupted")
+
+// trap must be deferred by every host function which accesses memory. if the function accessed memory out of
+// bounds, the fault is recorded so that Exec returns it. the panic is then continued to abort the call: wagon
+// unwinds the call and returns the panic as an error. wasmer can't unwind through host functions, so its
+// trampolines recover from the panic with recoverTrap and the host function returns zero values. the runtime
+// then keeps running until the call returns, but every memory access a host function makes after the fault
+// fails too
+func (h *hostContext) trap(function string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	if err, ok := r.(*MemoryAccessError); ok && h.fault == nil {
+		err.Function = function
+		h.fault = err
+		log.Error("[trap]", "error", err)
+	}
+
+	panic(r)
+}
+
+// recoverTrap recovers from the panic a host function aborts the call with when it faults or the call was
+// interrupted, see trap. it must be deferred by callers of host functions which can't be unwound through
+func recoverTrap() {
+	r := recover()
+	if _, ok := r.(*MemoryAccessError); ok || r == nil || r == errInterrupted {
+		return
+	}
+	panic(r)
+}
@@ -0,0 +1,191 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/trie"
+)
+
+// callHost calls the host function like wasmer does, recovering from the panic a fault aborts the call with
+func callHost(f func()) {
+	defer recoverTrap()
+	f()
+}
+
+func newTestHostContext() *hostContext {
+	mem := newMockMemory(1, 1)
+	return &hostContext{
+		memory:    mem,
+		storage:   NewStorage(&trie.Trie{}),
+		allocator: NewAllocator(mem, 1024),
+	}
+}
+
+func TestHostContext_OutOfBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(h *hostContext)
+	}{
+		{"ext_free", func(h *hostContext) { h.ext_free(pageSize + headerSize) }},
+		{"ext_free", func(h *hostContext) { h.ext_free(2) }},
+		{"ext_print_utf8", func(h *hostContext) { h.ext_print_utf8(pageSize-1, 2) }},
+		{"ext_set_storage", func(h *hostContext) { h.ext_set_storage(0, 1, pageSize, 1) }},
+		{"ext_set_storage", func(h *hostContext) { h.ext_set_storage(0, -1, 0, 1) }},
+		{"ext_get_storage_into", func(h *hostContext) { h.ext_get_storage_into(-8, 4, 0, 1, 0) }},
+		{"ext_get_allocated_storage", func(h *hostContext) { h.ext_get_allocated_storage(0, 4, pageSize-3) }},
+		{"ext_storage_root", func(h *hostContext) { h.ext_storage_root(pageSize - 31) }},
+		{"ext_blake2_256", func(h *hostContext) { h.ext_blake2_256(0, 32, pageSize) }},
+		{"ext_blake2_256_enumerated_trie_root", func(h *hostContext) { h.ext_blake2_256_enumerated_trie_root(0, 0, 1<<30, 0) }},
+		{"ext_sr25519_verify", func(h *hostContext) { h.ext_sr25519_verify(0, 0, pageSize-63, 0) }},
+		{"ext_ed25519_verify", func(h *hostContext) { h.ext_ed25519_verify(0, 0, 0, pageSize-31) }},
+	}
+
+	for _, test := range tests {
+		h := newTestHostContext()
+		callHost(func() { test.call(h) })
+
+		if h.fault == nil {
+			t.Fatalf("Fail: expected fault from %s", test.name)
+		}
+		if h.fault.Function != test.name {
+			t.Fatalf("Fail: got fault from %s expected %s", h.fault.Function, test.name)
+		}
+	}
+}
+
+func TestHostContext_FaultAbortsCall(t *testing.T) {
+	h := newTestHostContext()
+
+	// the fault is recorded, and the panic continues so the executor can unwind the call
+	defer func() {
+		if _, ok := recover().(*MemoryAccessError); !ok {
+			t.Fatal("Fail: expected fault to abort the call")
+		}
+		if h.fault == nil || h.fault.Function != "ext_free" {
+			t.Fatalf("Fail: got fault %v expected fault from ext_free", h.fault)
+		}
+	}()
+
+	h.ext_free(pageSize + headerSize)
+}
+
+func TestHostContext_FaultIsSticky(t *testing.T) {
+	h := newTestHostContext()
+
+	callHost(func() { h.ext_set_storage(0, 1, pageSize, 1) })
+	if h.fault == nil {
+		t.Fatal("Fail: expected fault")
+	}
+
+	// once a host function has faulted, later memory accesses fail too, so the runtime can't modify storage
+	callHost(func() { h.ext_set_storage(0, 1, 0, 1) })
+	val, err := h.storage.Get([]byte{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil {
+		t.Fatal("Fail: storage was modified after a fault")
+	}
+
+	var ptr int32
+	callHost(func() { ptr = h.ext_malloc(1) })
+	if ptr != 0 {
+		t.Fatal("Fail: memory was allocated after a fault")
+	}
+	if h.fault.Function != "ext_set_storage" || h.fault.Ptr != pageSize {
+		t.Fatalf("Fail: first fault was overwritten: %s", h.fault)
+	}
+}
+
+func TestHostContext_GetStorageIntoOffset(t *testing.T) {
+	h := newTestHostContext()
+
+	err := h.storage.Put([]byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(h.memory.Data(), []byte("noot"))
+
+	// an offset past the end of the value writes nothing, rather than slicing past the end of the value
+	n := h.ext_get_storage_into(0, 4, 100, 10, 1000)
+	if h.fault != nil {
+		t.Fatal(h.fault)
+	}
+	if n != 0 {
+		t.Fatalf("Fail: wrote %d bytes expected 0", n)
+	}
+
+	n = h.ext_get_storage_into(0, 4, 100, 10, 4)
+	if n != 4 || !bytes.Equal(h.memory.Data()[100:104], []byte("here")) {
+		t.Fatalf("Fail: got %s after writing %d bytes", h.memory.Data()[100:104], n)
+	}
+}
+
+func TestHostContext_SetStorageCopies(t *testing.T) {
+	h := newTestHostContext()
+
+	storageKey := append(append([]byte{}, trie.ChildStorageKeyPrefix...), []byte("child")...)
+	copy(h.memory.Data(), []byte("noot"))
+	copy(h.memory.Data()[100:], []byte("was here"))
+	copy(h.memory.Data()[200:], storageKey)
+
+	h.ext_set_storage(0, 4, 100, 8)
+	h.ext_set_child_storage(200, int32(len(storageKey)), 0, 4, 100, 8)
+	if h.fault != nil {
+		t.Fatal(h.fault)
+	}
+
+	// the runtime reuses its buffers after the call, which mustn't change what was stored
+	for i := range h.memory.Data() {
+		h.memory.Data()[i] = 0xff
+	}
+
+	val, err := h.storage.Get([]byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(val, []byte("was here")) {
+		t.Fatalf("Fail: got %x expected %x", val, []byte("was here"))
+	}
+
+	val, err = h.storage.GetChildStorage(storageKey, []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(val, []byte("was here")) {
+		t.Fatalf("Fail: got %x expected %x from child storage", val, []byte("was here"))
+	}
+}
+
+func TestExec_MemoryAccessError(t *testing.T) {
+	r, instance := newMockRuntime()
+	h := r.host
+
+	// a fault in a host function is returned from Exec
+	instance.call = func(function string, args ...int32) (int64, error) {
+		h.ext_blake2_256(0, 32, pageSize)
+		return 0, nil
+	}
+	_, err := r.Exec(context.Background(), "noot", 0, 0)
+	if e, ok := err.(*MemoryAccessError); !ok || e.Function != "ext_blake2_256" {
+		t.Fatalf("Fail: got %v expected fault from ext_blake2_256", err)
+	}
+
+	// the fault is cleared before the next call
+	instance.call = nil
+	_, err = r.Exec(context.Background(), "noot", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// so is an output outside the memory
+	instance.call = func(function string, args ...int32) (int64, error) {
+		return int64(8)<<32 | int64(pageSize-4), nil
+	}
+	_, err = r.Exec(context.Background(), "noot", 0, 0)
+	if e, ok := err.(*MemoryAccessError); !ok || e.Function != "noot" {
+		t.Fatalf("Fail: got %v expected fault from noot", err)
+	}
+}
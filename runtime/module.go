@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/go-interpreter/wagon/wasm"
+)
+
+// decodeModule decodes the sections of the wasm module, without resolving its imports
+func decodeModule(code []byte) (*wasm.Module, error) {
+	return wasm.DecodeModule(bytes.NewReader(code))
+}
+
+// heapBase returns the value of the __heap_base global exported by the wasm module, the address of the
+// first byte of memory after the module's stack and static data
+func heapBase(m *wasm.Module) (uint32, error) {
+	return exportedGlobal(m, "__heap_base")
+}
+
+// exportedGlobal returns the value of the constant i32 global exported by the wasm module with the given name
+func exportedGlobal(m *wasm.Module, name string) (uint32, error) {
+	if m.Export == nil {
+		return 0, fmt.Errorf("cannot find export %s", name)
+	}
+
+	export, ok := m.Export.Entries[name]
+	if !ok || export.Kind != wasm.ExternalGlobal {
+		return 0, fmt.Errorf("cannot find export %s", name)
+	}
+
+	// imported globals come before the module's own globals in the index space
+	index := export.Index
+	if m.Import != nil {
+		for _, entry := range m.Import.Entries {
+			if _, ok := entry.Type.(wasm.GlobalVarImport); !ok {
+				continue
+			}
+			if index == 0 {
+				return 0, fmt.Errorf("exported global %s is imported", name)
+			}
+			index--
+		}
+	}
+
+	if m.Global == nil || index >= uint32(len(m.Global.Globals)) {
+		return 0, fmt.Errorf("cannot find global %d", export.Index)
+	}
+
+	value, err := m.ExecInitExpr(m.Global.Globals[index].Init)
+	if err != nil {
+		return 0, fmt.Errorf("invalid initializer for global %d: %s", export.Index, err)
+	}
+
+	v, ok := value.(int32)
+	if !ok {
+		return 0, fmt.Errorf("global %d is not a constant i32", export.Index)
+	}
+
+	return uint32(v), nil
+}
+
+// memoryPages returns the initial size, in pages, of the memory defined by the wasm module
+func memoryPages(m *wasm.Module) (uint32, error) {
+	if m.Memory == nil || len(m.Memory.Entries) == 0 {
+		return 0, errors.New("module doesn't define a memory")
+	}
+
+	return m.Memory.Entries[0].Limits.Initial, nil
+}
@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestHeapBase(t *testing.T) {
+	code, err := ioutil.ReadFile(POLKADOT_RUNTIME_FP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := decodeModule(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := heapBase(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base != 1126872 {
+		t.Fatalf("Fail: got heap base %d expected %d", base, 1126872)
+	}
+
+	_, err = exportedGlobal(m, "noot")
+	if err == nil {
+		t.Fatal("Fail: expected error for missing export")
+	}
+}
+
+func TestHeapBase_ImportedGlobals(t *testing.T) {
+	m, err := decodeModule([]byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		// import section: env.g global i32 const, env.m memory with max
+		0x02, 0x14, 0x02,
+		0x03, 'e', 'n', 'v', 0x01, 'g', 0x03, 0x7f, 0x00,
+		0x03, 'e', 'n', 'v', 0x01, 'm', 0x02, 0x01, 0x01, 0x02,
+		// global section: two i32 globals, -1 and 1024
+		0x06, 0x0c, 0x02,
+		0x7f, 0x00, 0x41, 0x7f, 0x0b,
+		0x7f, 0x00, 0x41, 0x80, 0x08, 0x0b,
+		// export section: __heap_base is global 2, the second of the module's own globals, and g is global 0
+		0x07, 0x13, 0x02,
+		0x0b, '_', '_', 'h', 'e', 'a', 'p', '_', 'b', 'a', 's', 'e', 0x03, 0x02,
+		0x01, 'g', 0x03, 0x00,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := heapBase(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base != 1024 {
+		t.Fatalf("Fail: got heap base %d expected %d", base, 1024)
+	}
+
+	_, err = exportedGlobal(m, "g")
+	if err == nil {
+		t.Fatal("Fail: expected error for imported global")
+	}
+}
+
+func TestDecodeModule_Invalid(t *testing.T) {
+	tests := [][]byte{
+		[]byte("noot was here"),
+		// wrong version
+		{0x00, 0x61, 0x73, 0x6d, 0x02, 0x00, 0x00, 0x00},
+		// section longer than the module
+		{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x06, 0x7f, 0x01},
+		// global section with a truncated initializer
+		{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x06, 0x04, 0x01, 0x7f, 0x00, 0x41},
+	}
+
+	for _, test := range tests {
+		_, err := moduleHeapBase(test)
+		if err == nil {
+			t.Errorf("Fail: expected error for invalid module %x", test)
+		}
+	}
+}
+
+func TestHeapBase_NotConstant(t *testing.T) {
+	// __heap_base is initialized with an i64
+	m, err := decodeModule([]byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x06, 0x06, 0x01, 0x7e, 0x00, 0x42, 0x01, 0x0b,
+		0x07, 0x0f, 0x01,
+		0x0b, '_', '_', 'h', 'e', 'a', 'p', '_', 'b', 'a', 's', 'e', 0x03, 0x00,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = heapBase(m)
+	if err == nil {
+		t.Fatal("Fail: expected error for i64 heap base")
+	}
+}
+
+func TestMemoryPages(t *testing.T) {
+	m, err := decodeModule(wagonTestCode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := memoryPages(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pages != 1 {
+		t.Fatalf("Fail: got %d pages expected %d", pages, 1)
+	}
+
+	// without a __heap_base, the heap starts after the declared memory
+	base, err := moduleHeapBase(wagonTestCode())
+	if err != nil {
+		t.Fatal(err)
+	} else if base != pageSize {
+		t.Fatalf("Fail: got heap base %d expected %d", base, pageSize)
+	}
+
+	m, err = decodeModule([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = memoryPages(m)
+	if err == nil {
+		t.Fatal("Fail: expected error for module without memory")
+	}
+}
@@ -0,0 +1,244 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+
+	scale "github.com/ChainSafe/gossamer/codec"
+	"github.com/ChainSafe/gossamer/common"
+	trie "github.com/ChainSafe/gossamer/trie"
+	log "github.com/ChainSafe/log15"
+)
+
+type Runtime struct {
+	instance  Instance
+	trie      *trie.Trie
+	storage   *Storage
+	allocator *Allocator
+	host      *hostContext
+	// abandoned is set to 1 when a call times out and can't be interrupted
+	abandoned int32
+	// codeHash is the blake2b hash of the code the runtime was instantiated from
+	codeHash common.Hash
+}
+
+// NewRuntime instantiates the wasm module at fp with the executor, with the trie as its storage. the module is
+// compiled every time; use a ModuleCache to create runtimes which share compiled code
+func NewRuntime(fp string, t *trie.Trie, executor Executor) (*Runtime, error) {
+	code, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := common.Blake2bHash(code)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := compile(executor, code, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer m.module.Close()
+
+	return m.instantiate(t)
+}
+
+// Stop releases the resources held by the runtime. an abandoned runtime is stopped once the call that couldn't be
+// interrupted returns instead, since it's still using the instance
+func (r *Runtime) Stop() {
+	if r.Abandoned() {
+		return
+	}
+	r.instance.Stop()
+}
+
+// CodeHash returns the blake2b hash of the code the runtime is running
+func (r *Runtime) CodeHash() common.Hash {
+	return r.codeHash
+}
+
+// SetTrie replaces the trie the runtime uses as its storage, so the runtime can be reused to execute a block on
+// top of a different state. it can't be called while a transaction is open
+func (r *Runtime) SetTrie(t *trie.Trie) error {
+	err := r.storage.setTrie(t)
+	if err != nil {
+		return err
+	}
+
+	r.trie = t
+	return nil
+}
+
+// StartTransaction starts a storage transaction; changes made to storage by the runtime are buffered
+// until the transaction is committed or rolled back
+func (r *Runtime) StartTransaction() {
+	r.storage.StartTransaction()
+}
+
+// CommitTransaction applies the storage changes made since the last call to StartTransaction
+func (r *Runtime) CommitTransaction() error {
+	return r.storage.CommitTransaction()
+}
+
+// RollbackTransaction throws away the storage changes made since the last call to StartTransaction
+func (r *Runtime) RollbackTransaction() error {
+	return r.storage.RollbackTransaction()
+}
+
+// StartBlock clears the storage changes recorded for the changes trie, ready for the block with the given number
+func (r *Runtime) StartBlock(number uint64) error {
+	return r.storage.StartBlock(number)
+}
+
+// Changes returns the keys modified by the runtime since StartBlock was called, along with the extrinsics that
+// modified them. it can be stored with trie.ChangesTrieStore once the block is imported
+func (r *Runtime) Changes() *trie.ChangeSet {
+	return r.storage.Changes()
+}
+
+// DefaultCallTimeout is how long runtime calls made on behalf of RPC requests are allowed to run for
+const DefaultCallTimeout = 10 * time.Second
+
+// ErrAbandoned is returned by Exec when an earlier call timed out but couldn't be interrupted. that call may still
+// be running, so the runtime can't be used again and should be replaced by a new instance
+var ErrAbandoned = errors.New("cannot call runtime: an earlier call timed out and may still be running")
+
+// TimeoutError is returned by Exec when the call is stopped because its context was cancelled or its deadline
+// passed
+type TimeoutError struct {
+	Function string
+	// Err is the error of the context, context.Canceled or context.DeadlineExceeded
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s: runtime call stopped: %s", e.Function, e.Err)
+}
+
+// Exec calls the exported function with the pointer to and length of its input, and returns the output it
+// returns the pointer to and length of. if the context is done before the call returns, the call is interrupted,
+// its changes to storage are thrown away and a *TimeoutError is returned; if a host function accessed memory out
+// of bounds during the call, the *MemoryAccessError is returned. neither executor meters instructions, so calls
+// are only bounded by the context. wasmer can't interrupt calls either, so a wasmer call which times out keeps
+// running in the background, using a CPU until it returns by itself, and the runtime is abandoned. calls made on
+// behalf of untrusted callers should use wagon if they must be stopped
+func (r *Runtime) Exec(ctx context.Context, function string, data, dataLen int32) ([]byte, error) {
+	if r.Abandoned() {
+		return nil, ErrAbandoned
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &TimeoutError{Function: function, Err: err}
+	}
+
+	r.host.fault = nil
+	atomic.StoreInt32(&r.host.interrupted, 0)
+
+	res := r.call(ctx, function, data, dataLen)
+	if _, ok := res.err.(*TimeoutError); ok {
+		return nil, res.err
+	}
+	if res.fault != nil {
+		return nil, res.fault
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	length := uint32(res.res >> 32)
+	offset := uint32(res.res)
+	log.Debug("[Exec]", "offset", offset, "length", length)
+	mem := r.instance.Memory().Data()
+	if uint64(offset)+uint64(length) > uint64(len(mem)) {
+		return nil, &MemoryAccessError{Function: function, Ptr: offset, Length: uint64(length), Size: uint32(len(mem))}
+	}
+
+	rawdata := make([]byte, length)
+	copy(rawdata, mem[offset:offset+length])
+
+	return rawdata, nil
+}
+
+// callResult is the result of a call made by call
+type callResult struct {
+	res int64
+	err error
+	// fault is the first out of bounds memory access made by a host function during the call
+	fault *MemoryAccessError
+}
+
+// call calls the exported function, interrupting it if the context is done before it returns. a call which can
+// time out is made in a storage transaction on a snapshot of the storage, which is committed if the call returns
+// by itself and rolled back if it's interrupted. host functions stop accessing memory once the call is
+// interrupted, but one which already read its arguments may still write them to storage or hash them, so the
+// snapshot is what keeps a stopped call from changing the state. if the executor can't interrupt the call, the
+// call is left running on the instance and the snapshot, neither of which is used by anything else, and the
+// runtime is abandoned. its trie is never touched by the call again, so it can be given to a new runtime
+func (r *Runtime) call(ctx context.Context, function string, args ...int32) callResult {
+	if ctx.Done() == nil {
+		res, err := r.instance.Call(function, args...)
+		return callResult{res, err, r.host.fault}
+	}
+
+	r.storage.StartTransaction()
+
+	done := make(chan callResult, 1)
+	go func() {
+		// the fault is read by the goroutine that made the call, since an abandoned call may still be setting it
+		// after Exec returns
+		res, err := r.instance.Call(function, args...)
+		done <- callResult{res, err, r.host.fault}
+	}()
+
+	select {
+	case res := <-done:
+		err := r.storage.CommitTransaction()
+		if err != nil {
+			return callResult{err: err}
+		}
+		return res
+	case <-ctx.Done():
+	}
+
+	atomic.StoreInt32(&r.host.interrupted, 1)
+
+	if r.instance.Interrupt() {
+		<-done
+		err := r.storage.RollbackTransaction()
+		if err != nil {
+			return callResult{err: err}
+		}
+	} else {
+		log.Warn("[Exec] cannot interrupt runtime call, abandoning runtime", "function", function)
+		atomic.StoreInt32(&r.abandoned, 1)
+
+		go func() {
+			<-done
+			r.instance.Stop()
+		}()
+	}
+
+	return callResult{err: &TimeoutError{Function: function, Err: ctx.Err()}}
+}
+
+// Abandoned returns true if a call timed out and couldn't be interrupted, so the runtime can't be used any more
+func (r *Runtime) Abandoned() bool {
+	return atomic.LoadInt32(&r.abandoned) != 0
+}
+
+func decodeToInterface(in []byte, t interface{}) (interface{}, error) {
+	buf := &bytes.Buffer{}
+	sd := scale.Decoder{Reader: buf}
+	_, err := buf.Write(in)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := sd.Decode(t)
+	return output, err
+}
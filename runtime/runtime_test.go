@@ -0,0 +1,885 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/trie"
+	"golang.org/x/crypto/ed25519"
+)
+
+const POLKADOT_RUNTIME_FP string = "polkadot_runtime.compact.wasm"
+const POLKADOT_RUNTIME_URL string = "https://github.com/w3f/polkadot-re-tests/blob/master/polkadot-runtime/polkadot_runtime.compact.wasm?raw=true"
+
+// getRuntimeBlob checks if the polkadot runtime wasm file exists and if not, it fetches it from github
+func getRuntimeBlob() (n int64, err error) {
+	if Exists(POLKADOT_RUNTIME_FP) {
+		return 0, nil
+	}
+
+	out, err := os.Create(POLKADOT_RUNTIME_FP)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	resp, err := http.Get(POLKADOT_RUNTIME_URL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	n, err = io.Copy(out, resp.Body)
+	return n, err
+}
+
+// Exists reports whether the named file or directory exists.
+func Exists(name string) bool {
+	if _, err := os.Stat(name); err != nil {
+		if os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+func newRuntime(t *testing.T, executor string) (*Runtime, error) {
+	_, err := getRuntimeBlob()
+	if err != nil {
+		t.Fatalf("Fail: could not get polkadot runtime")
+	}
+
+	fp, err := filepath.Abs(POLKADOT_RUNTIME_FP)
+	if err != nil {
+		t.Fatal("could not create filepath")
+	}
+
+	tt := &trie.Trie{}
+
+	e, err := NewExecutor(executor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRuntime(fp, tt, e)
+	if err != nil {
+		t.Fatal(err)
+	} else if r == nil {
+		t.Fatal("did not create new VM")
+	}
+
+	return r, err
+}
+
+func TestExecVersion(t *testing.T) {
+	expected := &Version{
+		Spec_name:         []byte("polkadot"),
+		Impl_name:         []byte("parity-polkadot"),
+		Authoring_version: 1,
+		Spec_version:      1000,
+		Impl_version:      0,
+	}
+
+	for _, executor := range Executors() {
+		t.Run(executor, func(t *testing.T) {
+			r, err := newRuntime(t, executor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ret, err := r.Exec(context.Background(), "Core_version", 1, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Log(ret)
+
+			res, err := decodeToInterface(ret, &Version{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			version := res.(*Version)
+			t.Logf("Spec_name: %s\n", version.Spec_name)
+			t.Logf("Impl_name: %s\n", version.Impl_name)
+			t.Logf("Authoring_version: %d\n", version.Authoring_version)
+			t.Logf("Spec_version: %d\n", version.Spec_version)
+			t.Logf("Impl_version: %d\n", version.Impl_version)
+
+			if !reflect.DeepEqual(version, expected) {
+				t.Errorf("Fail: got %v expected %v\n", version, expected)
+			}
+		})
+	}
+}
+
+// newMockRuntime returns a runtime which calls the mockInstance
+func newMockRuntime() (*Runtime, *mockInstance) {
+	h := newTestHostContext()
+	instance := &mockInstance{memory: h.memory.(*mockMemory)}
+	return &Runtime{
+		instance:  instance,
+		storage:   h.storage,
+		allocator: h.allocator,
+		host:      h,
+	}, instance
+}
+
+func TestExec_Timeout(t *testing.T) {
+	r, instance := newMockRuntime()
+
+	// a call which runs past the deadline is interrupted, and host functions it calls afterwards can't modify storage
+	stop := make(chan struct{})
+	instance.call = func(function string, args ...int32) (int64, error) {
+		<-stop
+		r.host.ext_set_storage(0, 1, 0, 1)
+		return 0, nil
+	}
+	instance.interrupt = func() bool {
+		close(stop)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := r.Exec(ctx, "noot", 0, 0)
+	if e, ok := err.(*TimeoutError); !ok || e.Function != "noot" || e.Err != context.DeadlineExceeded {
+		t.Fatalf("Fail: got %v expected timeout", err)
+	}
+	val, err := r.storage.Get([]byte{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil || r.host.fault != nil {
+		t.Fatal("Fail: host function accessed memory after the call was interrupted")
+	}
+
+	// the runtime can still be used after an interrupted call
+	instance.call = nil
+	_, err = r.Exec(context.Background(), "noot", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Abandoned() {
+		t.Fatal("Fail: runtime was abandoned after an interrupted call")
+	}
+
+	// a cancelled context stops the call before it's made
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	instance.call = func(function string, args ...int32) (int64, error) {
+		t.Fatal("Fail: call made with a cancelled context")
+		return 0, nil
+	}
+	_, err = r.Exec(ctx, "noot", 0, 0)
+	if e, ok := err.(*TimeoutError); !ok || e.Err != context.Canceled {
+		t.Fatalf("Fail: got %v expected timeout", err)
+	}
+}
+
+func TestExec_TimeoutRollback(t *testing.T) {
+	r, instance := newMockRuntime()
+	copy(r.instance.Memory().Data(), []byte("noot"))
+
+	// the storage written by a call which returns in time is kept
+	instance.call = func(function string, args ...int32) (int64, error) {
+		r.host.ext_set_storage(0, 1, 0, 4)
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.Exec(ctx, "noot", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := r.storage.Get([]byte("n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(val, []byte("noot")) {
+		t.Fatalf("Fail: got %x expected %x", val, []byte("noot"))
+	}
+
+	// the storage written by an interrupted call before it was interrupted is thrown away
+	stop := make(chan struct{})
+	instance.call = func(function string, args ...int32) (int64, error) {
+		r.host.ext_set_storage(1, 1, 0, 4)
+		<-stop
+		return 0, nil
+	}
+	instance.interrupt = func() bool {
+		close(stop)
+		return true
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = r.Exec(ctx, "noot", 0, 0)
+	if _, ok := err.(*TimeoutError); !ok {
+		t.Fatalf("Fail: got %v expected timeout", err)
+	}
+	val, err = r.storage.Get([]byte("o"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil {
+		t.Fatal("Fail: storage written by interrupted call was kept")
+	}
+
+	// no transaction is left open
+	err = r.SetTrie(&trie.Trie{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitStopped waits for the abandoned call on the instance to return and stop it
+func waitStopped(t *testing.T, instance *mockInstance) {
+	for i := 0; !instance.isStopped(); i++ {
+		if i == 100 {
+			t.Fatal("Fail: abandoned instance was not stopped after its call returned")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExec_Abandoned(t *testing.T) {
+	r, instance := newMockRuntime()
+	base := r.storage.trie
+
+	// a call which can't be interrupted is left running and the runtime is abandoned. the call keeps writing to
+	// storage, as a host function which read its arguments before the call was interrupted does
+	block := make(chan struct{})
+	instance.call = func(function string, args ...int32) (int64, error) {
+		<-block
+		return 0, r.storage.Put([]byte("noot"), []byte("was here"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := r.Exec(ctx, "noot", 0, 0)
+	if _, ok := err.(*TimeoutError); !ok {
+		t.Fatalf("Fail: got %v expected timeout", err)
+	}
+	if !r.Abandoned() {
+		t.Fatal("Fail: runtime was not abandoned")
+	}
+
+	_, err = r.Exec(context.Background(), "noot", 0, 0)
+	if err != ErrAbandoned {
+		t.Fatalf("Fail: got %v expected %v", err, ErrAbandoned)
+	}
+
+	// the instance isn't stopped while the call may still be using it
+	r.Stop()
+	if instance.isStopped() {
+		t.Fatal("Fail: abandoned instance was stopped")
+	}
+
+	// once the call returns, the instance is stopped, and what the call wrote never reaches the trie
+	close(block)
+	waitStopped(t, instance)
+
+	val, err := base.Get([]byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil {
+		t.Fatal("Fail: abandoned call wrote to the trie")
+	}
+}
+
+func TestExec_ManyAbandonedCalls(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	blocked := func(function string, args ...int32) (int64, error) {
+		<-block
+		return 0, nil
+	}
+
+	for i := 0; i < 8; i++ {
+		r, instance := newMockRuntime()
+		instance.call = blocked
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := r.Exec(ctx, "noot", 0, 0)
+		cancel()
+		if _, ok := err.(*TimeoutError); !ok {
+			t.Fatalf("Fail: got %v expected timeout", err)
+		}
+	}
+
+	// abandoned calls still running don't stop other runtimes from making calls which can time out
+	r, _ := newMockRuntime()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.Exec(ctx, "noot", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+const TESTS_FP string = "./test_wasm.wasm"
+const TEST_WASM_URL string = "https://github.com/ChainSafe/gossamer-test-wasm/raw/master/target/wasm32-unknown-unknown/release/test_wasm.wasm"
+
+// getTestBlob checks if the test wasm file exists and if not, it fetches it from github
+func getTestBlob() (n int64, err error) {
+	if Exists(TESTS_FP) {
+		return 0, nil
+	}
+
+	out, err := os.Create(TESTS_FP)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	resp, err := http.Get(TEST_WASM_URL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	n, err = io.Copy(out, resp.Body)
+	return n, err
+}
+
+func newTestRuntime(executor string) (*Runtime, error) {
+	_, err := getTestBlob()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &trie.Trie{}
+	fp, err := filepath.Abs(TESTS_FP)
+	if err != nil {
+		return nil, err
+	}
+	e, err := NewExecutor(executor)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRuntime(fp, t, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// runOnExecutors runs the test against the test runtime on each executor
+func runOnExecutors(t *testing.T, test func(t *testing.T, runtime *Runtime)) {
+	for _, executor := range Executors() {
+		t.Run(executor, func(t *testing.T) {
+			runtime, err := newTestRuntime(executor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer runtime.Stop()
+
+			test(t, runtime)
+		})
+	}
+}
+
+// exportedFunc returns a func which calls the exported function of the runtime
+func exportedFunc(runtime *Runtime, name string) func(args ...int) (int64, error) {
+	return func(args ...int) (int64, error) {
+		in := make([]int32, len(args))
+		for i, arg := range args {
+			in[i] = int32(arg)
+		}
+		return runtime.instance.Call(name, in...)
+	}
+}
+
+// tests that the function ext_get_storage_into can retrieve a value from the trie
+// and store it in the wasm memory
+func TestExt_get_storage_into(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+
+		// store kv pair in trie
+		key := []byte(":noot")
+		value := []byte{1, 3, 3, 7}
+		err := runtime.trie.Put(key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// copy key to position `keyData` in memory
+		keyData := 170
+		// return value will be saved at position `valueData`
+		valueData := 200
+		// `valueOffset` is the position in the value following which its bytes should be stored
+		valueOffset := 0
+		copy(mem[keyData:keyData+len(key)], key)
+
+		testFunc := exportedFunc(runtime, "test_ext_get_storage_into")
+
+		ret, err := testFunc(keyData, len(key), valueData, len(value), valueOffset)
+		if err != nil {
+			t.Fatal(err)
+		} else if int32(ret) != int32(len(value)) {
+			t.Error("return value does not match length of value in trie")
+		} else if !bytes.Equal(mem[valueData:valueData+len(value)], value[valueOffset:]) {
+			t.Error("did not store correct value in memory")
+		}
+
+		key = []byte("doesntexist")
+		copy(mem[keyData:keyData+len(key)], key)
+		expected := 1<<32 - 1
+		ret, err = testFunc(keyData, len(key), valueData, len(value), valueOffset)
+		if err != nil {
+			t.Fatal(err)
+		} else if int32(ret) != int32(expected) {
+			t.Errorf("return value should be 2^32 - 1 since value doesn't exist, got %d", int32(ret))
+		}
+	})
+}
+
+// tests that ext_set_storage can storage a value in the trie
+func TestExt_set_storage(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+
+		// key,value we wish to store in the trie
+		key := []byte(":noot")
+		value := []byte{1, 3, 3, 7}
+
+		// copy key and value into wasm memory
+		keyData := 170
+		valueData := 200
+		copy(mem[keyData:keyData+len(key)], key)
+		copy(mem[valueData:valueData+len(value)], value)
+
+		testFunc := exportedFunc(runtime, "test_ext_set_storage")
+
+		_, err := testFunc(keyData, len(key), valueData, len(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// make sure we can get the value from the trie
+		trieValue, err := runtime.trie.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(value, trieValue) {
+			t.Error("did not store correct value in storage trie")
+		}
+
+		t.Log(trieValue)
+	})
+}
+
+// tests that we can retrieve the trie root hash and store it in wasm memory
+func TestExt_storage_root(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+		// save result at `resultPtr` in memory
+		resultPtr := 170
+		hash, err := runtime.trie.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testFunc := exportedFunc(runtime, "test_ext_storage_root")
+
+		_, err = testFunc(resultPtr)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(mem[resultPtr:resultPtr+32], hash[:]) {
+			t.Error("did not save trie hash to memory")
+		}
+	})
+}
+
+// test that ext_get_allocated_storage can get a value from the trie and store it
+// in wasm memory
+func TestExt_get_allocated_storage(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+		// put kv pair in trie
+		key := []byte(":noot")
+		value := []byte{1, 3, 3, 7}
+		err := runtime.trie.Put(key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// copy key to `keyData` in memory
+		keyData := 170
+		copy(mem[keyData:keyData+len(key)], key)
+		// memory location where length of return value is stored
+		writtenOut := 169
+
+		testFunc := exportedFunc(runtime, "test_ext_get_allocated_storage")
+
+		ret, err := testFunc(keyData, len(key), writtenOut)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// returns memory location where value is stored
+		retInt := uint32(ret)
+		mem = runtime.instance.Memory().Data()
+		length := binary.LittleEndian.Uint32(mem[writtenOut : writtenOut+4])
+		if length != uint32(len(value)) {
+			t.Error("did not save correct value length to memory")
+		} else if !bytes.Equal(mem[retInt:retInt+length], value) {
+			t.Error("did not save value to memory")
+		}
+
+		key = []byte("doesntexist")
+		copy(mem[keyData:keyData+len(key)], key)
+		ret, err = testFunc(keyData, len(key), writtenOut)
+		if err != nil {
+			t.Fatal(err)
+		} else if int32(ret) != int32(0) {
+			t.Errorf("return value should be 0 since value doesn't exist, got %d", int32(ret))
+		}
+	})
+}
+
+// test that ext_clear_storage can delete a value from the trie
+func TestExt_clear_storage(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+		// save kv pair in trie
+		key := []byte(":noot")
+		value := []byte{1, 3, 3, 7}
+		err := runtime.trie.Put(key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// copy key to wasm memory
+		keyData := 170
+		copy(mem[keyData:keyData+len(key)], key)
+
+		testFunc := exportedFunc(runtime, "test_ext_clear_storage")
+
+		_, err = testFunc(keyData, len(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// make sure value is deleted
+		ret, err := runtime.trie.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		} else if ret != nil {
+			t.Error("did not delete key from storage trie")
+		}
+	})
+}
+
+// test that ext_clear_prefix can delete all trie values with a certain prefix
+func TestExt_clear_prefix(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+
+		// store some values in the trie
+		tests := []struct {
+			key   []byte
+			value []byte
+		}{
+			{key: []byte{0x01, 0x35}, value: []byte("pen")},
+			{key: []byte{0x01, 0x35, 0x79}, value: []byte("penguin")},
+			{key: []byte{0xf2}, value: []byte("feather")},
+			{key: []byte{0x09, 0xd3}, value: []byte("noot")},
+		}
+
+		for _, test := range tests {
+			e := runtime.trie.Put(test.key, test.value)
+			if e != nil {
+				t.Fatal(e)
+			}
+		}
+
+		// we are going to delete prefix 0x0135
+		expected := []struct {
+			key   []byte
+			value []byte
+		}{
+			{key: []byte{0xf2}, value: []byte("feather")},
+			{key: []byte{0x09, 0xd3}, value: []byte("noot")},
+		}
+
+		expectedTrie := &trie.Trie{}
+
+		for _, test := range expected {
+			e := expectedTrie.Put(test.key, test.value)
+			if e != nil {
+				t.Fatal(e)
+			}
+		}
+
+		// copy prefix we want to delete to wasm memory
+		prefix := []byte{0x01, 0x35}
+		prefixData := 170
+		copy(mem[prefixData:prefixData+len(prefix)], prefix)
+
+		testFunc := exportedFunc(runtime, "test_ext_clear_prefix")
+
+		_, err := testFunc(prefixData, len(prefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// make sure entries with that prefix were deleted
+		runtimeTrieHash, err := runtime.trie.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedHash, err := expectedTrie.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(runtimeTrieHash[:], expectedHash[:]) {
+			t.Error("did not get expected trie")
+		}
+	})
+}
+
+// test that ext_blake2_256 performs a blake2b hash of the data
+func TestExt_blake2_256(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+		// save data in memory
+		data := []byte("helloworld")
+		pos := 170
+		out := 180
+		copy(mem[pos:pos+len(data)], data)
+
+		testFunc := exportedFunc(runtime, "test_ext_blake2_256")
+
+		_, err := testFunc(pos, len(data), out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// make sure hashes match
+		hash, err := common.Blake2bHash(data)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(hash[:], mem[out:out+32]) {
+			t.Error("hash saved in memory does not equal calculated hash")
+		}
+	})
+}
+
+// test that ext_sr25519_verify verifies a valid signature
+func TestExt_sr25519_verify(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+
+		// signature of "this is a message" in the substrate signing context, from sr25519-crust
+		msg := []byte("this is a message")
+		pub, err := common.HexToBytes("0x46ebddef8cd9bb167dc30878d7113b7e168e6f0646beffd77d69d39bad76b47a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := common.HexToBytes("0x4e172314444b8f820bb54c22e95076f220ed25373e5c178234aa6c211d29271244b947e3ff3418ff6b45fd1df1140c8cbff69fc58ee6dc96df70936a2bb74b82")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// copy message, public key and signature into memory
+		msgData := 170
+		copy(mem[msgData:msgData+len(msg)], msg)
+		pubkeyData := 190
+		copy(mem[pubkeyData:pubkeyData+len(pub)], pub)
+		sigData := 222
+		copy(mem[sigData:sigData+len(sig)], sig)
+
+		testFunc := exportedFunc(runtime, "test_ext_sr25519_verify")
+
+		verified, err := testFunc(msgData, len(msg), sigData, pubkeyData)
+		if err != nil {
+			t.Fatal(err)
+		} else if int32(verified) != 0 {
+			t.Error("did not verify sr25519 signature")
+		}
+
+		// verification should fail on wrong signature
+		sigData = 1
+		verified, err = testFunc(msgData, len(msg), sigData, pubkeyData)
+		if err != nil {
+			t.Fatal(err)
+		} else if int32(verified) != 1 {
+			t.Error("verified incorrect sr25519 signature")
+		}
+	})
+}
+
+// test that ext_ed25519_verify verifies a valid signature
+func TestExt_ed25519_verify(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+
+		// copy message into memory
+		msg := []byte("helloworld")
+		msgData := 170
+		copy(mem[msgData:msgData+len(msg)], msg)
+
+		// create key
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// copy public key into memory
+		pubkeyData := 180
+		copy(mem[pubkeyData:pubkeyData+len(pub)], pub)
+
+		// sign message, copy signature into memory
+		sig := ed25519.Sign(priv, msg)
+		sigData := 222
+		copy(mem[sigData:sigData+len(sig)], sig)
+
+		testFunc := exportedFunc(runtime, "test_ext_ed25519_verify")
+
+		verified, err := testFunc(msgData, len(msg), sigData, pubkeyData)
+		if err != nil {
+			t.Fatal(err)
+		} else if int32(verified) != 0 {
+			t.Error("did not verify ed25519 signature")
+		}
+
+		// verification should fail on wrong signature
+		sigData = 1
+		verified, err = testFunc(msgData, len(msg), sigData, pubkeyData)
+		if err != nil {
+			t.Fatal(err)
+		} else if int32(verified) != 1 {
+			t.Error("verified incorrect ed25519 signature")
+		}
+	})
+}
+
+// test that ext_blake2_256_enumerated_trie_root places values in an array into a trie
+// with the key being the index of the value and returns the hash
+func TestExt_blake2_256_enumerated_trie_root(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+
+		// construct expected trie
+		tests := []struct {
+			key   []byte
+			value []byte
+		}{
+			{key: []byte{0}, value: []byte("pen")},
+			{key: []byte{1}, value: []byte("penguin")},
+			{key: []byte{2}, value: []byte("feather")},
+			{key: []byte{3}, value: []byte("noot")},
+		}
+
+		expectedTrie := &trie.Trie{}
+		valuesArray := []byte{}
+		lensArray := []byte{}
+
+		for _, test := range tests {
+			e := expectedTrie.Put(test.key, test.value)
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			// construct array of values
+			valuesArray = append(valuesArray, test.value...)
+			lensVal := make([]byte, 4)
+			binary.LittleEndian.PutUint32(lensVal, uint32(len(test.value)))
+			// construct array of lengths of the values, where each length is int32
+			lensArray = append(lensArray, lensVal...)
+		}
+
+		// save value array into memory at `valuesData`
+		valuesData := 1
+		// save lengths array into memory at `lensData`
+		lensData := valuesData + len(valuesArray)
+		// save length of lengths array in memory at `lensLen`
+		lensLen := len(tests)
+		// return value will be saved at `result` in memory
+		result := lensLen + 1
+		copy(mem[valuesData:valuesData+len(valuesArray)], valuesArray)
+		copy(mem[lensData:lensData+len(lensArray)], lensArray)
+
+		testFunc := exportedFunc(runtime, "test_ext_blake2_256_enumerated_trie_root")
+
+		_, err := testFunc(valuesData, lensData, lensLen, result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedHash, err := expectedTrie.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// confirm that returned hash matches expected hash
+		if !bytes.Equal(mem[result:result+32], expectedHash[:]) {
+			t.Error("did not get expected trie")
+		}
+	})
+}
+
+// test that ext_twox_128 performs a xxHash64 twice on give byte array of the data
+func TestExt_twox_128(t *testing.T) {
+	runOnExecutors(t, func(t *testing.T, runtime *Runtime) {
+		mem := runtime.instance.Memory().Data()
+		// save data in memory
+		// test for empty []byte
+		data := []byte(nil)
+		pos := 170
+		out := pos + len(data)
+		copy(mem[pos:pos+len(data)], data)
+
+		// call wasm function
+		testFunc := exportedFunc(runtime, "test_ext_twox_128")
+
+		_, err := testFunc(pos, len(data), out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		//check result against expected value
+		t.Logf("Ext_twox_128 data: %s, result: %s", data, hex.EncodeToString(mem[out:out+16]))
+		if "99e9d85137db46ef4bbea33613baafd5" != hex.EncodeToString(mem[out:out+16]) {
+			t.Error("hash saved in memory does not equal calculated hash")
+		}
+
+		// test for data value "Hello world!"
+		data = []byte("Hello world!")
+		out = pos + len(data)
+		copy(mem[pos:pos+len(data)], data)
+
+		// call wasm function
+		_, err = testFunc(pos, len(data), out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		//check result against expected value
+		t.Logf("Ext_twox_128 data: %s, result: %s", data, hex.EncodeToString(mem[out:out+16]))
+		if "b27dfd7f223f177f2a13647b533599af" != hex.EncodeToString(mem[out:out+16]) {
+			t.Error("hash saved in memory does not equal calculated hash")
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+
+	common "github.com/ChainSafe/gossamer/common"
+	trie "github.com/ChainSafe/gossamer/trie"
+)
+
+// Storage is the storage that's accessed by the runtime through the host functions
+// changes to storage can be grouped into nested transactions; while a transaction is open, changes are
+// written to an overlay on top of the storage trie, and only reach the storage trie once every
+// transaction is committed. rolling back a transaction throws away the changes made since it was started
+// the keys modified in the current block are recorded, so that the block's changes trie can be built
+type Storage struct {
+	trie     *trie.Trie
+	overlays []*trie.Trie      // open transactions, the last overlay is the innermost transaction
+	changes  []*trie.ChangeSet // keys modified in the block, followed by the keys modified in each open transaction
+	block    uint64            // number of the current block, see StartBlock
+}
+
+// NewStorage returns storage on top of the given storage trie
+func NewStorage(t *trie.Trie) *Storage {
+	return &Storage{
+		trie:     t,
+		overlays: []*trie.Trie{},
+		changes:  []*trie.ChangeSet{trie.NewChangeSet()},
+	}
+}
+
+// setTrie replaces the storage trie, clearing the recorded changes. it can't be called while a transaction is open
+func (s *Storage) setTrie(t *trie.Trie) error {
+	if len(s.overlays) != 0 {
+		return errors.New("cannot set storage trie: transaction open")
+	}
+
+	s.trie = t
+	s.changes = []*trie.ChangeSet{trie.NewChangeSet()}
+	return nil
+}
+
+// current returns the trie that reads and writes go to; this is the innermost open transaction,
+// or the storage trie if no transaction is open
+func (s *Storage) current() *trie.Trie {
+	if len(s.overlays) == 0 {
+		return s.trie
+	}
+	return s.overlays[len(s.overlays)-1]
+}
+
+// Get returns the value stored at key, including uncommitted changes
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	return s.current().Get(key)
+}
+
+// Put stores the value at key
+func (s *Storage) Put(key, value []byte) error {
+	err := s.current().Put(key, value)
+	if err != nil {
+		return err
+	}
+	return s.record(key)
+}
+
+// Delete removes the value stored at key
+func (s *Storage) Delete(key []byte) error {
+	err := s.current().Delete(key)
+	if err != nil {
+		return err
+	}
+	return s.record(key)
+}
+
+// ClearPrefix removes all the values whose keys begin with prefix
+func (s *Storage) ClearPrefix(prefix []byte) error {
+	t := s.current()
+	keys := t.GetKeysWithPrefix(prefix)
+	for _, k := range keys {
+		err := t.Delete(k)
+		if err != nil {
+			return err
+		}
+
+		err = s.record(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Root returns the root of the storage trie, including uncommitted changes
+func (s *Storage) Root() (common.Hash, error) {
+	return s.current().Hash()
+}
+
+// childStorageKey returns the key of the child trie, without the child storage prefix
+func childStorageKey(storageKey []byte) ([]byte, error) {
+	if !bytes.HasPrefix(storageKey, trie.ChildStorageKeyPrefix) || len(storageKey) == len(trie.ChildStorageKeyPrefix) {
+		return nil, errors.New("invalid child storage key")
+	}
+	return storageKey[len(trie.ChildStorageKeyPrefix):], nil
+}
+
+// GetChildStorage returns the value stored at key in the child trie with the given storage key
+// the storage key must begin with trie.ChildStorageKeyPrefix
+func (s *Storage) GetChildStorage(storageKey, key []byte) ([]byte, error) {
+	keyToChild, err := childStorageKey(storageKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.current().GetFromChild(keyToChild, key)
+}
+
+// SetChildStorage stores the value at key in the child trie with the given storage key
+func (s *Storage) SetChildStorage(storageKey, key, value []byte) error {
+	keyToChild, err := childStorageKey(storageKey)
+	if err != nil {
+		return err
+	}
+	err = s.current().PutIntoChild(keyToChild, key, value)
+	if err != nil {
+		return err
+	}
+	return s.record(storageKey)
+}
+
+// ClearChildStorage removes the value stored at key in the child trie with the given storage key
+func (s *Storage) ClearChildStorage(storageKey, key []byte) error {
+	keyToChild, err := childStorageKey(storageKey)
+	if err != nil {
+		return err
+	}
+	err = s.current().DeleteFromChild(keyToChild, key)
+	if err != nil {
+		return err
+	}
+	return s.record(storageKey)
+}
+
+// KillChildStorage removes the child trie with the given storage key
+func (s *Storage) KillChildStorage(storageKey []byte) error {
+	keyToChild, err := childStorageKey(storageKey)
+	if err != nil {
+		return err
+	}
+	err = s.current().DeleteChild(keyToChild)
+	if err != nil {
+		return err
+	}
+	return s.record(storageKey)
+}
+
+// ChildStorageRoot returns the root of the child trie with the given storage key, including uncommitted changes
+func (s *Storage) ChildStorageRoot(storageKey []byte) (common.Hash, error) {
+	keyToChild, err := childStorageKey(storageKey)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return s.current().ChildHash(keyToChild)
+}
+
+// StartTransaction starts a new transaction nested inside any transactions that are already open
+func (s *Storage) StartTransaction() {
+	s.overlays = append(s.overlays, s.current().Snapshot())
+	s.changes = append(s.changes, trie.NewChangeSet())
+}
+
+// CommitTransaction applies the changes made in the innermost transaction to the enclosing transaction,
+// or to the storage trie if it is the outermost transaction
+func (s *Storage) CommitTransaction() error {
+	if len(s.overlays) == 0 {
+		return errors.New("cannot commit transaction: no transaction open")
+	}
+
+	overlay := s.overlays[len(s.overlays)-1]
+	s.overlays = s.overlays[:len(s.overlays)-1]
+
+	changes := s.changes[len(s.changes)-1]
+	s.changes = s.changes[:len(s.changes)-1]
+	s.changes[len(s.changes)-1].Merge(changes)
+
+	return s.current().Merge(overlay)
+}
+
+// RollbackTransaction throws away the changes made in the innermost transaction
+func (s *Storage) RollbackTransaction() error {
+	if len(s.overlays) == 0 {
+		return errors.New("cannot rollback transaction: no transaction open")
+	}
+
+	overlay := s.overlays[len(s.overlays)-1]
+	s.overlays = s.overlays[:len(s.overlays)-1]
+	s.changes = s.changes[:len(s.changes)-1]
+	overlay.Discard()
+	return nil
+}
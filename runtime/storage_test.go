@@ -0,0 +1,333 @@
+package runtime
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/trie"
+)
+
+func TestStorage_Transaction(t *testing.T) {
+	tt := &trie.Trie{}
+	s := NewStorage(tt)
+
+	key := []byte(":noot")
+	value := []byte{1, 3, 3, 7}
+
+	err := s.Put(key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.StartTransaction()
+
+	err = s.Put(key, []byte("odd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Put([]byte(":stuff"), []byte("stuff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reads are served from the transaction
+	val, err := s.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("odd")) {
+		t.Errorf("Fail: got %x expected %x", val, []byte("odd"))
+	}
+
+	// the storage trie is unchanged until the transaction is committed
+	val, err = tt.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, value) {
+		t.Errorf("Fail: storage trie modified before commit: got %x expected %x", val, value)
+	}
+
+	err = s.CommitTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err = tt.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("odd")) {
+		t.Errorf("Fail: got %x expected %x", val, []byte("odd"))
+	}
+
+	val, err = tt.Get([]byte(":stuff"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("stuff")) {
+		t.Errorf("Fail: got %x expected %x", val, []byte("stuff"))
+	}
+}
+
+func TestStorage_Rollback(t *testing.T) {
+	tt := &trie.Trie{}
+	s := NewStorage(tt)
+
+	err := s.Put([]byte{0x01, 0x35}, []byte("pen"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Put([]byte{0x01, 0x35, 0x79}, []byte("penguin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := tt.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.StartTransaction()
+
+	err = s.ClearPrefix([]byte{0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := s.Get([]byte{0x01, 0x35})
+	if err != nil {
+		t.Fatal(err)
+	} else if val != nil {
+		t.Errorf("Fail: did not clear prefix, got %x", val)
+	}
+
+	err = s.RollbackTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := s.Root()
+	if err != nil {
+		t.Fatal(err)
+	} else if root != expected {
+		t.Errorf("Fail: rollback did not restore storage: got root %x expected %x", root, expected)
+	}
+
+	err = s.RollbackTransaction()
+	if err == nil {
+		t.Error("Fail: should not rollback with no open transaction")
+	}
+}
+
+func TestStorage_NestedTransactions(t *testing.T) {
+	tt := &trie.Trie{}
+	s := NewStorage(tt)
+
+	s.StartTransaction()
+	err := s.Put([]byte("outer"), []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.StartTransaction()
+	err = s.Put([]byte("inner"), []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// roll back the inner transaction, keep the outer one
+	err = s.RollbackTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.CommitTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := tt.Get([]byte("outer"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("noot")) {
+		t.Errorf("Fail: outer transaction not committed, got %x", val)
+	}
+
+	val, err = tt.Get([]byte("inner"))
+	if err != nil {
+		t.Fatal(err)
+	} else if val != nil {
+		t.Errorf("Fail: inner transaction not rolled back, got %x", val)
+	}
+
+	err = s.CommitTransaction()
+	if err == nil {
+		t.Error("Fail: should not commit with no open transaction")
+	}
+}
+
+func TestStorage_ChildStorage(t *testing.T) {
+	tt := &trie.Trie{}
+	s := NewStorage(tt)
+
+	storageKey := append(append([]byte{}, trie.ChildStorageKeyPrefix...), []byte("noot")...)
+	key := []byte(":noot")
+	value := []byte{1, 3, 3, 7}
+
+	err := s.SetChildStorage(storageKey, key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.StartTransaction()
+
+	err = s.ClearChildStorage(storageKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := s.GetChildStorage(storageKey, key)
+	if err != nil {
+		t.Fatal(err)
+	} else if val != nil {
+		t.Errorf("Fail: got %x expected nil", val)
+	}
+
+	err = s.RollbackTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err = s.GetChildStorage(storageKey, key)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, value) {
+		t.Errorf("Fail: got %x expected %x", val, value)
+	}
+
+	root, err := s.ChildStorageRoot(storageKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the child root is stored in the storage trie when its root is calculated
+	_, err = s.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err = tt.Get(storageKey)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, root[:]) {
+		t.Errorf("Fail: got child root %x expected %x", val, root)
+	}
+
+	err = s.KillChildStorage(storageKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err = tt.Get(storageKey)
+	if err != nil {
+		t.Fatal(err)
+	} else if val != nil {
+		t.Errorf("Fail: child trie was not removed")
+	}
+
+	_, err = s.GetChildStorage([]byte("noot"), key)
+	if err == nil {
+		t.Errorf("Fail: expected error for storage key without child storage prefix")
+	}
+}
+
+func TestStorage_Changes(t *testing.T) {
+	tt := &trie.Trie{}
+	s := NewStorage(tt)
+
+	err := s.StartBlock(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Put([]byte("init"), []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changes tries are disabled until the config is set
+	_, ok, err := s.ChangesTrieRoot()
+	if err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("Fail: changes trie root should not be computed without changes trie config")
+	}
+
+	err = s.Put(ChangesTrieConfigKey, []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Put(ExtrinsicIndexKey, []byte{2, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.StartTransaction()
+	err = s.Put([]byte("noot"), []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CommitTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.StartTransaction()
+	err = s.Put([]byte("rolledback"), []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.RollbackTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := s.Changes()
+	if ext := changes.Extrinsics([]byte("init")); len(ext) != 1 || ext[0] != trie.NoExtrinsicIndex {
+		t.Errorf("Fail: got extrinsics %v for change outside of extrinsic", ext)
+	}
+
+	if ext := changes.Extrinsics([]byte("noot")); len(ext) != 1 || ext[0] != 2 {
+		t.Errorf("Fail: got extrinsics %v expected [2]", ext)
+	}
+
+	if ext := changes.Extrinsics([]byte("rolledback")); len(ext) != 0 {
+		t.Errorf("Fail: rolled back change was recorded")
+	}
+
+	root, ok, err := s.ChangesTrieRoot()
+	if err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("Fail: changes trie root was not computed")
+	}
+
+	expected, err := trie.BuildChangesTrie(nil, 1, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRoot, err := expected.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if root != expectedRoot {
+		t.Errorf("Fail: got changes trie root %x expected %x", root, expectedRoot)
+	}
+
+	err = s.StartBlock(2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(s.Changes().Keys()) != 0 {
+		t.Errorf("Fail: changes were not cleared for new block")
+	}
+}
@@ -1,5 +1,9 @@
 package runtime
 
+import (
+	"fmt"
+)
+
 type Version struct {
 	Spec_name         []byte
 	Impl_name         []byte
@@ -7,3 +11,8 @@ type Version struct {
 	Spec_version      int32
 	Impl_version      int32
 }
+
+// String formats the version the way substrate does, as spec_name-spec_version:impl_name-impl_version
+func (v *Version) String() string {
+	return fmt.Sprintf("%s-%d:%s-%d", v.Spec_name, v.Spec_version, v.Impl_name, v.Impl_version)
+}
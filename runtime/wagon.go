@@ -0,0 +1,304 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+
+	"github.com/go-interpreter/wagon/exec"
+	"github.com/go-interpreter/wagon/wasm"
+)
+
+func init() {
+	registerExecutor(WagonExecutor, func(cfg *Config) Executor {
+		return &wagonExecutor{heapPages: cfg.HeapPages}
+	})
+}
+
+// hostModuleName is the name of the module runtimes import host functions from
+const hostModuleName = "env"
+
+// wagonExecutor interprets modules with wagon. it's much slower than wasmer, but is written in pure Go,
+// so it can be cross compiled and debugged like the rest of the code
+type wagonExecutor struct {
+	// heapPages is the number of pages added to the memory each instance starts with, for the allocator to hand
+	// out. wagon only grows memory when the module executes grow_memory, so the heap is reserved up front
+	heapPages uint32
+}
+
+func (e *wagonExecutor) Compile(code []byte, imports Imports) (Module, error) {
+	host, err := wagonHostModule(imports)
+	if err != nil {
+		return nil, err
+	}
+
+	module, err := wasm.ReadModule(bytes.NewReader(code), func(name string) (*wasm.Module, error) {
+		if name != hostModuleName {
+			return nil, fmt.Errorf("cannot import module %s", name)
+		}
+		return host, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &wagonModule{module: module, heapPages: e.heapPages}, nil
+}
+
+// wagonModule is a module read by wagon. the host functions it imports are resolved when the module is read,
+// so each instance gets a copy of the module with its own host functions swapped in. wagon compiles the
+// functions of the module when a VM is created for it, so unlike wasmer's modules, only the parsing is shared by
+// the instances; each one compiles the code again
+type wagonModule struct {
+	module    *wasm.Module
+	heapPages uint32
+}
+
+func (m *wagonModule) Instantiate(imports Imports) (Instance, error) {
+	module := *m.module
+	module.FunctionIndexSpace = make([]wasm.Function, len(m.module.FunctionIndexSpace))
+	copy(module.FunctionIndexSpace, m.module.FunctionIndexSpace)
+
+	// imported functions come first in the function index space, in the order they're imported. wagon only
+	// names them if the module has a name section, so they're matched to the host functions by import name
+	i := 0
+	if module.Import != nil {
+		for _, entry := range module.Import.Entries {
+			if _, ok := entry.Type.(wasm.FuncImport); !ok {
+				continue
+			}
+
+			fn := module.FunctionIndexSpace[i]
+			imp, ok := imports[entry.FieldName]
+			if !ok {
+				return nil, fmt.Errorf("cannot instantiate module: missing host function %s", entry.FieldName)
+			}
+
+			sig, host, err := wagonHostFunction(imp)
+			if err != nil {
+				return nil, fmt.Errorf("cannot import host function %s: %s", entry.FieldName, err)
+			}
+			if !reflect.DeepEqual(sig.ParamTypes, fn.Sig.ParamTypes) || !reflect.DeepEqual(sig.ReturnTypes, fn.Sig.ReturnTypes) {
+				return nil, fmt.Errorf("cannot import host function %s: signature doesn't match the module's import", entry.FieldName)
+			}
+
+			module.FunctionIndexSpace[i].Host = host
+			i++
+		}
+	}
+
+	// the memory section is copied too, so the heap is only reserved in this instance's copy
+	if module.Memory != nil && len(module.Memory.Entries) > 0 {
+		memory := *module.Memory
+		memory.Entries = make([]wasm.Memory, len(module.Memory.Entries))
+		copy(memory.Entries, module.Memory.Entries)
+
+		limits := &memory.Entries[0].Limits
+		pages := uint64(limits.Initial) + uint64(m.heapPages)
+		if limits.Flags&1 != 0 && pages > uint64(limits.Maximum) {
+			pages = uint64(limits.Maximum)
+		}
+		if pages > maxPages {
+			pages = maxPages
+		}
+		limits.Initial = uint32(pages)
+
+		module.Memory = &memory
+	}
+
+	vm, err := newWagonVM(&module)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &wagonInstance{
+		module: &module,
+		vm:     vm,
+	}
+	instance.memory = &wagonMemory{instance: instance}
+	return instance, nil
+}
+
+// Close does nothing, the module is garbage collected
+func (m *wagonModule) Close() {}
+
+// newWagonVM returns a VM which executes the module
+func newWagonVM(module *wasm.Module) (*exec.VM, error) {
+	vm, err := exec.NewVM(module)
+	if err != nil {
+		return nil, err
+	}
+
+	// traps and panicking host functions are returned as errors, rather than crashing the node
+	vm.RecoverPanic = true
+	return vm, nil
+}
+
+// wagonHostModule returns a module which exports the host functions, for runtimes to import
+func wagonHostModule(imports Imports) (*wasm.Module, error) {
+	names := []string{}
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m := wasm.NewModule()
+	m.Types = &wasm.SectionTypes{
+		Entries: make([]wasm.FunctionSig, len(names)),
+	}
+	m.FunctionIndexSpace = make([]wasm.Function, len(names))
+	m.Export = &wasm.SectionExports{
+		Entries: make(map[string]wasm.ExportEntry),
+	}
+
+	for i, name := range names {
+		sig, host, err := wagonHostFunction(imports[name])
+		if err != nil {
+			return nil, fmt.Errorf("cannot import host function %s: %s", name, err)
+		}
+
+		m.Types.Entries[i] = *sig
+		m.FunctionIndexSpace[i] = wasm.Function{
+			Sig:  &m.Types.Entries[i],
+			Body: &wasm.FunctionBody{},
+			Host: host,
+			Name: name,
+		}
+		m.Export.Entries[name] = wasm.ExportEntry{
+			FieldStr: name,
+			Kind:     wasm.ExternalFunction,
+			Index:    uint32(i),
+		}
+	}
+
+	return m, nil
+}
+
+// wagonHostFunction returns the signature of the host function, and wraps it in a func which takes the
+// *exec.Process that wagon passes to host functions as their first argument
+func wagonHostFunction(f interface{}) (*wasm.FunctionSig, reflect.Value, error) {
+	fn := reflect.ValueOf(f)
+	if fn.Kind() != reflect.Func {
+		return nil, reflect.Value{}, errors.New("not a func")
+	}
+
+	sig := &wasm.FunctionSig{}
+	in := []reflect.Type{reflect.TypeOf(&exec.Process{})}
+	for i := 0; i < fn.Type().NumIn(); i++ {
+		valueType, err := wagonValueType(fn.Type().In(i))
+		if err != nil {
+			return nil, reflect.Value{}, err
+		}
+		sig.ParamTypes = append(sig.ParamTypes, valueType)
+		in = append(in, fn.Type().In(i))
+	}
+
+	out := []reflect.Type{}
+	for i := 0; i < fn.Type().NumOut(); i++ {
+		valueType, err := wagonValueType(fn.Type().Out(i))
+		if err != nil {
+			return nil, reflect.Value{}, err
+		}
+		sig.ReturnTypes = append(sig.ReturnTypes, valueType)
+		out = append(out, fn.Type().Out(i))
+	}
+
+	if len(out) > 1 {
+		return nil, reflect.Value{}, errors.New("more than one result")
+	}
+
+	host := reflect.MakeFunc(reflect.FuncOf(in, out, false), func(args []reflect.Value) []reflect.Value {
+		return fn.Call(args[1:])
+	})
+	return sig, host, nil
+}
+
+// wagonValueType returns the wasm type of a host function parameter or result
+func wagonValueType(t reflect.Type) (wasm.ValueType, error) {
+	switch t.Kind() {
+	case reflect.Int32:
+		return wasm.ValueTypeI32, nil
+	case reflect.Int64:
+		return wasm.ValueTypeI64, nil
+	default:
+		return 0, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// wagonInstance is a module instantiated by wagon
+type wagonInstance struct {
+	module *wasm.Module
+	vm     *exec.VM
+	memory *wagonMemory
+	// interrupted is set by Interrupt, so the next call knows to restart the terminated VM
+	interrupted int32
+}
+
+func (i *wagonInstance) Call(function string, args ...int32) (int64, error) {
+	export, ok := i.module.Export.Entries[function]
+	if !ok || export.Kind != wasm.ExternalFunction {
+		return 0, fmt.Errorf("could not find exported function %s", function)
+	}
+
+	// a terminated VM stops every call made on it until it's restarted, which resets the globals but keeps the
+	// compiled code. the memory is left as the interrupted call left it, which is fine since the allocator is
+	// cleared before every call
+	if atomic.CompareAndSwapInt32(&i.interrupted, 1, 0) {
+		i.vm.Restart()
+	}
+
+	in := make([]uint64, len(args))
+	for j, arg := range args {
+		in[j] = uint64(uint32(arg))
+	}
+
+	res, err := i.vm.ExecCode(int64(export.Index), in...)
+	if err != nil {
+		return 0, err
+	}
+
+	switch res := res.(type) {
+	case uint32:
+		return int64(int32(res)), nil
+	case uint64:
+		return int64(res), nil
+	default:
+		return 0, nil
+	}
+}
+
+func (i *wagonInstance) Memory() Memory {
+	return i.memory
+}
+
+// Interrupt terminates the call; wagon checks for termination before executing each instruction. the VM is
+// terminated before interrupted is set, so a call which sees interrupted always restarts a terminated VM
+func (i *wagonInstance) Interrupt() bool {
+	exec.NewProcess(i.vm).Terminate()
+	atomic.StoreInt32(&i.interrupted, 1)
+	return true
+}
+
+func (i *wagonInstance) Stop() {}
+
+// wagonMemory is the Memory of a wagon instance
+type wagonMemory struct {
+	instance *wagonInstance
+}
+
+func (m *wagonMemory) Data() []byte {
+	return m.instance.vm.Memory()
+}
+
+func (m *wagonMemory) Length() uint32 {
+	return uint32(len(m.instance.vm.Memory()))
+}
+
+// Grow can't grow the memory: wagon only grows memory when the module executes grow_memory. instances start with
+// the configured number of heap pages more than the module declares instead
+func (m *wagonMemory) Grow(pages uint32) error {
+	return fmt.Errorf("cannot grow memory by %d pages: wagon memory can only be grown by the module", pages)
+}
@@ -0,0 +1,208 @@
+package runtime
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/trie"
+	"github.com/go-interpreter/wagon/exec"
+	"github.com/go-interpreter/wagon/wasm"
+)
+
+// wasmSection returns the wasm section with the given id and contents, which must be shorter than 128 bytes
+func wasmSection(id byte, contents ...byte) []byte {
+	return append([]byte{id, byte(len(contents))}, contents...)
+}
+
+// wagonTestCode returns the code of a module which exports fault, which passes ext_blake2_256 an out of bounds
+// pointer and then loops forever, and spin, which loops forever. both take two i32s and return an i64
+func wagonTestCode() []byte {
+	code := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	// (i32, i32, i32) -> () and (i32, i32) -> i64
+	code = append(code, wasmSection(1, 0x02, 0x60, 0x03, 0x7f, 0x7f, 0x7f, 0x00, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e)...)
+	imp := []byte{0x01, 0x03, 'e', 'n', 'v', 0x0e}
+	imp = append(imp, "ext_blake2_256"...)
+	code = append(code, wasmSection(2, append(imp, 0x00, 0x00)...)...)
+	code = append(code, wasmSection(3, 0x02, 0x01, 0x01)...)
+	code = append(code, wasmSection(5, 0x01, 0x00, 0x01)...)
+	code = append(code, wasmSection(7,
+		0x02, 0x05, 'f', 'a', 'u', 'l', 't', 0x00, 0x01,
+		0x04, 's', 'p', 'i', 'n', 0x00, 0x02,
+	)...)
+	// loop br 0 end, followed by i64.const 0 end
+	spin := []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x42, 0x00, 0x0b}
+	// i32.const 0 i32.const 32 i32.const -1 call 0
+	fault := append([]byte{0x00, 0x41, 0x00, 0x41, 0x20, 0x41, 0x7f, 0x10, 0x00}, spin...)
+	body := append([]byte{0x02, byte(len(fault))}, fault...)
+	body = append(body, byte(len(spin)+1), 0x00)
+	body = append(body, spin...)
+	return append(code, wasmSection(10, body...)...)
+}
+
+// newWagonTestRuntime returns a runtime instantiated from wagonTestCode by wagon
+func newWagonTestRuntime(t *testing.T) *Runtime {
+	return newWagonTestRuntimeWithConfig(t, &Config{Executor: WagonExecutor})
+}
+
+// newWagonTestRuntimeWithConfig returns a runtime instantiated from wagonTestCode by the configured wagon executor
+func newWagonTestRuntimeWithConfig(t *testing.T, cfg *Config) *Runtime {
+	e, err := NewExecutorFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := wagonTestCode()
+	hash, err := common.Blake2bHash(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := compile(e, code, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := m.instantiate(&trie.Trie{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestWagonModule_UnnamedImports(t *testing.T) {
+	// the module has no name section, so wagon doesn't name its imports
+	r := newWagonTestRuntime(t)
+	defer r.Stop()
+}
+
+func TestWagon_FaultAbortsCall(t *testing.T) {
+	r := newWagonTestRuntime(t)
+	defer r.Stop()
+
+	// the call would loop forever after the fault, so it's only stopped by the deadline if the fault doesn't abort it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.Exec(ctx, "fault", 0, 0)
+	if e, ok := err.(*MemoryAccessError); !ok || e.Function != "ext_blake2_256" {
+		t.Fatalf("Fail: got %v expected fault from ext_blake2_256", err)
+	}
+}
+
+func TestWagon_Interrupt(t *testing.T) {
+	r := newWagonTestRuntime(t)
+	defer r.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	vm := r.instance.(*wagonInstance).vm
+
+	_, err := r.Exec(ctx, "spin", 0, 0)
+	if _, ok := err.(*TimeoutError); !ok {
+		t.Fatalf("Fail: got %v expected timeout", err)
+	}
+	if r.Abandoned() {
+		t.Fatal("Fail: wagon runtime was abandoned")
+	}
+
+	// the terminated VM is restarted rather than compiled again, so the next call runs
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.Exec(ctx, "fault", 0, 0)
+	if _, ok := err.(*MemoryAccessError); !ok {
+		t.Fatalf("Fail: got %v expected fault", err)
+	}
+
+	if r.instance.(*wagonInstance).vm != vm {
+		t.Fatal("Fail: interrupted VM was replaced")
+	}
+}
+
+func TestWagonMemory_HeapPages(t *testing.T) {
+	r := newWagonTestRuntime(t)
+	defer r.Stop()
+
+	// the module declares one page, and doesn't export its heap base, so the heap starts after that page
+	if r.allocator.heapBase != pageSize {
+		t.Fatalf("Fail: got heap base %d expected %d", r.allocator.heapBase, pageSize)
+	}
+
+	length := r.instance.Memory().Length()
+	if length != (1+DefaultHeapPages)*pageSize {
+		t.Fatalf("Fail: got memory length %d expected %d", length, (1+DefaultHeapPages)*pageSize)
+	}
+
+	err := r.instance.Memory().Grow(1)
+	if err == nil {
+		t.Fatal("Fail: expected error growing wagon memory")
+	}
+
+	r = newWagonTestRuntimeWithConfig(t, &Config{Executor: WagonExecutor, HeapPages: 16})
+	defer r.Stop()
+
+	length = r.instance.Memory().Length()
+	if length != (1+16)*pageSize {
+		t.Fatalf("Fail: got memory length %d expected %d", length, (1+16)*pageSize)
+	}
+}
+
+func TestWagonHostFunction(t *testing.T) {
+	var called []int64
+	f := func(a int32, b int64) int32 {
+		called = []int64{int64(a), b}
+		return a + 1
+	}
+
+	sig, host, err := wagonHostFunction(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &wasm.FunctionSig{
+		ParamTypes:  []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI64},
+		ReturnTypes: []wasm.ValueType{wasm.ValueTypeI32},
+	}
+	if !reflect.DeepEqual(sig, expected) {
+		t.Fatalf("Fail: got %v expected %v", sig, expected)
+	}
+
+	// wagon passes the process as the first argument
+	res := host.Call([]reflect.Value{reflect.ValueOf(&exec.Process{}), reflect.ValueOf(int32(7)), reflect.ValueOf(int64(9))})
+	if res[0].Int() != 8 || !reflect.DeepEqual(called, []int64{7, 9}) {
+		t.Fatalf("Fail: got %d with args %v", res[0].Int(), called)
+	}
+
+	_, _, err = wagonHostFunction(func(a float64) {})
+	if err == nil {
+		t.Fatal("Fail: expected error for unsupported parameter type")
+	}
+
+	_, _, err = wagonHostFunction(func() (int32, int32) { return 0, 0 })
+	if err == nil {
+		t.Fatal("Fail: expected error for multiple results")
+	}
+}
+
+func TestWagonHostModule(t *testing.T) {
+	h := &hostContext{}
+	m, err := wagonHostModule(h.imports())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range h.imports() {
+		export, ok := m.Export.Entries[name]
+		if !ok {
+			t.Fatalf("Fail: host function %s is not exported", name)
+		}
+
+		if m.FunctionIndexSpace[export.Index].Name != name {
+			t.Fatalf("Fail: export %s refers to %s", name, m.FunctionIndexSpace[export.Index].Name)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package runtime
 
 // #include <stdlib.h>
+// #include <stdint.h>
 //
 // extern int32_t ext_malloc(void *context, int32_t size);
 // extern void ext_free(void *context, int32_t addr);
@@ -8,448 +9,427 @@ package runtime
 // extern void ext_print_hex(void *context, int32_t data, int32_t len);
 // extern int32_t ext_get_storage_into(void *context, int32_t keyData, int32_t keyLen, int32_t valueData, int32_t valueLen, int32_t valueOffset);
 // extern void ext_set_storage(void *context, int32_t keyData, int32_t keyLen, int32_t valueData, int32_t valueLen);
+// extern void ext_set_child_storage(void *context, int32_t storageKeyData, int32_t storageKeyLen, int32_t keyData, int32_t keyLen, int32_t valueData, int32_t valueLen);
+// extern int32_t ext_get_child_storage_into(void *context, int32_t storageKeyData, int32_t storageKeyLen, int32_t keyData, int32_t keyLen, int32_t valueData, int32_t valueLen, int32_t valueOffset);
+// extern int32_t ext_get_allocated_child_storage(void *context, int32_t storageKeyData, int32_t storageKeyLen, int32_t keyData, int32_t keyLen, int32_t writtenOut);
+// extern int32_t ext_exists_child_storage(void *context, int32_t storageKeyData, int32_t storageKeyLen, int32_t keyData, int32_t keyLen);
+// extern void ext_clear_child_storage(void *context, int32_t storageKeyData, int32_t storageKeyLen, int32_t keyData, int32_t keyLen);
+// extern void ext_kill_child_storage(void *context, int32_t storageKeyData, int32_t storageKeyLen);
+// extern int32_t ext_child_storage_root(void *context, int32_t storageKeyData, int32_t storageKeyLen, int32_t writtenOut);
 // extern void ext_blake2_256(void *context, int32_t data, int32_t len, int32_t out);
 // extern void ext_clear_storage(void *context, int32_t keyData, int32_t keyLen);
 // extern void ext_twox_128(void *context, int32_t data, int32_t len, int32_t out);
 // extern int32_t ext_get_allocated_storage(void *context, int32_t keyData, int32_t keyLen, int32_t writtenOut);
 // extern void ext_storage_root(void *context, int32_t resultPtr);
-// extern int32_t ext_storage_changes_root(void *context, int32_t a, int32_t b, int32_t c);
+// extern int32_t ext_storage_changes_root(void *context, int32_t parentHashData, int32_t parentHashLen, int32_t result);
 // extern void ext_clear_prefix(void *context, int32_t prefixData, int32_t prefixLen);
 // extern int32_t ext_sr25519_verify(void *context, int32_t msgData, int32_t msgLen, int32_t sigData, int32_t pubkeyData);
 // extern int32_t ext_ed25519_verify(void *context, int32_t msgData, int32_t msgLen, int32_t sigData, int32_t pubkeyData);
 // extern void ext_blake2_256_enumerated_trie_root(void *context, int32_t valuesData, int32_t lensData, int32_t lensLen, int32_t result);
 // extern void ext_print_num(void *context, int64_t data);
+//
+// typedef struct wasmer_instance_t wasmer_instance_t;
+// typedef struct wasmer_exports_t wasmer_exports_t;
+// typedef struct wasmer_export_t wasmer_export_t;
+// typedef struct wasmer_memory_t wasmer_memory_t;
+// extern void wasmer_instance_exports(wasmer_instance_t *instance, wasmer_exports_t **exports);
+// extern int wasmer_exports_len(wasmer_exports_t *exports);
+// extern wasmer_export_t *wasmer_exports_get(wasmer_exports_t *exports, int idx);
+// extern uint32_t wasmer_export_kind(wasmer_export_t *export_);
+// extern int wasmer_export_to_memory(const wasmer_export_t *export_, wasmer_memory_t **memory);
+// extern void wasmer_exports_destroy(wasmer_exports_t *exports);
+// extern uint8_t *wasmer_memory_data(const wasmer_memory_t *mem);
+// extern uint32_t wasmer_memory_data_length(wasmer_memory_t *mem);
+// extern int wasmer_memory_grow(wasmer_memory_t *memory, uint32_t delta);
 import "C"
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"unsafe"
 
-	scale "github.com/ChainSafe/gossamer/codec"
-	common "github.com/ChainSafe/gossamer/common"
-	trie "github.com/ChainSafe/gossamer/trie"
-	log "github.com/ChainSafe/log15"
-	xxhash "github.com/OneOfOne/xxhash"
 	wasm "github.com/wasmerio/go-ext-wasm/wasmer"
-	ed25519 "golang.org/x/crypto/ed25519"
 )
 
+func init() {
+	registerExecutor(WasmerExecutor, func(cfg *Config) Executor {
+		return &wasmerExecutor{}
+	})
+}
+
+// wasmer calls host functions through C, so each host function has an exported trampoline which looks up its
+// implementation in the instance's imports and calls it. a panic can't unwind through the C frames, so the
+// trampolines recover from the panic a host function aborts the call with, see recoverTrap
+
+// wasmerContext is the context data of a wasmer instance
+type wasmerContext struct {
+	imports Imports
+}
+
+// hostImport returns the implementation of the host function from the imports of the instance
+func hostImport(context unsafe.Pointer, name string) interface{} {
+	instanceContext := wasm.IntoInstanceContext(context)
+	return (*wasmerContext)(instanceContext.Data()).imports[name]
+}
+
 //export ext_print_num
 func ext_print_num(context unsafe.Pointer, data C.int64_t) {
-	log.Debug("[ext_print_num] executing...")
-	log.Debug("[ext_print_num]", "message", fmt.Sprintf("%d", data))
+	defer recoverTrap()
+	hostImport(context, "ext_print_num").(func(int64))(int64(data))
 }
 
 //export ext_malloc
 func ext_malloc(context unsafe.Pointer, size C.int32_t) C.int32_t {
-	log.Debug("[ext_malloc] executing...")
-	log.Debug("[ext_malloc]", "size", size)
-	return 1
+	defer recoverTrap()
+	return C.int32_t(hostImport(context, "ext_malloc").(func(int32) int32)(int32(size)))
 }
 
 //export ext_free
 func ext_free(context unsafe.Pointer, addr C.int32_t) {
-	log.Debug("[ext_free] executing...")
-	log.Debug("[ext_free]", "addr", addr)
+	defer recoverTrap()
+	hostImport(context, "ext_free").(func(int32))(int32(addr))
 }
 
-// prints string located in memory at location `offset` with length `size`
 //export ext_print_utf8
 func ext_print_utf8(context unsafe.Pointer, utf8_data, utf8_len int32) {
-	log.Debug("[ext_print_utf8] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	log.Debug("[ext_print_utf8]", "message", fmt.Sprintf("%s", memory[utf8_data:utf8_data+utf8_len]))
+	defer recoverTrap()
+	hostImport(context, "ext_print_utf8").(func(int32, int32))(utf8_data, utf8_len)
 }
 
-// prints hex formatted bytes located in memory at location `offset` with length `size`
 //export ext_print_hex
 func ext_print_hex(context unsafe.Pointer, offset, size int32) {
-	log.Debug("[ext_print_hex] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	log.Debug("[ext_print_hex]", "message", fmt.Sprintf("%x", memory[offset:offset+size]))
+	defer recoverTrap()
+	hostImport(context, "ext_print_hex").(func(int32, int32))(offset, size)
 }
 
-// gets the key stored at memory location `keyData` with length `keyLen` and stores the value in memory at
-// location `valueData`. the value can have up to value `valueLen` and the returned value starts at value[valueOffset:]
 //export ext_get_storage_into
 func ext_get_storage_into(context unsafe.Pointer, keyData, keyLen, valueData, valueLen, valueOffset int32) int32 {
-	log.Debug("[ext_get_storage_into] executing...")
-
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	t := (*trie.Trie)(instanceContext.Data())
-
-	key := memory[keyData : keyData+keyLen]
-	val, err := t.Get(key)
-	if err != nil || val == nil {
-		ret := 1<<32 - 1
-		return int32(ret)
-	}
-
-	if len(val) > int(valueLen) {
-		log.Error("[ext_get_storage_into]", "error", "value exceeds allocated buffer length")
-		return 0
-	}
-
-	copy(memory[valueData:valueData+valueLen], val[valueOffset:])
-	return int32(len(val[valueOffset:]))
+	defer recoverTrap()
+	return hostImport(context, "ext_get_storage_into").(func(int32, int32, int32, int32, int32) int32)(keyData, keyLen, valueData, valueLen, valueOffset)
 }
 
-// puts the key at memory location `keyData` with length `keyLen` and value at memory location `valueData`
-// with length `valueLen` into the storage trie
 //export ext_set_storage
 func ext_set_storage(context unsafe.Pointer, keyData, keyLen, valueData, valueLen int32) {
-	log.Debug("[ext_set_storage] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	t := (*trie.Trie)(instanceContext.Data())
-
-	key := memory[keyData : keyData+keyLen]
-	val := memory[valueData : valueData+valueLen]
-	log.Debug("[ext_set_storage]", "key", key, "val", val)
-	err := t.Put(key, val)
-	if err != nil {
-		log.Error("[ext_set_storage]", "error", err)
-	}
+	defer recoverTrap()
+	hostImport(context, "ext_set_storage").(func(int32, int32, int32, int32))(keyData, keyLen, valueData, valueLen)
 }
 
-// returns the trie root in the memory location `resultPtr`
-//export ext_storage_root
-func ext_storage_root(context unsafe.Pointer, resultPtr int32) {
-	log.Debug("[ext_storage_root] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	t := (*trie.Trie)(instanceContext.Data())
-
-	root, err := t.Hash()
-	if err != nil {
-		log.Error("[ext_storage_root]", "error", err)
-	}
+//export ext_set_child_storage
+func ext_set_child_storage(context unsafe.Pointer, storageKeyData, storageKeyLen, keyData, keyLen, valueData, valueLen int32) {
+	defer recoverTrap()
+	hostImport(context, "ext_set_child_storage").(func(int32, int32, int32, int32, int32, int32))(storageKeyData, storageKeyLen, keyData, keyLen, valueData, valueLen)
+}
 
-	copy(memory[resultPtr:resultPtr+32], root[:])
+//export ext_get_child_storage_into
+func ext_get_child_storage_into(context unsafe.Pointer, storageKeyData, storageKeyLen, keyData, keyLen, valueData, valueLen, valueOffset int32) int32 {
+	defer recoverTrap()
+	return hostImport(context, "ext_get_child_storage_into").(func(int32, int32, int32, int32, int32, int32, int32) int32)(storageKeyData, storageKeyLen, keyData, keyLen, valueData, valueLen, valueOffset)
 }
 
-//export ext_storage_changes_root
-func ext_storage_changes_root(context unsafe.Pointer, a, b, c int32) int32 {
-	log.Debug("[ext_storage_changes_root] executing...")
-	return 0
+//export ext_get_allocated_child_storage
+func ext_get_allocated_child_storage(context unsafe.Pointer, storageKeyData, storageKeyLen, keyData, keyLen, writtenOut int32) int32 {
+	defer recoverTrap()
+	return hostImport(context, "ext_get_allocated_child_storage").(func(int32, int32, int32, int32, int32) int32)(storageKeyData, storageKeyLen, keyData, keyLen, writtenOut)
 }
 
-// gets value stored at key at memory location `keyData` with length `keyLen` and returns the location
-// in memory where it's stored and stores its length in `writtenOut`
-//export ext_get_allocated_storage
-func ext_get_allocated_storage(context unsafe.Pointer, keyData, keyLen, writtenOut int32) int32 {
-	log.Debug("[ext_get_allocated_storage] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	t := (*trie.Trie)(instanceContext.Data())
+//export ext_exists_child_storage
+func ext_exists_child_storage(context unsafe.Pointer, storageKeyData, storageKeyLen, keyData, keyLen int32) int32 {
+	defer recoverTrap()
+	return hostImport(context, "ext_exists_child_storage").(func(int32, int32, int32, int32) int32)(storageKeyData, storageKeyLen, keyData, keyLen)
+}
 
-	key := memory[keyData : keyData+keyLen]
-	val, err := t.Get(key)
-	if err == nil && len(val) >= (1<<32) {
-		err = errors.New("retrieved value length exceeds 2^32")
-	}
+//export ext_clear_child_storage
+func ext_clear_child_storage(context unsafe.Pointer, storageKeyData, storageKeyLen, keyData, keyLen int32) {
+	defer recoverTrap()
+	hostImport(context, "ext_clear_child_storage").(func(int32, int32, int32, int32))(storageKeyData, storageKeyLen, keyData, keyLen)
+}
 
-	if err != nil {
-		log.Error("[ext_get_allocated_storage]", "error", err)
-		return 0
-	}
+//export ext_kill_child_storage
+func ext_kill_child_storage(context unsafe.Pointer, storageKeyData, storageKeyLen int32) {
+	defer recoverTrap()
+	hostImport(context, "ext_kill_child_storage").(func(int32, int32))(storageKeyData, storageKeyLen)
+}
 
-	// writtenOut stores the location of the 4 bytes of memory that was allocated
-	var lenPtr int32 = 1
-	memory[writtenOut] = byte(lenPtr)
-	if val == nil {
-		copy(memory[lenPtr:lenPtr+4], []byte{0xff, 0xff, 0xff, 0xff})
-		return 0
-	}
+//export ext_child_storage_root
+func ext_child_storage_root(context unsafe.Pointer, storageKeyData, storageKeyLen, writtenOut int32) int32 {
+	defer recoverTrap()
+	return hostImport(context, "ext_child_storage_root").(func(int32, int32, int32) int32)(storageKeyData, storageKeyLen, writtenOut)
+}
 
-	// copy value to memory
-	var ptr int32 = lenPtr + 4
-	copy(memory[ptr:ptr+int32(len(val))], val)
+//export ext_storage_root
+func ext_storage_root(context unsafe.Pointer, resultPtr int32) {
+	defer recoverTrap()
+	hostImport(context, "ext_storage_root").(func(int32))(resultPtr)
+}
 
-	// copy length to memory
-	byteLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(byteLen, uint32(len(val)))
-	copy(memory[lenPtr:lenPtr+4], byteLen)
+//export ext_storage_changes_root
+func ext_storage_changes_root(context unsafe.Pointer, parentHashData, parentHashLen, result int32) int32 {
+	defer recoverTrap()
+	return hostImport(context, "ext_storage_changes_root").(func(int32, int32, int32) int32)(parentHashData, parentHashLen, result)
+}
 
-	// return ptr to value
-	return ptr
+//export ext_get_allocated_storage
+func ext_get_allocated_storage(context unsafe.Pointer, keyData, keyLen, writtenOut int32) int32 {
+	defer recoverTrap()
+	return hostImport(context, "ext_get_allocated_storage").(func(int32, int32, int32) int32)(keyData, keyLen, writtenOut)
 }
 
-// deletes the trie entry with key at memory location `keyData` with length `keyLen`
 //export ext_clear_storage
 func ext_clear_storage(context unsafe.Pointer, keyData, keyLen int32) {
-	log.Debug("[ext_sr25519_verify] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	t := (*trie.Trie)(instanceContext.Data())
-
-	key := memory[keyData : keyData+keyLen]
-	err := t.Delete(key)
-	if err != nil {
-		log.Error("[ext_storage_root]", "error", err)
-	}
+	defer recoverTrap()
+	hostImport(context, "ext_clear_storage").(func(int32, int32))(keyData, keyLen)
 }
 
-// deletes all entries in the trie that have a key beginning with the prefix stored at `prefixData`
 //export ext_clear_prefix
 func ext_clear_prefix(context unsafe.Pointer, prefixData, prefixLen int32) {
-	log.Debug("[ext_clear_prefix] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	t := (*trie.Trie)(instanceContext.Data())
-
-	prefix := memory[prefixData : prefixData+prefixLen]
-	entries := t.Entries()
-	for k := range entries {
-		if bytes.Equal([]byte(k)[:prefixLen], prefix) {
-			err := t.Delete([]byte(k))
-			if err != nil {
-				log.Error("[ext_clear_prefix]", "err", err)
-			}
-		}
-	}
+	defer recoverTrap()
+	hostImport(context, "ext_clear_prefix").(func(int32, int32))(prefixData, prefixLen)
 }
 
-// accepts an array of values, puts them into a trie, and returns the root
-// the keys to the values are their position in the array
 //export ext_blake2_256_enumerated_trie_root
 func ext_blake2_256_enumerated_trie_root(context unsafe.Pointer, valuesData, lensData, lensLen, result int32) {
-	log.Debug("[ext_blake2_256_enumerated_trie_root] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	t := &trie.Trie{}
-
-	var i int32
-	var pos int32 = 0
-	for i = 0; i < lensLen; i++ {
-		valueLenBytes := memory[lensData+i*4 : lensData+(i+1)*4]
-		valueLen := int32(binary.LittleEndian.Uint32(valueLenBytes))
-		value := memory[valuesData+pos : valuesData+pos+valueLen]
-		log.Debug("[ext_blake2_256_enumerated_trie_root]", "key", i, "value", fmt.Sprintf("%x", value), "valueLen", valueLen)
-		pos += valueLen
-
-		err := t.Put([]byte{byte(i)}, value)
-		if err != nil {
-			log.Error("[ext_blake2_256_enumerated_trie_root]", "error", err)
-		}
-	}
-
-	root, err := t.Hash()
-	if err != nil {
-		log.Error("[ext_blake2_256_enumerated_trie_root]", "error", err)
-	}
-
-	copy(memory[result:result+32], root[:])
+	defer recoverTrap()
+	hostImport(context, "ext_blake2_256_enumerated_trie_root").(func(int32, int32, int32, int32))(valuesData, lensData, lensLen, result)
 }
 
-// performs blake2b 256-bit hash of the byte array at memory location `data` with length `length` and saves the
-// hash at memory location `out`
 //export ext_blake2_256
 func ext_blake2_256(context unsafe.Pointer, data, length, out int32) {
-	log.Debug("[ext_blake2_256] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	hash, err := common.Blake2bHash(memory[data : data+length])
-	if err != nil {
-		log.Error("[ext_blake2_256]", "error", err)
-	}
-
-	copy(memory[out:out+32], hash[:])
+	defer recoverTrap()
+	hostImport(context, "ext_blake2_256").(func(int32, int32, int32))(data, length, out)
 }
 
 //export ext_twox_128
 func ext_twox_128(context unsafe.Pointer, data, len, out int32) {
-	log.Debug("[ext_twox_128] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
-	log.Debug("[ext_twox_128]", "value", memory[data:data+len])
-
-	// compute xxHash64 twice with seeds 0 and 1 applied on given byte array
-	h0 := xxhash.NewS64(0) // create xxHash with 0 seed
-	_, err := h0.Write(memory[data : data+len])
-	if err != nil {
-		log.Error("[ext_twox_128]", "error", err)
-	}
-	res0 := h0.Sum64()
-	log.Debug("[ext_twox_128]", "xxH64(0) of value", res0)
-	hash0 := make([]byte, 8)
-	binary.LittleEndian.PutUint64(hash0, uint64(res0))
-
-	h1 := xxhash.NewS64(1) // create xxHash with 1 seed
-	_, err = h1.Write(memory[data : data+len])
-	if err != nil {
-		log.Error("[ext_twox_128]", "error", err)
-	}
-	res1 := h1.Sum64()
-	log.Debug("[ext_twox_128]", "xxH64(1) of value", res1)
-	hash1 := make([]byte, 8)
-	binary.LittleEndian.PutUint64(hash1, uint64(res1))
-
-	//concatenaded result
-	both := append(hash0, hash1...)
-
-	copy(memory[out:out+16], both)
+	defer recoverTrap()
+	hostImport(context, "ext_twox_128").(func(int32, int32, int32))(data, len, out)
 }
 
 //export ext_sr25519_verify
 func ext_sr25519_verify(context unsafe.Pointer, msgData, msgLen, sigData, pubkeyData int32) int32 {
-	log.Debug("[ext_sr25519_verify] executing...")
-	return 0
+	defer recoverTrap()
+	return hostImport(context, "ext_sr25519_verify").(func(int32, int32, int32, int32) int32)(msgData, msgLen, sigData, pubkeyData)
 }
 
 //export ext_ed25519_verify
 func ext_ed25519_verify(context unsafe.Pointer, msgData, msgLen, sigData, pubkeyData int32) int32 {
-	log.Debug("[ext_ed25519_verify] executing...")
-	instanceContext := wasm.IntoInstanceContext(context)
-	memory := instanceContext.Memory().Data()
+	defer recoverTrap()
+	return hostImport(context, "ext_ed25519_verify").(func(int32, int32, int32, int32) int32)(msgData, msgLen, sigData, pubkeyData)
+}
 
-	msg := memory[msgData : msgData+msgLen]
-	sig := memory[sigData : sigData+64]
-	pubkey := ed25519.PublicKey(memory[pubkeyData : pubkeyData+32])
+// wasmerImport is a host function wasmer can import
+type wasmerImport struct {
+	name      string
+	goFunc    interface{}
+	cFunction unsafe.Pointer
+}
 
-	if ed25519.Verify(pubkey, msg, sig) {
-		return 0
+// wasmerImports returns the host functions wasmer can import
+func wasmerImports() []wasmerImport {
+	return []wasmerImport{
+		{"ext_print_num", ext_print_num, C.ext_print_num},
+		{"ext_malloc", ext_malloc, C.ext_malloc},
+		{"ext_free", ext_free, C.ext_free},
+		{"ext_print_utf8", ext_print_utf8, C.ext_print_utf8},
+		{"ext_print_hex", ext_print_hex, C.ext_print_hex},
+		{"ext_get_storage_into", ext_get_storage_into, C.ext_get_storage_into},
+		{"ext_set_storage", ext_set_storage, C.ext_set_storage},
+		{"ext_set_child_storage", ext_set_child_storage, C.ext_set_child_storage},
+		{"ext_get_child_storage_into", ext_get_child_storage_into, C.ext_get_child_storage_into},
+		{"ext_get_allocated_child_storage", ext_get_allocated_child_storage, C.ext_get_allocated_child_storage},
+		{"ext_exists_child_storage", ext_exists_child_storage, C.ext_exists_child_storage},
+		{"ext_clear_child_storage", ext_clear_child_storage, C.ext_clear_child_storage},
+		{"ext_kill_child_storage", ext_kill_child_storage, C.ext_kill_child_storage},
+		{"ext_child_storage_root", ext_child_storage_root, C.ext_child_storage_root},
+		{"ext_storage_root", ext_storage_root, C.ext_storage_root},
+		{"ext_storage_changes_root", ext_storage_changes_root, C.ext_storage_changes_root},
+		{"ext_get_allocated_storage", ext_get_allocated_storage, C.ext_get_allocated_storage},
+		{"ext_clear_storage", ext_clear_storage, C.ext_clear_storage},
+		{"ext_clear_prefix", ext_clear_prefix, C.ext_clear_prefix},
+		{"ext_blake2_256_enumerated_trie_root", ext_blake2_256_enumerated_trie_root, C.ext_blake2_256_enumerated_trie_root},
+		{"ext_blake2_256", ext_blake2_256, C.ext_blake2_256},
+		{"ext_twox_128", ext_twox_128, C.ext_twox_128},
+		{"ext_sr25519_verify", ext_sr25519_verify, C.ext_sr25519_verify},
+		{"ext_ed25519_verify", ext_ed25519_verify, C.ext_ed25519_verify},
 	}
-
-	return 1
 }
 
-type Runtime struct {
-	vm   wasm.Instance
-	trie *trie.Trie
-}
+// wasmerExecutor compiles modules with wasmer
+type wasmerExecutor struct{}
 
-func NewRuntime(fp string, t *trie.Trie) (*Runtime, error) {
-	// Reads the WebAssembly module as bytes.
-	bytes, err := wasm.ReadBytes(fp)
-	if err != nil {
-		return nil, err
+func (e *wasmerExecutor) Compile(code []byte, imports Imports) (Module, error) {
+	if len(code) == 0 {
+		return nil, errors.New("cannot compile module: code is empty")
 	}
 
-	imports, err := wasm.NewImports().Append("ext_malloc", ext_malloc, C.ext_malloc)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_free", ext_free, C.ext_free)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_print_utf8", ext_print_utf8, C.ext_print_utf8)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_print_hex", ext_print_hex, C.ext_print_hex)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_print_num", ext_print_num, C.ext_print_num)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_get_storage_into", ext_get_storage_into, C.ext_get_storage_into)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_get_allocated_storage", ext_get_allocated_storage, C.ext_get_allocated_storage)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_set_storage", ext_set_storage, C.ext_set_storage)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_blake2_256", ext_blake2_256, C.ext_blake2_256)
-	if err != nil {
-		return nil, err
-	}
-	_, err = imports.Append("ext_blake2_256_enumerated_trie_root", ext_blake2_256_enumerated_trie_root, C.ext_blake2_256_enumerated_trie_root)
+	// the imports are resolved to the C trampolines when the module is instantiated, so wasmer doesn't
+	// need them to compile it
+	module, err := wasm.Compile(code)
 	if err != nil {
 		return nil, err
 	}
-	_, err = imports.Append("ext_clear_storage", ext_clear_storage, C.ext_clear_storage)
-	if err != nil {
-		return nil, err
+
+	return &wasmerModule{module: module}, nil
+}
+
+// wasmerModule is a module compiled by wasmer
+type wasmerModule struct {
+	module wasm.Module
+}
+
+func (m *wasmerModule) Instantiate(imports Imports) (Instance, error) {
+	wasmImports := wasm.NewImports()
+	for _, imp := range wasmerImports() {
+		if imports[imp.name] == nil {
+			return nil, fmt.Errorf("cannot instantiate module: missing host function %s", imp.name)
+		}
+
+		_, err := wasmImports.Append(imp.name, imp.goFunc, imp.cFunction)
+		if err != nil {
+			return nil, err
+		}
 	}
-	_, err = imports.Append("ext_clear_prefix", ext_clear_prefix, C.ext_clear_prefix)
+
+	instance, err := m.module.InstantiateWithImports(wasmImports)
 	if err != nil {
 		return nil, err
 	}
-	_, err = imports.Append("ext_twox_128", ext_twox_128, C.ext_twox_128)
+
+	memory, err := newWasmerMemory(&instance)
 	if err != nil {
+		instance.Close()
 		return nil, err
 	}
-	_, err = imports.Append("ext_storage_root", ext_storage_root, C.ext_storage_root)
-	if err != nil {
-		return nil, err
+
+	ctx := &wasmerContext{
+		imports: imports,
 	}
-	_, err = imports.Append("ext_storage_changes_root", ext_storage_changes_root, C.ext_storage_changes_root)
-	if err != nil {
-		return nil, err
+	instance.SetContextData(unsafe.Pointer(ctx))
+
+	return &wasmerInstance{
+		vm:     instance,
+		memory: memory,
+		ctx:    ctx,
+	}, nil
+}
+
+func (m *wasmerModule) Close() {
+	m.module.Close()
+}
+
+// wasmerInstance is a module instantiated by wasmer
+type wasmerInstance struct {
+	vm     wasm.Instance
+	memory *wasmerMemory
+	// ctx is referenced so that it isn't garbage collected while wasmer holds a pointer to it
+	ctx *wasmerContext
+}
+
+func (i *wasmerInstance) Call(function string, args ...int32) (int64, error) {
+	runtimeFunc, ok := i.vm.Exports[function]
+	if !ok {
+		return 0, fmt.Errorf("could not find exported function %s", function)
 	}
-	_, err = imports.Append("ext_sr25519_verify", ext_sr25519_verify, C.ext_sr25519_verify)
-	if err != nil {
-		return nil, err
+
+	in := make([]interface{}, len(args))
+	for j, arg := range args {
+		in[j] = arg
 	}
-	_, err = imports.Append("ext_ed25519_verify", ext_ed25519_verify, C.ext_ed25519_verify)
+
+	res, err := runtimeFunc(in...)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	// Instantiates the WebAssembly module.
-	instance, err := wasm.NewInstanceWithImports(bytes, imports)
-	if err != nil {
-		return nil, err
+	switch res.GetType() {
+	case wasm.TypeI32:
+		return int64(res.ToI32()), nil
+	case wasm.TypeI64:
+		return res.ToI64(), nil
+	default:
+		return 0, nil
 	}
+}
 
-	data := unsafe.Pointer(t)
-	instance.SetContextData(data)
+func (i *wasmerInstance) Memory() Memory {
+	return i.memory
+}
 
-	return &Runtime{
-		vm:   instance,
-		trie: t,
-	}, nil
+// Interrupt returns false, since the wasmer C API has no way to interrupt a call, or to meter one so that it stops
+// by itself. the call keeps running until it returns, and the runtime is abandoned, see Runtime.Exec
+func (i *wasmerInstance) Interrupt() bool {
+	return false
 }
 
-func (r *Runtime) Stop() {
-	r.vm.Close()
+func (i *wasmerInstance) Stop() {
+	i.vm.Close()
+	i.memory.close()
 }
 
-func (r *Runtime) Exec(function string, data, len int32) ([]byte, error) {
-	runtimeFunc, ok := r.vm.Exports[function]
-	if !ok {
-		return nil, errors.New("could not find exported function")
-	}
+const (
+	// wasmerExportKindMemory is the wasmer_import_export_kind of exported memories
+	wasmerExportKindMemory = 2
+	// wasmerOk is the wasmer_result_t returned when a call succeeds
+	wasmerOk = 1
+)
 
-	res, err := runtimeFunc(data, len)
-	if err != nil {
-		return nil, err
+// wasmerMemory is the Memory of a wasmer instance. the memory of wasm.Instance points into the instance's exports,
+// which the bindings free as soon as the instance is created, so it's only valid until the next instance is
+// created. wasmerMemory gets the exports itself, and keeps them until the instance is stopped
+type wasmerMemory struct {
+	exports *C.wasmer_exports_t
+	memory  *C.wasmer_memory_t
+}
+
+// newWasmerMemory returns the memory exported by the instance
+func newWasmerMemory(instance *wasm.Instance) (*wasmerMemory, error) {
+	// the wasmer_instance_t is the first field of wasm.Instance
+	cInstance := *(*unsafe.Pointer)(unsafe.Pointer(instance))
+
+	m := &wasmerMemory{}
+	C.wasmer_instance_exports((*C.wasmer_instance_t)(cInstance), &m.exports)
+
+	for i := C.int(0); i < C.wasmer_exports_len(m.exports); i++ {
+		export := C.wasmer_exports_get(m.exports, i)
+		if C.wasmer_export_kind(export) != wasmerExportKindMemory {
+			continue
+		}
+
+		if C.wasmer_export_to_memory(export, &m.memory) != wasmerOk {
+			m.close()
+			return nil, errors.New("cannot get memory exported by module")
+		}
+		return m, nil
 	}
-	resi := res.ToI64()
 
-	length := int32(resi >> 32)
-	offset := int32(resi)
-	fmt.Printf("offset %d length %d\n", offset, length)
-	mem := r.vm.Memory.Data()
-	rawdata := make([]byte, length)
-	copy(rawdata, mem[offset:offset+length])
+	m.close()
+	return nil, errors.New("module does not export memory")
+}
 
-	return rawdata, err
+func (m *wasmerMemory) Data() []byte {
+	length := m.Length()
+	if length == 0 {
+		return []byte{}
+	}
+	return (*[maxPages * pageSize]byte)(unsafe.Pointer(C.wasmer_memory_data(m.memory)))[:length:length]
 }
 
-func decodeToInterface(in []byte, t interface{}) (interface{}, error) {
-	buf := &bytes.Buffer{}
-	sd := scale.Decoder{Reader: buf}
-	_, err := buf.Write(in)
-	if err != nil {
-		return nil, err
+func (m *wasmerMemory) Length() uint32 {
+	return uint32(C.wasmer_memory_data_length(m.memory))
+}
+
+// Grow grows the memory by the given number of pages. the wasmer bindings don't expose growing memory,
+// so the C API is called directly
+func (m *wasmerMemory) Grow(pages uint32) error {
+	if C.wasmer_memory_grow(m.memory, C.uint32_t(pages)) != wasmerOk {
+		return fmt.Errorf("cannot grow memory by %d pages", pages)
 	}
+	return nil
+}
 
-	output, err := sd.Decode(t)
-	return output, err
+// close frees the exports the memory points into
+func (m *wasmerMemory) close() {
+	if m.exports != nil {
+		C.wasmer_exports_destroy(m.exports)
+		m.exports = nil
+	}
 }
@@ -0,0 +1,291 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+
+	scale "github.com/ChainSafe/gossamer/codec"
+	"github.com/ChainSafe/gossamer/common"
+)
+
+// NoExtrinsicIndex is the extrinsic index recorded for changes made outside of an extrinsic,
+// such as while initializing or finalizing a block
+const NoExtrinsicIndex = 0xffffffff
+
+// extrinsicIndexType is the type byte of changes trie keys which map a changed key to the extrinsics that changed it
+const extrinsicIndexType = 1
+
+// changesRootPrefix is the prefix of the database keys under which the changes trie root of each block is stored
+var changesRootPrefix = []byte("changes_root")
+
+// ChangeSet records the keys that were modified in a block, along with the indices of the extrinsics that
+// modified them
+type ChangeSet struct {
+	changes map[string]map[uint32]bool
+}
+
+// NewChangeSet returns an empty ChangeSet
+func NewChangeSet() *ChangeSet {
+	return &ChangeSet{
+		changes: make(map[string]map[uint32]bool),
+	}
+}
+
+// Record records that the key was modified by the extrinsic with the given index
+func (c *ChangeSet) Record(key []byte, extrinsic uint32) {
+	if c.changes[string(key)] == nil {
+		c.changes[string(key)] = make(map[uint32]bool)
+	}
+	c.changes[string(key)][extrinsic] = true
+}
+
+// Merge adds the changes recorded in other to the change set
+func (c *ChangeSet) Merge(other *ChangeSet) {
+	for k, extrinsics := range other.changes {
+		for e := range extrinsics {
+			c.Record([]byte(k), e)
+		}
+	}
+}
+
+// Keys returns the modified keys in ascending order
+func (c *ChangeSet) Keys() [][]byte {
+	keys := make([]string, 0, len(c.changes))
+	for k := range c.changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([][]byte, len(keys))
+	for i, k := range keys {
+		res[i] = []byte(k)
+	}
+	return res
+}
+
+// Contains returns true if the key was modified
+func (c *ChangeSet) Contains(key []byte) bool {
+	_, ok := c.changes[string(key)]
+	return ok
+}
+
+// Extrinsics returns the indices of the extrinsics that modified the key in ascending order
+func (c *ChangeSet) Extrinsics(key []byte) []uint32 {
+	extrinsics := []uint32{}
+	for e := range c.changes[string(key)] {
+		extrinsics = append(extrinsics, e)
+	}
+	sort.Slice(extrinsics, func(i, j int) bool { return extrinsics[i] < extrinsics[j] })
+	return extrinsics
+}
+
+// BuildChangesTrie builds the changes trie of the block with the given number from the change set
+// the changes trie maps each modified key to the extrinsics that modified it:
+// Enc(1 | block number as u64 | key) -> Enc(extrinsic indices as Vec<u32>)
+// digest entries, which aggregate the changes of multiple blocks, are not included
+func BuildChangesTrie(db *Database, block uint64, changes *ChangeSet) (*Trie, error) {
+	t := NewEmptyTrie(db)
+
+	for _, key := range changes.Keys() {
+		k, err := extrinsicIndexKey(block, key)
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.Put(k, encodeExtrinsics(changes.Extrinsics(key)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// extrinsicIndexKey returns the changes trie key for the changes to key in the given block
+func extrinsicIndexKey(block uint64, key []byte) ([]byte, error) {
+	encKey, err := scale.Encode(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k := make([]byte, 9)
+	k[0] = extrinsicIndexType
+	binary.LittleEndian.PutUint64(k[1:], block)
+	return append(k, encKey...), nil
+}
+
+// encodeExtrinsics SCALE encodes the extrinsic indices as a Vec<u32>
+func encodeExtrinsics(extrinsics []uint32) []byte {
+	enc, _ := scale.Encode(big.NewInt(int64(len(extrinsics))))
+	for _, e := range extrinsics {
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, e)
+		enc = append(enc, buf...)
+	}
+	return enc
+}
+
+// decodeExtrinsics decodes a SCALE encoded Vec<u32> of extrinsic indices
+func decodeExtrinsics(enc []byte) ([]uint32, error) {
+	sd := &scale.Decoder{Reader: bytes.NewReader(enc)}
+	length, err := sd.DecodeInteger()
+	if err != nil {
+		return nil, err
+	}
+
+	extrinsics := make([]uint32, length)
+	for i := range extrinsics {
+		e, err := sd.DecodeFixedWidthInt(uint32(0))
+		if err != nil {
+			return nil, err
+		} else if e == nil {
+			return nil, errors.New("cannot decode extrinsic indices: invalid encoding")
+		}
+		extrinsics[i] = e.(uint32)
+	}
+
+	return extrinsics, nil
+}
+
+// KeyChange is a block in which a key was changed, along with the extrinsics that changed it
+type KeyChange struct {
+	Block      uint64
+	Extrinsics []uint32
+}
+
+// ChangesTrieStore stores the changes tries of blocks in a database, so that the blocks in which a key changed
+// can be looked up, eg. to serve light clients
+type ChangesTrieStore struct {
+	db *Database
+}
+
+// NewChangesTrieStore returns a ChangesTrieStore on top of the database
+// the database shouldn't have a Pruner, since the changes tries of old blocks must be kept
+func NewChangesTrieStore(db *Database) *ChangesTrieStore {
+	return &ChangesTrieStore{
+		db: db,
+	}
+}
+
+// Insert builds the changes trie of the block from the change set and writes it to the database
+// it returns the root of the changes trie
+func (s *ChangesTrieStore) Insert(block uint64, changes *ChangeSet) (common.Hash, error) {
+	t, err := BuildChangesTrie(s.db, block, changes)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	root, err := t.Hash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	if t.root != nil {
+		err = t.WriteToDB()
+		if err != nil {
+			return [32]byte{}, err
+		}
+
+		err = t.Commit()
+		if err != nil {
+			return [32]byte{}, err
+		}
+	}
+
+	s.db.Lock.Lock()
+	defer s.db.Lock.Unlock()
+	return root, s.db.Db.Put(changesRootKey(block), root[:])
+}
+
+// Root returns the root of the changes trie of the block, or false if there is no changes trie for the block
+func (s *ChangesTrieStore) Root(block uint64) (common.Hash, bool, error) {
+	s.db.Lock.RLock()
+	defer s.db.Lock.RUnlock()
+
+	// not every database returns an error for missing keys, so check the key exists first
+	has, err := s.db.Db.Has(changesRootKey(block))
+	if err != nil || !has {
+		return [32]byte{}, false, err
+	}
+
+	root, err := s.db.Db.Get(changesRootKey(block))
+	if err != nil || len(root) == 0 {
+		return [32]byte{}, false, err
+	}
+
+	return common.NewHash(root), true, nil
+}
+
+// KeyChanges returns the blocks between from and to, inclusive, in which the key was changed,
+// along with the extrinsics that changed it. blocks without a changes trie are skipped
+func (s *ChangesTrieStore) KeyChanges(key []byte, from, to uint64) ([]*KeyChange, error) {
+	if from > to {
+		return nil, errors.New("cannot get key changes: from is after to")
+	}
+
+	changes := []*KeyChange{}
+	for block := from; ; block++ {
+		root, ok, err := s.Root(block)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok && root != emptyHash() {
+			t, err := LoadFromDB(s.db, root)
+			if err != nil {
+				return nil, err
+			}
+
+			k, err := extrinsicIndexKey(block, key)
+			if err != nil {
+				return nil, err
+			}
+
+			enc, err := t.Get(k)
+			if err != nil {
+				return nil, err
+			}
+
+			if enc != nil {
+				extrinsics, err := decodeExtrinsics(enc)
+				if err != nil {
+					return nil, err
+				}
+				changes = append(changes, &KeyChange{Block: block, Extrinsics: extrinsics})
+			}
+		}
+
+		// checked after the loop body, so that to = max uint64 doesn't overflow
+		if block == to {
+			break
+		}
+	}
+
+	return changes, nil
+}
+
+// changesRootKey returns the database key under which the changes trie root of the block is stored
+func changesRootKey(block uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, block)
+	return append(append([]byte{}, changesRootPrefix...), k...)
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/polkadb"
+)
+
+func TestChangeSet(t *testing.T) {
+	c := NewChangeSet()
+	c.Record([]byte("noot"), 2)
+	c.Record([]byte("noot"), 0)
+	c.Record([]byte("noot"), 2)
+
+	other := NewChangeSet()
+	other.Record([]byte("abc"), NoExtrinsicIndex)
+	other.Record([]byte("noot"), 1)
+	c.Merge(other)
+
+	keys := c.Keys()
+	if !reflect.DeepEqual(keys, [][]byte{[]byte("abc"), []byte("noot")}) {
+		t.Errorf("Fail: got keys %s", keys)
+	}
+
+	extrinsics := c.Extrinsics([]byte("noot"))
+	if !reflect.DeepEqual(extrinsics, []uint32{0, 1, 2}) {
+		t.Errorf("Fail: got extrinsics %v expected %v", extrinsics, []uint32{0, 1, 2})
+	}
+
+	if !c.Contains([]byte("abc")) || c.Contains([]byte("nootwashere")) {
+		t.Error("Fail: Contains returned the wrong result")
+	}
+}
+
+func TestBuildChangesTrie(t *testing.T) {
+	c := NewChangeSet()
+	c.Record([]byte("noot"), 1)
+	c.Record([]byte("noot"), 3)
+
+	ct, err := BuildChangesTrie(nil, 7, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enc(1 | 7 as u64 | Enc("noot"))
+	key := []byte{1, 7, 0, 0, 0, 0, 0, 0, 0, 16, 'n', 'o', 'o', 't'}
+	val, err := ct.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enc([1, 3] as Vec<u32>)
+	expected := []byte{8, 1, 0, 0, 0, 3, 0, 0, 0}
+	if !reflect.DeepEqual(val, expected) {
+		t.Errorf("Fail: got %x expected %x", val, expected)
+	}
+
+	extrinsics, err := decodeExtrinsics(val)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(extrinsics, []uint32{1, 3}) {
+		t.Errorf("Fail: got extrinsics %v expected %v", extrinsics, []uint32{1, 3})
+	}
+}
+
+func TestChangesTrieStore_KeyChanges(t *testing.T) {
+	hasher, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewChangesTrieStore(&Database{
+		Db:     polkadb.NewMemDatabase(),
+		Hasher: hasher,
+	})
+
+	key := []byte("noot")
+	changes := map[uint64][]uint32{
+		1: {0},
+		3: {1, 2},
+		4: {NoExtrinsicIndex},
+	}
+
+	for block := uint64(0); block < 6; block++ {
+		c := NewChangeSet()
+		c.Record([]byte("other"), 0)
+		for _, e := range changes[block] {
+			c.Record(key, e)
+		}
+
+		_, err = s.Insert(block, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := s.KeyChanges(key, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*KeyChange{
+		{Block: 1, Extrinsics: []uint32{0}},
+		{Block: 3, Extrinsics: []uint32{1, 2}},
+		{Block: 4, Extrinsics: []uint32{NoExtrinsicIndex}},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("Fail: got %v expected %v", res, expected)
+	}
+
+	res, err = s.KeyChanges(key, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(res, expected[1:2]) {
+		t.Errorf("Fail: got %v expected %v", res, expected[1:2])
+	}
+
+	// blocks without a changes trie are skipped
+	res, err = s.KeyChanges(key, 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(res, expected[2:]) {
+		t.Errorf("Fail: got %v expected %v", res, expected[2:])
+	}
+
+	_, err = s.KeyChanges(key, 3, 2)
+	if err == nil {
+		t.Errorf("Fail: expected error for invalid range")
+	}
+}
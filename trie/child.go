@@ -0,0 +1,179 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/ChainSafe/gossamer/common"
+)
+
+// ChildStorageKeyPrefix is the prefix of the keys in the parent trie under which the roots of
+// default child tries are stored
+var ChildStorageKeyPrefix = []byte(":child_storage:default:")
+
+// childKey returns the key in the parent trie under which the root of the child trie is stored
+func childKey(keyToChild []byte) []byte {
+	return append(append([]byte{}, ChildStorageKeyPrefix...), keyToChild...)
+}
+
+// PutChild inserts the child trie into the trie under the given key, replacing any existing child trie
+func (t *Trie) PutChild(keyToChild []byte, child *Trie) error {
+	if t.children == nil {
+		t.children = make(map[string]*Trie)
+	}
+
+	t.children[string(keyToChild)] = child
+	return t.updateChildRoot(keyToChild, child)
+}
+
+// GetChild returns the child trie stored under the given key, or nil if there is none
+// if the child trie hasn't been used since the trie was loaded, it's loaded from the database
+func (t *Trie) GetChild(keyToChild []byte) (*Trie, error) {
+	if child, ok := t.children[string(keyToChild)]; ok {
+		return child, nil
+	}
+
+	root, err := t.Get(childKey(keyToChild))
+	if err != nil || root == nil {
+		return nil, err
+	}
+
+	if t.db == nil {
+		return nil, errors.New("cannot load child trie: trie has no database")
+	}
+
+	child, err := LoadFromDB(t.db, common.NewHash(root))
+	if err != nil {
+		return nil, err
+	}
+
+	if t.children == nil {
+		t.children = make(map[string]*Trie)
+	}
+
+	t.children[string(keyToChild)] = child
+	return child, nil
+}
+
+// PutIntoChild inserts the key with value into the child trie stored under keyToChild, creating the
+// child trie if it doesn't exist
+// the root of the child trie stored in the parent is updated when the parent is hashed or written to the database
+func (t *Trie) PutIntoChild(keyToChild, key, value []byte) error {
+	child, err := t.GetChild(keyToChild)
+	if err != nil {
+		return err
+	}
+
+	if child == nil {
+		child = NewEmptyTrie(t.db)
+		if t.children == nil {
+			t.children = make(map[string]*Trie)
+		}
+		t.children[string(keyToChild)] = child
+	}
+
+	return child.Put(key, value)
+}
+
+// GetFromChild returns the value stored at key in the child trie stored under keyToChild
+func (t *Trie) GetFromChild(keyToChild, key []byte) ([]byte, error) {
+	child, err := t.GetChild(keyToChild)
+	if err != nil || child == nil {
+		return nil, err
+	}
+
+	return child.Get(key)
+}
+
+// DeleteFromChild removes the key from the child trie stored under keyToChild
+func (t *Trie) DeleteFromChild(keyToChild, key []byte) error {
+	child, err := t.GetChild(keyToChild)
+	if err != nil || child == nil {
+		return err
+	}
+
+	return child.Delete(key)
+}
+
+// DeleteChild removes the child trie stored under keyToChild, along with its root in the parent
+func (t *Trie) DeleteChild(keyToChild []byte) error {
+	delete(t.children, string(keyToChild))
+	return t.Delete(childKey(keyToChild))
+}
+
+// ChildHash returns the root hash of the child trie stored under keyToChild
+// if there is no child trie, it returns the hash of the empty trie
+func (t *Trie) ChildHash(keyToChild []byte) (common.Hash, error) {
+	child, err := t.GetChild(keyToChild)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	if child == nil {
+		return emptyHash(), nil
+	}
+
+	return child.Hash()
+}
+
+// updateChildRoots stores the current root of every child trie in the trie
+func (t *Trie) updateChildRoots() error {
+	keys := make([]string, 0, len(t.children))
+	for k := range t.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		err := t.updateChildRoot([]byte(k), t.children[k])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateChildRoot stores the root of the child trie in the trie under keyToChild
+// an empty child trie is removed from the trie
+func (t *Trie) updateChildRoot(keyToChild []byte, child *Trie) error {
+	key := childKey(keyToChild)
+
+	if child.root == nil {
+		delete(t.children, string(keyToChild))
+		return t.Delete(key)
+	}
+
+	root, err := child.Hash()
+	if err != nil {
+		return err
+	}
+
+	curr, err := t.Get(key)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(curr, root[:]) {
+		return nil
+	}
+
+	return t.Put(key, root[:])
+}
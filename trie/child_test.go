@@ -0,0 +1,252 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/polkadb"
+)
+
+func TestPutAndGetChild(t *testing.T) {
+	parent := &Trie{}
+	keyToChild := []byte("child")
+
+	child := &Trie{}
+	err := child.Put([]byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = parent.PutChild(keyToChild, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := parent.GetChild(keyToChild)
+	if err != nil {
+		t.Fatal(err)
+	} else if res != child {
+		t.Fatalf("Fail: did not get child trie")
+	}
+
+	childHash, err := child.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := parent.Get(childKey(keyToChild))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(root, childHash[:]) {
+		t.Fatalf("Fail: got child root %x expected %x", root, childHash)
+	}
+}
+
+func TestPutIntoChild(t *testing.T) {
+	parent := &Trie{}
+	keyToChild := []byte("child")
+
+	err := parent.Put([]byte("key"), []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := parent.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := generateRandomTests(100)
+	for _, test := range rt {
+		err = parent.PutIntoChild(keyToChild, test.key, test.value)
+		if err != nil {
+			t.Errorf("Fail to put into child with key %x and value %x: %s", test.key, test.value, err)
+		}
+	}
+
+	for _, test := range rt {
+		val, err := parent.GetFromChild(keyToChild, test.key)
+		if err != nil {
+			t.Errorf("Fail to get key %x from child: %s", test.key, err)
+		} else if !bytes.Equal(val, test.value) {
+			t.Errorf("Fail to get key %x with value %x from child: got %x", test.key, test.value, val)
+		}
+	}
+
+	// the child root is folded into the parent when it's hashed
+	after, err := parent.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if after == before {
+		t.Fatalf("Fail: parent hash did not change after modifying child trie")
+	}
+
+	childHash, err := parent.ChildHash(keyToChild)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := parent.Get(childKey(keyToChild))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(root, childHash[:]) {
+		t.Fatalf("Fail: got child root %x expected %x", root, childHash)
+	}
+
+	// removing every key from the child removes the child from the parent
+	for _, test := range rt {
+		err = parent.DeleteFromChild(keyToChild, test.key)
+		if err != nil {
+			t.Errorf("Fail to delete key %x from child: %s", test.key, err)
+		}
+	}
+
+	after, err = parent.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if after != before {
+		t.Fatalf("Fail: got parent hash %x expected %x", after, before)
+	}
+}
+
+func TestDeleteChild(t *testing.T) {
+	parent := &Trie{}
+	keyToChild := []byte("child")
+
+	err := parent.PutIntoChild(keyToChild, []byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parent.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = parent.DeleteChild(keyToChild)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child, err := parent.GetChild(keyToChild)
+	if err != nil {
+		t.Fatal(err)
+	} else if child != nil {
+		t.Fatalf("Fail: child trie was not deleted")
+	}
+
+	if parent.root != nil {
+		t.Fatalf("Fail: child root was not removed from parent")
+	}
+
+	childHash, err := parent.ChildHash(keyToChild)
+	if err != nil {
+		t.Fatal(err)
+	} else if childHash != emptyHash() {
+		t.Fatalf("Fail: got hash %x for missing child expected empty hash", childHash)
+	}
+}
+
+func TestChild_Snapshot(t *testing.T) {
+	parent := &Trie{}
+	keyToChild := []byte("child")
+
+	err := parent.PutIntoChild(keyToChild, []byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := parent.Snapshot()
+
+	err = snapshot.PutIntoChild(keyToChild, []byte("noot"), []byte("is here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := parent.GetFromChild(keyToChild, []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("was here")) {
+		t.Fatalf("Fail: parent child trie modified by snapshot: got %s", val)
+	}
+
+	err = parent.Merge(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err = parent.GetFromChild(keyToChild, []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("is here")) {
+		t.Fatalf("Fail: got %s expected %s", val, "is here")
+	}
+}
+
+func TestChild_LoadFromDB(t *testing.T) {
+	hasher, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &Database{
+		Db:     polkadb.NewMemDatabase(),
+		Hasher: hasher,
+	}
+
+	parent := NewEmptyTrie(db)
+	keyToChild := []byte("child")
+
+	rt := generateRandomTests(100)
+	for _, test := range rt {
+		err = parent.PutIntoChild(keyToChild, test.key, test.value)
+		if err != nil {
+			t.Errorf("Fail to put into child with key %x and value %x: %s", test.key, test.value, err)
+		}
+	}
+
+	err = parent.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = parent.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := parent.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := LoadFromDB(db, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range rt {
+		val, err := res.GetFromChild(keyToChild, test.key)
+		if err != nil {
+			t.Errorf("Fail to get key %x from child: %s", test.key, err)
+		} else if !bytes.Equal(val, test.value) {
+			t.Errorf("Fail to get key %x with value %x from child: got %x", test.key, test.value, val)
+		}
+	}
+}
@@ -17,8 +17,12 @@
 package trie
 
 import (
+	"bytes"
+	"errors"
+	"sort"
 	"sync"
 
+	"github.com/ChainSafe/gossamer/common"
 	"github.com/ChainSafe/gossamer/polkadb"
 )
 
@@ -28,19 +32,128 @@ type Database struct {
 	Batch  polkadb.Batch
 	Lock   sync.RWMutex
 	Hasher *Hasher
+	// Pruner, if set, removes nodes which are no longer referenced by recently committed roots
+	Pruner *Pruner
 }
 
 // WriteToDB writes the trie to the underlying database batch writer
 // Stores the merkle value of the node as the key and the encoded node as the value
 // This does not actually write to the db, just to the batch writer
 // Commit must be called afterwards to finish writing to the db
+// Any child tries are written along with the trie
 func (t *Trie) WriteToDB() error {
+	// make sure the child roots stored in the trie are up to date
+	err := t.updateChildRoots()
+	if err != nil {
+		return err
+	}
+
 	t.db.Batch = t.db.Db.NewBatch()
-	return t.writeToDB(t.root)
+
+	if t.db.Pruner != nil {
+		t.db.Pruner.reset()
+	}
+
+	err = t.writeTrieToDB(t.root)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(t.children))
+	for k := range t.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		err = t.writeTrieToDB(t.children[k].root)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTrieToDB writes the trie with the given root to the db batch writer
+func (t *Trie) writeTrieToDB(root node) error {
+	if root == nil {
+		return nil
+	}
+
+	if t.db.Pruner != nil {
+		err := t.recordRoot(root)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the root is always referenced by the hash of its encoding, even if the encoding is < 32 bytes,
+	// so make sure it can be looked up by its hash when loading the trie
+	err := t.writeRootToDB(root)
+	if err != nil {
+		return err
+	}
+
+	return t.writeToDB(root)
+}
+
+// recordRoot tells the pruner which root is being written, so it is added to the journal on Commit
+func (t *Trie) recordRoot(root node) error {
+	encRoot, err := Encode(root)
+	if err != nil {
+		return err
+	}
+
+	hash, err := common.Blake2bHash(encRoot)
+	if err != nil {
+		return err
+	}
+
+	// the root node itself is stored under its merkle value, which is the encoding if it's < 32 bytes
+	key := hash[:]
+	if len(encRoot) < 32 {
+		key = encRoot
+	}
+
+	t.db.Pruner.recordRoot(hash, key)
+	return nil
 }
 
-// writeToDB recursively attempts to write each node in the trie to the db batch writer
+// writeRootToDB writes the encoded root to the db batch writer using the root's hash as the key
+func (t *Trie) writeRootToDB(root node) error {
+	if root == nil || !root.isDirty() {
+		return nil
+	}
+
+	encRoot, err := Encode(root)
+	if err != nil {
+		return err
+	}
+
+	if len(encRoot) >= 32 {
+		// the root will be written under its hash by writeNodeToDB
+		return nil
+	}
+
+	hash, err := common.Blake2bHash(encRoot)
+	if err != nil {
+		return err
+	}
+
+	t.db.Lock.Lock()
+	defer t.db.Lock.Unlock()
+	return t.db.Batch.Put(hash[:], encRoot)
+}
+
+// writeToDB recursively writes the dirty nodes in the trie to the db batch writer
+// a node is marked dirty whenever one of its descendants is modified, so the subtree of a clean node is
+// already in the db and isn't walked
 func (t *Trie) writeToDB(n node) error {
+	if !n.isDirty() {
+		return nil
+	}
+
 	_, err := t.writeNodeToDB(n)
 	if err != nil {
 		return err
@@ -82,12 +195,156 @@ func (t *Trie) writeNodeToDB(n node) (bool, error) {
 	t.db.Lock.Lock()
 	err = t.db.Batch.Put(hash[:], encRoot)
 	t.db.Lock.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	if t.db.Pruner != nil {
+		t.db.Pruner.record(hash, encRoot)
+	}
 
 	n.setDirty(false)
-	return true, err
+	return true, nil
 }
 
 // Commit writes the contents of the db's batch writer to the db
+// If the db has a Pruner, nodes no longer referenced by the retained roots are removed in the same write
 func (t *Trie) Commit() error {
+	if t.db.Pruner != nil {
+		return t.db.Pruner.commit(t.db.Batch)
+	}
+
 	return t.db.Batch.Write()
 }
+
+// LoadFromDB loads the trie with the given state root from the database
+// the encoded nodes are retrieved by their merkle values and decoded, recreating the trie
+// as it was when it was written with WriteToDB and Commit
+func LoadFromDB(db *Database, root common.Hash) (*Trie, error) {
+	t, err := loadRootFromDB(db, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.root == nil {
+		return t, nil
+	}
+
+	err = t.load(t.root)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// loadRootFromDB returns a trie with only the root node loaded from the database
+// the root's children are left as stubs, which can be loaded with resolve
+func loadRootFromDB(db *Database, root common.Hash) (*Trie, error) {
+	t := NewEmptyTrie(db)
+
+	if root == emptyHash() {
+		return t, nil
+	}
+
+	enc, err := t.getNodeFromDB(root[:])
+	if err != nil {
+		return nil, err
+	}
+
+	rootNode, err := Decode(bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+
+	// the root's merkle value is its encoding if it's < 32 bytes, even though it's stored under its hash
+	if len(enc) < 32 {
+		rootNode.setHash(enc)
+	} else {
+		rootNode.setHash(root[:])
+	}
+	t.root = rootNode
+	return t, nil
+}
+
+// load recursively replaces the stubbed children of a decoded branch with the nodes stored in the database
+func (t *Trie) load(curr node) error {
+	if c, ok := curr.(*branch); ok {
+		for i, child := range c.children {
+			if child == nil {
+				continue
+			}
+
+			// children with encodings < 32 bytes were decoded inline along with their parent
+			hash := child.getHash()
+			if len(hash) < 32 {
+				continue
+			}
+
+			enc, err := t.getNodeFromDB(hash)
+			if err != nil {
+				return err
+			}
+
+			child, err = Decode(bytes.NewReader(enc))
+			if err != nil {
+				return err
+			}
+
+			child.setHash(hash)
+			c.children[i] = child
+
+			err = t.load(child)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolve returns the node stored in the database for a stubbed child of a decoded branch
+// any other node is returned as it is
+func (t *Trie) resolve(n node) (node, error) {
+	l, ok := n.(*leaf)
+	if !ok || l.value != nil || len(l.getHash()) < 32 || t.db == nil {
+		return n, nil
+	}
+
+	hash := l.getHash()
+	enc, err := t.getNodeFromDB(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := Decode(bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+
+	res.setHash(hash)
+	return res, nil
+}
+
+// getNodeFromDB returns the encoded node stored in the database under the given merkle value
+func (t *Trie) getNodeFromDB(hash []byte) ([]byte, error) {
+	t.db.Lock.RLock()
+	enc, err := t.db.Db.Get(hash)
+	t.db.Lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(enc) == 0 {
+		return nil, errors.New("cannot find node in database")
+	}
+
+	return enc, nil
+}
+
+// emptyHash returns the hash of the encoding of an empty trie
+func emptyHash() common.Hash {
+	h, _ := common.Blake2bHash([]byte{0})
+	return h
+}
@@ -99,3 +99,206 @@ func TestWriteDirty(t *testing.T) {
 
 	trie.closeDb()
 }
+
+func TestWriteToDB_CleanSubtrees(t *testing.T) {
+	trie, err := newTrie()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trie.closeDb()
+
+	for _, test := range generateRandomTests(1000) {
+		err = trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mark a leaf dirty without marking its ancestors. the ancestors are clean, so their subtrees are already
+	// in the db and the leaf isn't visited again
+	var n node = trie.root
+	for {
+		b, ok := n.(*branch)
+		if !ok {
+			break
+		}
+		for _, child := range b.children {
+			if child != nil {
+				n = child
+				break
+			}
+		}
+	}
+	n.setDirty(true)
+
+	err = trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !n.isDirty() {
+		t.Fatal("Fail: clean subtree was walked")
+	}
+}
+
+func TestLoadFromDB(t *testing.T) {
+	trie, err := newTrie()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trie.closeDb()
+
+	rt := generateRandomTests(1000)
+	for _, test := range rt {
+		err = trie.Put(test.key, test.value)
+		if err != nil {
+			t.Errorf("Fail to put with key %x and value %x: %s", test.key, test.value, err.Error())
+		}
+	}
+
+	err = trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := LoadFromDB(trie.db, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := res.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if hash != expected {
+		t.Fatalf("Fail: got root %x expected %x", hash, expected)
+	}
+
+	for _, test := range rt {
+		val, err := res.Get(test.key)
+		if err != nil {
+			t.Errorf("Fail to get key %x: %s", test.key, err.Error())
+		} else if !bytes.Equal(val, test.value) {
+			t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
+		}
+	}
+}
+
+func TestLoadFromDB_SmallRoot(t *testing.T) {
+	trie, err := newTrie()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trie.closeDb()
+
+	err = trie.Put([]byte{0x01}, []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := LoadFromDB(trie.db, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := res.Get([]byte{0x01})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("noot")) {
+		t.Errorf("Fail to get key %x with value %x: got %x", []byte{0x01}, []byte("noot"), val)
+	}
+}
+
+func TestLoadFromDB_InlineChildren(t *testing.T) {
+	trie, err := newTrie()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trie.closeDb()
+
+	// the encodings of the leaves are < 32 bytes, so they're stored inline in their parent branch
+	rt := []trieTest{
+		{key: []byte{0x01, 0x35}, value: []byte("a")},
+		{key: []byte{0x01, 0x36}, value: []byte("b")},
+		{key: []byte{0x01, 0x37, 0x01}, value: []byte("c")},
+		{key: []byte{0x01, 0x37, 0x02}, value: []byte("d")},
+		{key: []byte{0xf2}, value: generateRandBytes(40)},
+	}
+
+	for _, test := range rt {
+		err = trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := LoadFromDB(trie.db, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := res.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if hash != expected {
+		t.Fatalf("Fail: got root %x expected %x", hash, expected)
+	}
+
+	for _, test := range rt {
+		val, err := res.Get(test.key)
+		if err != nil {
+			t.Errorf("Fail to get key %x: %s", test.key, err.Error())
+		} else if !bytes.Equal(val, test.value) {
+			t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
+		}
+	}
+}
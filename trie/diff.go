@@ -0,0 +1,257 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ChainSafe/gossamer/common"
+)
+
+// KeyDiff is a key whose value differs between two tries
+type KeyDiff struct {
+	Key      []byte
+	OldValue []byte // value in the first trie, nil if the key was added
+	NewValue []byte // value in the second trie, nil if the key was removed
+}
+
+// Added returns true if the key is only in the second trie
+func (d *KeyDiff) Added() bool {
+	return d.OldValue == nil
+}
+
+// Removed returns true if the key is only in the first trie
+func (d *KeyDiff) Removed() bool {
+	return d.NewValue == nil
+}
+
+// Changed returns true if the key is in both tries with different values
+func (d *KeyDiff) Changed() bool {
+	return d.OldValue != nil && d.NewValue != nil
+}
+
+// differ walks two tries at once, collecting the keys whose values differ
+type differ struct {
+	a, b   *Trie
+	hasher *Hasher
+	diffs  []*KeyDiff
+}
+
+// Diff returns the keys that were added, removed or changed going from trie a to trie b, sorted by key
+// both tries are walked at once, and subtrees with equal merkle values are skipped
+func Diff(a, b *Trie) ([]*KeyDiff, error) {
+	hasher, err := NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &differ{
+		a:      a,
+		b:      b,
+		hasher: hasher,
+		diffs:  []*KeyDiff{},
+	}
+
+	err = d.diff(a.root, nil, b.root, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(d.diffs, func(i, j int) bool {
+		return bytes.Compare(d.diffs[i].Key, d.diffs[j].Key) < 0
+	})
+	return d.diffs, nil
+}
+
+// DiffRoots returns the keys that were added, removed or changed going from the trie with root a to
+// the trie with root b, where both tries are stored in the database
+// only the nodes of subtrees that differ are loaded from the database
+func DiffRoots(db *Database, a, b common.Hash) ([]*KeyDiff, error) {
+	ta, err := loadRootFromDB(db, a)
+	if err != nil {
+		return nil, err
+	}
+
+	tb, err := loadRootFromDB(db, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return Diff(ta, tb)
+}
+
+// diff compares the node an of trie a with the node bn of trie b, where aPath and bPath are the full keys,
+// as nibbles, of the nodes' parents
+func (d *differ) diff(an node, aPath []byte, bn node, bPath []byte) (err error) {
+	an, err = d.a.resolve(an)
+	if err != nil {
+		return err
+	}
+
+	bn, err = d.b.resolve(bn)
+	if err != nil {
+		return err
+	}
+
+	if an == nil && bn == nil {
+		return nil
+	} else if an == nil {
+		return d.all(d.b, bn, bPath, true)
+	} else if bn == nil {
+		return d.all(d.a, an, aPath, false)
+	}
+
+	aKey := common.Concat(aPath, nodeKey(an)...)
+	bKey := common.Concat(bPath, nodeKey(bn)...)
+
+	switch {
+	case bytes.Equal(aKey, bKey):
+		// the nodes are at the same position in both tries, so equal merkle values mean equal subtrees
+		aHash, err := d.hasher.Hash(an)
+		if err != nil {
+			return err
+		}
+
+		bHash, err := d.hasher.Hash(bn)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(aHash, bHash) {
+			return nil
+		}
+
+		d.compare(aKey, nodeValue(an), nodeValue(bn))
+
+		for i := 0; i < 16; i++ {
+			err = d.diff(nodeChild(an, i), common.Concat(aKey, byte(i)), nodeChild(bn, i), common.Concat(bKey, byte(i)))
+			if err != nil {
+				return err
+			}
+		}
+	case len(aKey) < len(bKey) && bytes.HasPrefix(bKey, aKey):
+		// bn is below an in trie a; it can only be compared with the child of an on its path
+		d.compare(aKey, nodeValue(an), nil)
+
+		for i := 0; i < 16; i++ {
+			path := common.Concat(aKey, byte(i))
+			if byte(i) == bKey[len(aKey)] {
+				err = d.diff(nodeChild(an, i), path, bn, bPath)
+			} else {
+				err = d.all(d.a, nodeChild(an, i), path, false)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	case len(bKey) < len(aKey) && bytes.HasPrefix(aKey, bKey):
+		// an is below bn in trie b
+		d.compare(bKey, nil, nodeValue(bn))
+
+		for i := 0; i < 16; i++ {
+			path := common.Concat(bKey, byte(i))
+			if byte(i) == aKey[len(bKey)] {
+				err = d.diff(an, aPath, nodeChild(bn, i), path)
+			} else {
+				err = d.all(d.b, nodeChild(bn, i), path, true)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		// the keys diverge, so the subtrees have no keys in common
+		err = d.all(d.a, an, aPath, false)
+		if err != nil {
+			return err
+		}
+
+		return d.all(d.b, bn, bPath, true)
+	}
+
+	return nil
+}
+
+// compare records a diff for the key if the values differ
+func (d *differ) compare(key, oldValue, newValue []byte) {
+	if bytes.Equal(oldValue, newValue) {
+		return
+	}
+
+	d.diffs = append(d.diffs, &KeyDiff{
+		Key:      nibblesToKeyLE(key),
+		OldValue: oldValue,
+		NewValue: newValue,
+	})
+}
+
+// all records every key in the subtree n of trie t as added if added is true, or as removed otherwise
+func (d *differ) all(t *Trie, n node, path []byte, added bool) (err error) {
+	n, err = t.resolve(n)
+	if err != nil || n == nil {
+		return err
+	}
+
+	key := common.Concat(path, nodeKey(n)...)
+	if added {
+		d.compare(key, nil, nodeValue(n))
+	} else {
+		d.compare(key, nodeValue(n), nil)
+	}
+
+	for i := 0; i < 16; i++ {
+		err = d.all(t, nodeChild(n, i), common.Concat(key, byte(i)), added)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nodeKey returns the partial key of the node
+func nodeKey(n node) []byte {
+	switch n := n.(type) {
+	case *branch:
+		return n.key
+	case *leaf:
+		return n.key
+	}
+	return nil
+}
+
+// nodeValue returns the value stored at the node
+func nodeValue(n node) []byte {
+	switch n := n.(type) {
+	case *branch:
+		return n.value
+	case *leaf:
+		return n.value
+	}
+	return nil
+}
+
+// nodeChild returns the child of the node at index i, which is always nil for a leaf
+func nodeChild(n node, i int) node {
+	if b, ok := n.(*branch); ok {
+		return b.children[i]
+	}
+	return nil
+}
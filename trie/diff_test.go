@@ -0,0 +1,215 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/polkadb"
+)
+
+// expectedDiff returns the diff between the entries of a and b, computed without walking the tries
+func expectedDiff(a, b map[string][]byte) []*KeyDiff {
+	diffs := []*KeyDiff{}
+	for k, v := range a {
+		if !bytes.Equal(v, b[k]) {
+			diffs = append(diffs, &KeyDiff{Key: []byte(k), OldValue: v, NewValue: b[k]})
+		}
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			diffs = append(diffs, &KeyDiff{Key: []byte(k), NewValue: v})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return bytes.Compare(diffs[i].Key, diffs[j].Key) < 0
+	})
+	return diffs
+}
+
+func checkDiff(t *testing.T, res, expected []*KeyDiff) {
+	if len(res) != len(expected) {
+		t.Fatalf("Fail: got %d diffs expected %d", len(res), len(expected))
+	}
+
+	for i, d := range res {
+		e := expected[i]
+		if !bytes.Equal(d.Key, e.Key) || !bytes.Equal(d.OldValue, e.OldValue) || !bytes.Equal(d.NewValue, e.NewValue) {
+			t.Errorf("Fail: got diff %x: %x -> %x expected %x: %x -> %x", d.Key, d.OldValue, d.NewValue, e.Key, e.OldValue, e.NewValue)
+		}
+	}
+}
+
+// modifiedTries returns a random trie, and a snapshot of it with some keys added, removed and changed
+func modifiedTries(t *testing.T, db *Database) (*Trie, *Trie) {
+	a := NewEmptyTrie(db)
+	rt := generateRandomTests(1000)
+	for _, test := range rt {
+		err := a.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := a.Snapshot()
+	for _, test := range rt[:50] {
+		err := b.Delete(test.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range rt[50:100] {
+		err := b.Put(test.key, []byte("changed"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range generateRandomTests(50) {
+		err := b.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return a, b
+}
+
+func TestDiff(t *testing.T) {
+	a, b := modifiedTries(t, nil)
+
+	res, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDiff(t, res, expectedDiff(a.Entries(), b.Entries()))
+
+	res, err = Diff(b, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDiff(t, res, expectedDiff(b.Entries(), a.Entries()))
+
+	res, err = Diff(a, a)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res) != 0 {
+		t.Errorf("Fail: got %d diffs between equal tries", len(res))
+	}
+
+	res, err = Diff(&Trie{}, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDiff(t, res, expectedDiff(map[string][]byte{}, a.Entries()))
+}
+
+func TestDiff_Structure(t *testing.T) {
+	tests := []struct {
+		a map[string][]byte
+		b map[string][]byte
+	}{
+		// a branch in one trie is a leaf in the other
+		{map[string][]byte{"\x01\x23": {1}, "\x01\x24": {2}}, map[string][]byte{"\x01\x23": {1}}},
+		// the tries branch at different depths
+		{map[string][]byte{"\x01\x23": {1}, "\x01\x24": {2}}, map[string][]byte{"\x01\x23": {1}, "\x01\x24": {2}, "\x02": {3}}},
+		// a value at a branch in one trie
+		{map[string][]byte{"\x01": {1}, "\x01\x24": {2}}, map[string][]byte{"\x01\x24": {2}, "\x01\x25": {2}}},
+		// no keys in common
+		{map[string][]byte{"\x01": {1}}, map[string][]byte{"\xf1": {1}}},
+	}
+
+	for _, test := range tests {
+		a, b := &Trie{}, &Trie{}
+		for k, v := range test.a {
+			err := a.Put([]byte(k), v)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for k, v := range test.b {
+			err := b.Put([]byte(k), v)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res, err := Diff(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDiff(t, res, expectedDiff(test.a, test.b))
+
+		res, err = Diff(b, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDiff(t, res, expectedDiff(test.b, test.a))
+	}
+}
+
+func TestDiffRoots(t *testing.T) {
+	hasher, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &Database{
+		Db:     polkadb.NewMemDatabase(),
+		Hasher: hasher,
+	}
+
+	a, b := modifiedTries(t, db)
+	roots := []*Trie{a, b}
+	for _, tr := range roots {
+		err = tr.WriteToDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = tr.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	aRoot, err := a.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bRoot, err := b.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := DiffRoots(db, aRoot, bRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDiff(t, res, expectedDiff(a.Entries(), b.Entries()))
+
+	for _, d := range res {
+		if d.Added() == d.Removed() && !d.Changed() {
+			t.Errorf("Fail: diff %x is not added, removed or changed", d.Key)
+		}
+	}
+}
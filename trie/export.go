@@ -0,0 +1,235 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	scale "github.com/ChainSafe/gossamer/codec"
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/golang/snappy"
+)
+
+// snapshotMagic begins every state snapshot, followed by the snapshot version
+var snapshotMagic = []byte("gossamer-state")
+
+const snapshotVersion = 1
+
+// types of the entries in a state snapshot
+const (
+	snapshotEnd   = 0 // end of the snapshot
+	snapshotEntry = 1 // key-value pair in the trie
+	snapshotChild = 2 // key-value pair in a child trie, preceded by the key to the child trie
+)
+
+// Export writes the key-value pairs of the trie to w in ascending key order, along with the trie's root hash,
+// as a snappy compressed state snapshot which can be read with Import
+// the key-value pairs of any child tries are written after the key that holds their root
+func (t *Trie) Export(w io.Writer) error {
+	root, err := t.Hash()
+	if err != nil {
+		return err
+	}
+
+	sw := snappy.NewBufferedWriter(w)
+	se := &scale.Encoder{Writer: sw}
+
+	_, err = sw.Write(append(append([]byte{}, snapshotMagic...), snapshotVersion))
+	if err != nil {
+		return err
+	}
+
+	_, err = sw.Write(root[:])
+	if err != nil {
+		return err
+	}
+
+	it := t.NewIterator()
+	for it.Next() {
+		err = writeSnapshotEntry(se, snapshotEntry, it.Key(), it.Value())
+		if err != nil {
+			return err
+		}
+
+		if !bytes.HasPrefix(it.Key(), ChildStorageKeyPrefix) {
+			continue
+		}
+
+		keyToChild := it.Key()[len(ChildStorageKeyPrefix):]
+		child, err := t.GetChild(keyToChild)
+		if err != nil {
+			return err
+		}
+
+		cit := child.NewIterator()
+		for cit.Next() {
+			err = writeSnapshotEntry(se, snapshotChild, keyToChild, cit.Key(), cit.Value())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = sw.Write([]byte{snapshotEnd})
+	if err != nil {
+		return err
+	}
+
+	return sw.Close()
+}
+
+// writeSnapshotEntry writes the entry type followed by each of the fields as a SCALE encoded byte array
+func writeSnapshotEntry(se *scale.Encoder, typ byte, fields ...[]byte) error {
+	_, err := se.Writer.Write([]byte{typ})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		_, err = se.Encode(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Import reads a state snapshot written by Export and rebuilds the trie with the given database
+// if the root of the rebuilt trie doesn't match the root recorded in the snapshot an error is returned,
+// otherwise the trie is written to the database with WriteToDB and Commit
+func Import(db *Database, r io.Reader) (*Trie, error) {
+	sr := &fullReader{snappy.NewReader(r)}
+	sd := &scale.Decoder{Reader: sr}
+
+	header := make([]byte, len(snapshotMagic)+1)
+	_, err := io.ReadFull(sr, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) {
+		return nil, errors.New("cannot import state: not a state snapshot")
+	}
+
+	if header[len(snapshotMagic)] != snapshotVersion {
+		return nil, fmt.Errorf("cannot import state: unsupported snapshot version %d", header[len(snapshotMagic)])
+	}
+
+	expected := common.Hash{}
+	_, err = io.ReadFull(sr, expected[:])
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewEmptyTrie(db)
+
+	for {
+		typ, err := sd.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if typ == snapshotEnd {
+			break
+		}
+
+		switch typ {
+		case snapshotEntry:
+			key, value, err := readSnapshotPair(sd)
+			if err != nil {
+				return nil, err
+			}
+
+			// the roots of child tries are stored once the child tries have been rebuilt
+			if bytes.HasPrefix(key, ChildStorageKeyPrefix) {
+				continue
+			}
+
+			err = t.Put(key, value)
+			if err != nil {
+				return nil, err
+			}
+		case snapshotChild:
+			keyToChild, err := sd.DecodeByteArray()
+			if err != nil {
+				return nil, err
+			}
+
+			key, value, err := readSnapshotPair(sd)
+			if err != nil {
+				return nil, err
+			}
+
+			err = t.PutIntoChild(keyToChild, key, value)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("cannot import state: invalid entry type %d", typ)
+		}
+	}
+
+	root, err := t.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if root != expected {
+		return nil, fmt.Errorf("cannot import state: got root %x expected %x", root, expected)
+	}
+
+	err = t.WriteToDB()
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// readSnapshotPair reads a key-value pair from a state snapshot
+func readSnapshotPair(sd *scale.Decoder) ([]byte, []byte, error) {
+	key, err := sd.DecodeByteArray()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	value, err := sd.DecodeByteArray()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, value, nil
+}
+
+// fullReader reads exactly len(p) bytes on every call to Read, since the decoder expects
+// each read to fill the buffer, but the snappy reader returns at most one chunk at a time
+type fullReader struct {
+	r io.Reader
+}
+
+func (fr *fullReader) Read(p []byte) (int, error) {
+	return io.ReadFull(fr.r, p)
+}
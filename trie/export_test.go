@@ -0,0 +1,157 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/polkadb"
+	"github.com/golang/snappy"
+)
+
+func newMemTrieDatabase(t *testing.T) *Database {
+	hasher, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Database{
+		Db:     polkadb.NewMemDatabase(),
+		Hasher: hasher,
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	trie := NewEmptyTrie(newMemTrieDatabase(t))
+
+	rt := generateRandomTests(1000)
+	for _, test := range rt {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a value larger than a snappy chunk
+	large := bytes.Repeat([]byte{0xab}, 1<<17)
+	err := trie.Put([]byte("large"), large)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.PutIntoChild([]byte("child"), []byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = trie.Export(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := newMemTrieDatabase(t)
+	res, err := Import(db, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := res.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if root != expected {
+		t.Fatalf("Fail: got root %x expected %x", root, expected)
+	}
+
+	// the imported trie should have been committed to the database
+	loaded, err := LoadFromDB(db, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range rt {
+		val, err := loaded.Get(test.key)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(val, test.value) {
+			t.Fatalf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
+		}
+	}
+
+	val, err := loaded.Get([]byte("large"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, large) {
+		t.Fatal("Fail: large value was not imported")
+	}
+
+	val, err = loaded.GetFromChild([]byte("child"), []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("was here")) {
+		t.Fatalf("Fail to get child key with value %x: got %x", []byte("was here"), val)
+	}
+}
+
+func TestImport_Invalid(t *testing.T) {
+	trie := NewEmptyTrie(newMemTrieDatabase(t))
+	err := trie.Put([]byte("noot"), []byte("was here"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = trie.Export(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// change the expected root recorded in the snapshot
+	dec, err := ioutil.ReadAll(snappy.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec[len(snapshotMagic)+1]++
+
+	tampered := new(bytes.Buffer)
+	sw := snappy.NewBufferedWriter(tampered)
+	_, err = sw.Write(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Import(newMemTrieDatabase(t), tampered)
+	if err == nil {
+		t.Fatal("Fail: expected error for mismatched root")
+	}
+
+	_, err = Import(newMemTrieDatabase(t), bytes.NewReader([]byte("noot")))
+	if err == nil {
+		t.Fatal("Fail: expected error for invalid snapshot")
+	}
+}
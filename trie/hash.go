@@ -18,11 +18,18 @@ package trie
 
 import (
 	"hash"
+	"runtime"
+	"sync"
 
 	"golang.org/x/crypto/blake2b"
 )
 
+// parallelDepth is the depth of the subtrees that are hashed concurrently when hashing a trie
+// a depth of 2 splits the trie into up to 256 subtrees
+const parallelDepth = 2
+
 // Hasher is a wrapper around a hash function
+// a Hasher must not be used by multiple goroutines at once
 type Hasher struct {
 	hash hash.Hash
 }
@@ -38,23 +45,113 @@ func NewHasher() (*Hasher, error) {
 	}, nil
 }
 
-// Hash encodes the node and then hashes it if its encoded length is > 32 bytes
+// Hash returns the merkle value of the node; it encodes the node and then hashes it if its encoded length is > 32 bytes
+// the merkle value is cached on the node, so it is only recomputed once the node is modified
 func (h *Hasher) Hash(n node) (res []byte, err error) {
-	encNode, err := n.Encode()
+	if hash := n.getHash(); hash != nil {
+		return hash, nil
+	}
+
+	encNode, err := h.encode(n)
 	if err != nil {
 		return nil, err
 	}
 
 	// if length of encoded leaf is less than 32 bytes, do not hash
 	if len(encNode) < 32 {
+		n.setHash(encNode)
 		return encNode, nil
 	}
 
 	// otherwise, hash encoded node
+	h.hash.Reset()
 	_, err = h.hash.Write(encNode)
 	if err == nil {
 		res = h.hash.Sum(nil)
+		n.setHash(res)
 	}
 
 	return res, err
 }
+
+// encode encodes the node, using the hasher to compute the merkle values of its children
+func (h *Hasher) encode(n node) ([]byte, error) {
+	if b, ok := n.(*branch); ok {
+		return b.encode(h)
+	}
+	return Encode(n)
+}
+
+// hashParallel computes the merkle values of the modified subtrees of n concurrently, using a pool of workers
+// each with their own Hasher. the merkle values are cached on the subtrees, so n can then be hashed without
+// re-hashing them. subtrees whose merkle value is already cached are skipped
+func hashParallel(n node) error {
+	subtrees := []node{}
+	collectUnhashed(n, 0, &subtrees)
+	if len(subtrees) < 2 {
+		return nil
+	}
+
+	workers := runtime.NumCPU()
+	if workers > len(subtrees) {
+		workers = len(subtrees)
+	}
+
+	jobs := make(chan node)
+	errs := make(chan error, workers)
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < workers; i++ {
+		h, err := NewHasher()
+		if err != nil {
+			close(jobs)
+			wg.Wait()
+			return err
+		}
+
+		wg.Add(1)
+		go func(h *Hasher) {
+			defer wg.Done()
+			for subtree := range jobs {
+				_, err := h.Hash(subtree)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}(h)
+	}
+
+	for _, subtree := range subtrees {
+		jobs <- subtree
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// collectUnhashed adds the subtrees of n at parallelDepth that don't have a cached merkle value to subtrees
+// leaves above parallelDepth are added as they are
+func collectUnhashed(n node, depth int, subtrees *[]node) {
+	if n == nil || n.getHash() != nil {
+		return
+	}
+
+	b, ok := n.(*branch)
+	if !ok || depth == parallelDepth {
+		*subtrees = append(*subtrees, n)
+		return
+	}
+
+	for _, child := range b.children {
+		collectUnhashed(child, depth+1, subtrees)
+	}
+}
@@ -19,7 +19,10 @@ package trie
 import (
 	"bytes"
 	"math/rand"
+	"sync"
 	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
 )
 
 func generateRandBytes(size int) []byte {
@@ -113,3 +116,156 @@ func TestHashShort(t *testing.T) {
 		t.Errorf("did not return encoded node padded to 32 bytes: got %s", h)
 	}
 }
+
+func TestHashCached(t *testing.T) {
+	hasher, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trie := &Trie{}
+	rt := generateRandomTests(100)
+	for _, test := range rt {
+		err = trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := hasher.Hash(trie.root)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(trie.root.getHash(), h) {
+		t.Fatalf("Fail: merkle value was not cached on node")
+	}
+
+	err = trie.Put(rt[0].key, []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trie.root.getHash() != nil {
+		t.Fatalf("Fail: cached merkle value was not cleared when the node was modified")
+	}
+}
+
+// hashSerially returns the root hash of a trie with the given entries, without using parallel hashing or cached merkle values
+func hashSerially(t *testing.T, entries map[string][]byte) common.Hash {
+	trie := &Trie{}
+	for k, v := range entries {
+		err := trie.Put([]byte(k), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enc, err := trie.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := common.Blake2bHash(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestTrieHash_Parallel(t *testing.T) {
+	trie := &Trie{}
+	entries := make(map[string][]byte)
+
+	for _, test := range generateRandomTests(5000) {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[string(test.key)] = test.value
+	}
+
+	h, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := hashSerially(t, entries); h != expected {
+		t.Fatalf("Fail: got root %x expected %x", h, expected)
+	}
+
+	// modify the trie, so that only some of the cached merkle values are still valid
+	rt := generateRandomTests(100)
+	for _, test := range rt {
+		err = trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[string(test.key)] = test.value
+	}
+
+	for _, test := range rt[:50] {
+		err = trie.Delete(test.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		delete(entries, string(test.key))
+	}
+
+	h, err = trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := hashSerially(t, entries); h != expected {
+		t.Fatalf("Fail: got root %x after modifying trie expected %x", h, expected)
+	}
+}
+
+func TestTrieHash_ConcurrentSnapshot(t *testing.T) {
+	trie := &Trie{}
+	entries := make(map[string][]byte)
+
+	for _, test := range generateRandomTests(5000) {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[string(test.key)] = test.value
+	}
+
+	// the snapshot is taken before either trie is hashed, so the caches of the nodes they share are filled by
+	// both of them at once. run with -race to check the caches are guarded
+	snapshot := trie.Snapshot()
+	snapshotEntries := make(map[string][]byte)
+	for k, v := range entries {
+		snapshotEntries[k] = v
+	}
+
+	for _, test := range generateRandomTests(10) {
+		err := snapshot.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshotEntries[string(test.key)] = test.value
+	}
+
+	hashes := make([]common.Hash, 2)
+	errs := make([]error, 2)
+	wg := new(sync.WaitGroup)
+	for i, tr := range []*Trie{trie, snapshot} {
+		wg.Add(1)
+		go func(i int, tr *Trie) {
+			defer wg.Done()
+			hashes[i], errs[i] = tr.Hash()
+		}(i, tr)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if expected := hashSerially(t, entries); hashes[0] != expected {
+		t.Fatalf("Fail: got root %x expected %x", hashes[0], expected)
+	}
+	if expected := hashSerially(t, snapshotEntries); hashes[1] != expected {
+		t.Fatalf("Fail: got snapshot root %x expected %x", hashes[1], expected)
+	}
+}
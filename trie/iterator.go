@@ -0,0 +1,171 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+)
+
+// Iterator iterates over the key-value pairs in the trie in ascending key order
+// the trie must not be modified while it's being iterated over
+type Iterator struct {
+	trie  *Trie
+	stack []*iteratorFrame
+	seek  []byte // nibbles of the key to start iterating from
+	key   []byte // nibbles of the current key
+	value []byte
+}
+
+// iteratorFrame is a node that's being visited by the iterator
+type iteratorFrame struct {
+	n      node
+	prefix []byte // nibbles of the key up to the node's partial key
+	child  int    // index of the next child to visit, -1 if the node's value hasn't been visited yet
+}
+
+// NewIterator returns an iterator positioned before the first key in the trie
+func (t *Trie) NewIterator() *Iterator {
+	it := &Iterator{
+		trie: t,
+	}
+	it.Seek(nil)
+	return it
+}
+
+// Seek moves the iterator so that the next call to Next moves it to the first key that's
+// greater than or equal to key
+func (it *Iterator) Seek(key []byte) {
+	it.seek = keyToNibbles(key)
+	it.key = nil
+	it.value = nil
+	it.stack = []*iteratorFrame{}
+
+	if it.trie.root != nil {
+		it.stack = append(it.stack, &iteratorFrame{n: it.trie.root, child: -1})
+	}
+}
+
+// Next moves the iterator to the next key in the trie
+// it returns false once there are no more keys
+func (it *Iterator) Next() bool {
+	for len(it.stack) > 0 {
+		f := it.stack[len(it.stack)-1]
+
+		var full []byte
+		switch n := f.n.(type) {
+		case *branch:
+			full = append(copyNibbles(f.prefix), n.key...)
+		case *leaf:
+			full = append(copyNibbles(f.prefix), n.key...)
+		default:
+			it.pop()
+			continue
+		}
+
+		// all the keys in this subtree are before the key we're seeking to
+		if f.child == -1 && it.beforeSeek(full) {
+			it.pop()
+			continue
+		}
+
+		switch n := f.n.(type) {
+		case *leaf:
+			it.pop()
+			if bytes.Compare(full, it.seek) >= 0 {
+				it.key = full
+				it.value = n.value
+				return true
+			}
+		case *branch:
+			if f.child == -1 {
+				f.child = 0
+				if n.value != nil && bytes.Compare(full, it.seek) >= 0 {
+					it.key = full
+					it.value = n.value
+					return true
+				}
+			}
+
+			for f.child < 16 && n.children[f.child] == nil {
+				f.child++
+			}
+
+			if f.child == 16 {
+				it.pop()
+				continue
+			}
+
+			prefix := append(full, byte(f.child))
+			it.stack = append(it.stack, &iteratorFrame{n: n.children[f.child], prefix: prefix, child: -1})
+			f.child++
+		}
+	}
+
+	it.key = nil
+	it.value = nil
+	return false
+}
+
+// Key returns the key the iterator is positioned at
+func (it *Iterator) Key() []byte {
+	if it.key == nil {
+		return nil
+	}
+	return nibblesToKeyLE(it.key)
+}
+
+// Value returns the value the iterator is positioned at
+func (it *Iterator) Value() []byte {
+	return it.value
+}
+
+func (it *Iterator) pop() {
+	it.stack = it.stack[:len(it.stack)-1]
+}
+
+// beforeSeek returns true if every key beginning with prefix is less than the key we're seeking to
+func (it *Iterator) beforeSeek(prefix []byte) bool {
+	length := len(prefix)
+	if len(it.seek) < length {
+		length = len(it.seek)
+	}
+	return bytes.Compare(prefix[:length], it.seek[:length]) < 0
+}
+
+// copyNibbles returns a copy of the nibbles so that appending to it doesn't modify the original slice
+func copyNibbles(in []byte) []byte {
+	out := make([]byte, len(in))
+	copy(out, in)
+	return out
+}
+
+// GetKeysWithPrefix returns all the keys in the trie that begin with prefix, in ascending order
+func (t *Trie) GetKeysWithPrefix(prefix []byte) [][]byte {
+	keys := [][]byte{}
+
+	it := t.NewIterator()
+	it.Seek(prefix)
+	for it.Next() {
+		key := it.Key()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
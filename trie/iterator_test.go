@@ -0,0 +1,137 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+)
+
+func TestIterator(t *testing.T) {
+	trie := newEmpty()
+
+	rt := generateRandomTests(1000)
+	kv := make(map[string][]byte)
+	for _, test := range rt {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(test.value) == 0 {
+			delete(kv, string(test.key))
+		} else {
+			kv[string(test.key)] = test.value
+		}
+	}
+
+	expected := []string{}
+	for k := range kv {
+		expected = append(expected, k)
+	}
+	sort.Strings(expected)
+
+	it := trie.NewIterator()
+	i := 0
+	for it.Next() {
+		if i >= len(expected) {
+			t.Fatalf("Fail: iterator returned more than %d keys", len(expected))
+		}
+
+		if !bytes.Equal(it.Key(), []byte(expected[i])) {
+			t.Fatalf("Fail: got key %x expected %x", it.Key(), []byte(expected[i]))
+		} else if !bytes.Equal(it.Value(), kv[expected[i]]) {
+			t.Fatalf("Fail: got value %x expected %x", it.Value(), kv[expected[i]])
+		}
+		i++
+	}
+
+	if i != len(expected) {
+		t.Fatalf("Fail: iterator returned %d keys expected %d", i, len(expected))
+	}
+}
+
+func TestIterator_Seek(t *testing.T) {
+	trie := buildSmallTrie()
+
+	tests := []struct {
+		seek     []byte
+		expected [][]byte
+	}{
+		{seek: nil, expected: [][]byte{{}, {0x01, 0x35}, {0x01, 0x35, 0x07}, {0x01, 0x35, 0x79}, {0x09, 0xd3}, {0xf2}}},
+		{seek: []byte{0x01, 0x35}, expected: [][]byte{{0x01, 0x35}, {0x01, 0x35, 0x07}, {0x01, 0x35, 0x79}, {0x09, 0xd3}, {0xf2}}},
+		{seek: []byte{0x01, 0x35, 0x08}, expected: [][]byte{{0x01, 0x35, 0x79}, {0x09, 0xd3}, {0xf2}}},
+		{seek: []byte{0x02}, expected: [][]byte{{0x09, 0xd3}, {0xf2}}},
+		{seek: []byte{0xf2, 0x00}, expected: [][]byte{}},
+	}
+
+	for _, test := range tests {
+		it := trie.NewIterator()
+		it.Seek(test.seek)
+
+		res := [][]byte{}
+		for it.Next() {
+			res = append(res, it.Key())
+		}
+
+		if len(res) != len(test.expected) {
+			t.Errorf("Fail to seek to %x: got %x expected %x", test.seek, res, test.expected)
+			continue
+		}
+
+		for i := range res {
+			if !bytes.Equal(res[i], test.expected[i]) {
+				t.Errorf("Fail to seek to %x: got %x expected %x", test.seek, res, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestGetKeysWithPrefix(t *testing.T) {
+	trie := buildSmallTrie()
+
+	tests := []struct {
+		prefix   []byte
+		expected [][]byte
+	}{
+		{prefix: []byte{0x01, 0x35}, expected: [][]byte{{0x01, 0x35}, {0x01, 0x35, 0x07}, {0x01, 0x35, 0x79}}},
+		{prefix: []byte{0x01, 0x35, 0x79}, expected: [][]byte{{0x01, 0x35, 0x79}}},
+		{prefix: []byte{0x09}, expected: [][]byte{{0x09, 0xd3}}},
+		{prefix: []byte{0x02}, expected: [][]byte{}},
+	}
+
+	for _, test := range tests {
+		res := trie.GetKeysWithPrefix(test.prefix)
+		if len(res) != len(test.expected) {
+			t.Errorf("Fail to get keys with prefix %x: got %x expected %x", test.prefix, res, test.expected)
+			continue
+		}
+
+		for i := range res {
+			if !bytes.Equal(res[i], test.expected[i]) {
+				t.Errorf("Fail to get keys with prefix %x: got %x expected %x", test.prefix, res, test.expected)
+				break
+			}
+		}
+	}
+
+	res := trie.GetKeysWithPrefix(nil)
+	if len(res) != len(trie.Entries()) {
+		t.Errorf("Fail to get keys with empty prefix: got %d keys expected %d", len(res), len(trie.Entries()))
+	}
+}
@@ -18,7 +18,10 @@ package trie
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
+	"io"
+	"sync"
 
 	scale "github.com/ChainSafe/gossamer/codec"
 	"github.com/ChainSafe/gossamer/common"
@@ -26,22 +29,38 @@ import (
 
 type node interface {
 	Encode() ([]byte, error)
+	Decode(r io.Reader, h byte) error
 	isDirty() bool
 	setDirty(dirty bool)
 	setKey(key []byte)
+	getHash() []byte
+	setHash(h []byte)
+	getGeneration() uint64
+	copy() node
 }
 
+// cacheLock guards the cached merkle values and encodings of nodes, and their dirty flags. nodes are shared between
+// a trie and its snapshots, so a node's cache can be filled by two tries being hashed at the same time, and a trie
+// can be written to the database while a snapshot of it is being modified
+var cacheLock sync.RWMutex
+
 type (
 	branch struct {
-		key      []byte // partial key
-		children [16]node
-		value    []byte
-		dirty    bool
+		key        []byte // partial key
+		children   [16]node
+		value      []byte
+		dirty      bool
+		hash       []byte // cached merkle value, cleared when the node is modified
+		encoding   []byte // cached encoding, cleared when the node is modified
+		generation uint64 // generation of the trie that created the node, see Trie.Snapshot
 	}
 	leaf struct {
-		key   []byte // partial key
-		value []byte
-		dirty bool
+		key        []byte // partial key
+		value      []byte
+		dirty      bool
+		hash       []byte // cached merkle value, cleared when the node is modified
+		encoding   []byte // cached encoding, cleared when the node is modified
+		generation uint64 // generation of the trie that created the node, see Trie.Snapshot
 	}
 )
 
@@ -67,19 +86,38 @@ func (b *branch) numChildren() int {
 }
 
 func (l *leaf) isDirty() bool {
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
 	return l.dirty
 }
 
 func (b *branch) isDirty() bool {
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
 	return b.dirty
 }
 
+// setDirty marks the node as needing to be written to the database
+// a node is marked dirty whenever it or one of its descendants is modified, so its cached encoding
+// and merkle value are cleared
 func (l *leaf) setDirty(dirty bool) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
 	l.dirty = dirty
+	if dirty {
+		l.hash = nil
+		l.encoding = nil
+	}
 }
 
 func (b *branch) setDirty(dirty bool) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
 	b.dirty = dirty
+	if dirty {
+		b.hash = nil
+		b.encoding = nil
+	}
 }
 
 func (l *leaf) setKey(key []byte) {
@@ -90,6 +128,65 @@ func (b *branch) setKey(key []byte) {
 	b.key = key
 }
 
+func (l *leaf) getHash() []byte {
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
+	return l.hash
+}
+
+func (b *branch) getHash() []byte {
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
+	return b.hash
+}
+
+func (l *leaf) setHash(h []byte) {
+	cacheLock.Lock()
+	l.hash = h
+	cacheLock.Unlock()
+}
+
+func (b *branch) setHash(h []byte) {
+	cacheLock.Lock()
+	b.hash = h
+	cacheLock.Unlock()
+}
+
+func (l *leaf) getGeneration() uint64 {
+	return l.generation
+}
+
+func (b *branch) getGeneration() uint64 {
+	return b.generation
+}
+
+// copy returns a shallow copy of the branch; the children are shared with the original branch
+// the partial key is copied, since it may be appended to when the copy is modified
+// the encoding and merkle value aren't copied, since a node is only copied right before it's modified
+func (b *branch) copy() node {
+	cpy := &branch{
+		key:        make([]byte, len(b.key)),
+		children:   b.children,
+		value:      b.value,
+		dirty:      b.isDirty(),
+		generation: b.generation,
+	}
+	copy(cpy.key, b.key)
+	return cpy
+}
+
+// copy returns a copy of the leaf
+func (l *leaf) copy() node {
+	cpy := &leaf{
+		key:        make([]byte, len(l.key)),
+		value:      l.value,
+		dirty:      l.isDirty(),
+		generation: l.generation,
+	}
+	copy(cpy.key, l.key)
+	return cpy
+}
+
 // Encode is the high-level function wrapping the encoding for different node types
 // encoding has the following format:
 // NodeHeader | Extra partial key length | Partial Key | Value
@@ -117,6 +214,24 @@ func Encode(n node) ([]byte, error) {
 // Value is:
 // Children Bitmap | Enc(Child[i_1]) | Enc(Child[i_2]) | ... | Enc(Child[i_n]) | SCALE Branch Node Value
 func (b *branch) Encode() ([]byte, error) {
+	hasher, err := NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.encode(hasher)
+}
+
+// encode encodes the branch, using the hasher to compute the merkle values of any children that aren't cached
+// the encoding is cached on the branch until it's modified
+func (b *branch) encode(hasher *Hasher) ([]byte, error) {
+	cacheLock.RLock()
+	encoding := b.encoding
+	cacheLock.RUnlock()
+	if encoding != nil {
+		return encoding, nil
+	}
+
 	encoding, err := b.header()
 	if err != nil {
 		return nil, err
@@ -137,11 +252,6 @@ func (b *branch) Encode() ([]byte, error) {
 
 	for _, child := range b.children {
 		if child != nil {
-			hasher, err := NewHasher()
-			if err != nil {
-				return nil, err
-			}
-
 			encChild, err := hasher.Hash(child)
 			if err != nil {
 				return encoding, err
@@ -154,6 +264,9 @@ func (b *branch) Encode() ([]byte, error) {
 		}
 	}
 
+	cacheLock.Lock()
+	b.encoding = encoding
+	cacheLock.Unlock()
 	return encoding, nil
 }
 
@@ -166,7 +279,15 @@ func (b *branch) Encode() ([]byte, error) {
 // consists of the remaining key length
 // Partial Key is the leaf's key
 // Value is the leaf's SCALE encoded value
+// the encoding is cached on the leaf until it's modified
 func (l *leaf) Encode() ([]byte, error) {
+	cacheLock.RLock()
+	encoding := l.encoding
+	cacheLock.RUnlock()
+	if encoding != nil {
+		return encoding, nil
+	}
+
 	encoding, err := l.header()
 	if err != nil {
 		return nil, err
@@ -182,6 +303,9 @@ func (l *leaf) Encode() ([]byte, error) {
 	}
 	encoding = append(encoding, buffer.Bytes()...)
 
+	cacheLock.Lock()
+	l.encoding = encoding
+	cacheLock.Unlock()
 	return encoding, nil
 }
 
@@ -248,3 +372,158 @@ func encodeExtraPartialKeyLength(pkLen int) ([]byte, error) {
 
 	return fullHeader, nil
 }
+
+// Decode wraps the decoding of different node types back into a node
+// the first byte of the encoding is the node header, which determines the node type
+func Decode(r io.Reader) (node, error) {
+	header, err := readByte(r)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeType := header >> 6
+	switch nodeType {
+	case 1:
+		l := new(leaf)
+		err = l.Decode(r, header)
+		return l, err
+	case 2, 3:
+		b := new(branch)
+		err = b.Decode(r, header)
+		return b, err
+	default:
+		if header == 0 {
+			return nil, nil
+		}
+		return nil, errors.New("cannot decode node: invalid node type")
+	}
+}
+
+// Decode decodes a byte array with the encoding specified by branch.Encode into a branch node
+// the header byte has already been read from r and is passed in as h
+// children whose encoding is < 32 bytes are stored inline in the branch and are decoded directly.
+// otherwise the encoded branch only stores the hash of the child, so the child can't be
+// reconstructed from the encoding alone; instead it is stubbed with a leaf holding the child's hash
+func (b *branch) Decode(r io.Reader, h byte) (err error) {
+	if h>>6 != 2 && h>>6 != 3 {
+		return errors.New("cannot decode branch: invalid node type")
+	}
+
+	b.key, err = decodeKey(r, h)
+	if err != nil {
+		return err
+	}
+
+	bitmapBytes := make([]byte, 2)
+	_, err = io.ReadFull(r, bitmapBytes)
+	if err != nil {
+		return err
+	}
+	bitmap := binary.LittleEndian.Uint16(bitmapBytes)
+
+	sd := &scale.Decoder{Reader: r}
+
+	if h>>6 == 3 {
+		b.value, err = sd.DecodeByteArray()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < 16; i++ {
+		if (bitmap>>uint(i))&1 == 1 {
+			hash, err := sd.DecodeByteArray()
+			if err != nil {
+				return err
+			}
+
+			if len(hash) < 32 {
+				child, err := Decode(bytes.NewReader(hash))
+				if err != nil {
+					return err
+				} else if child == nil {
+					return errors.New("cannot decode branch: invalid inline child")
+				}
+
+				child.setHash(hash)
+				b.children[i] = child
+				continue
+			}
+
+			b.children[i] = &leaf{
+				hash: hash,
+			}
+		}
+	}
+
+	b.dirty = false
+	return nil
+}
+
+// Decode decodes a byte array with the encoding specified by leaf.Encode into a leaf node
+// the header byte has already been read from r and is passed in as h
+func (l *leaf) Decode(r io.Reader, h byte) (err error) {
+	if h>>6 != 1 {
+		return errors.New("cannot decode leaf: invalid node type")
+	}
+
+	l.key, err = decodeKey(r, h)
+	if err != nil {
+		return err
+	}
+
+	sd := &scale.Decoder{Reader: r}
+	l.value, err = sd.DecodeByteArray()
+	if err != nil {
+		return err
+	}
+
+	l.dirty = false
+	return nil
+}
+
+// decodeKey reads the partial key from r given the node header
+// the partial key length is stored in the lower six bits of the header, followed by
+// the extra partial key length bytes if the length is >= 63
+func decodeKey(r io.Reader, h byte) ([]byte, error) {
+	pkLen := int(h & 0x3f)
+
+	if pkLen == 0x3f {
+		for {
+			b, err := readByte(r)
+			if err != nil {
+				return nil, err
+			}
+
+			pkLen += int(b)
+			if b < 255 {
+				break
+			}
+
+			if pkLen >= 1<<16+63 {
+				return nil, errors.New("partial key length greater than or equal to 2^16")
+			}
+		}
+	}
+
+	if pkLen == 0 {
+		return []byte{}, nil
+	}
+
+	key := make([]byte, pkLen/2+pkLen%2)
+	_, err := io.ReadFull(r, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyToNibbles(key)[pkLen%2:], nil
+}
+
+func readByte(r io.Reader) (byte, error) {
+	buf := make([]byte, 1)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
@@ -65,24 +65,24 @@ func TestBranchHeader(t *testing.T) {
 		br     *branch
 		header []byte
 	}{
-		{&branch{nil, [16]node{}, nil, true}, []byte{0x80}},
-		{&branch{[]byte{0x00}, [16]node{}, nil, true}, []byte{0x81}},
-		{&branch{[]byte{0x00, 0x00, 0xf, 0x3}, [16]node{}, nil, true}, []byte{0x84}},
-
-		{&branch{nil, [16]node{}, []byte{0x01}, true}, []byte{0xc0}},
-		{&branch{[]byte{0x00}, [16]node{}, []byte{0x01}, true}, []byte{0xc1}},
-		{&branch{[]byte{0x00, 0x00}, [16]node{}, []byte{0x01}, true}, []byte{0xc2}},
-		{&branch{[]byte{0x00, 0x00, 0xf}, [16]node{}, []byte{0x01}, true}, []byte{0xc3}},
-
-		{&branch{byteArray(62), [16]node{}, nil, true}, []byte{0xbe}},
-		{&branch{byteArray(62), [16]node{}, []byte{0x00}, true}, []byte{0xfe}},
-		{&branch{byteArray(63), [16]node{}, nil, true}, []byte{0xbf, 0}},
-		{&branch{byteArray(64), [16]node{}, nil, true}, []byte{0xbf, 1}},
-		{&branch{byteArray(64), [16]node{}, []byte{0x01}, true}, []byte{0xff, 1}},
-
-		{&branch{byteArray(317), [16]node{}, []byte{0x01}, true}, []byte{255, 254}},
-		{&branch{byteArray(318), [16]node{}, []byte{0x01}, true}, []byte{255, 255, 0}},
-		{&branch{byteArray(573), [16]node{}, []byte{0x01}, true}, []byte{255, 255, 255, 0}},
+		{&branch{dirty: true}, []byte{0x80}},
+		{&branch{key: []byte{0x00}, dirty: true}, []byte{0x81}},
+		{&branch{key: []byte{0x00, 0x00, 0xf, 0x3}, dirty: true}, []byte{0x84}},
+
+		{&branch{value: []byte{0x01}, dirty: true}, []byte{0xc0}},
+		{&branch{key: []byte{0x00}, value: []byte{0x01}, dirty: true}, []byte{0xc1}},
+		{&branch{key: []byte{0x00, 0x00}, value: []byte{0x01}, dirty: true}, []byte{0xc2}},
+		{&branch{key: []byte{0x00, 0x00, 0xf}, value: []byte{0x01}, dirty: true}, []byte{0xc3}},
+
+		{&branch{key: byteArray(62), dirty: true}, []byte{0xbe}},
+		{&branch{key: byteArray(62), value: []byte{0x00}, dirty: true}, []byte{0xfe}},
+		{&branch{key: byteArray(63), dirty: true}, []byte{0xbf, 0}},
+		{&branch{key: byteArray(64), dirty: true}, []byte{0xbf, 1}},
+		{&branch{key: byteArray(64), value: []byte{0x01}, dirty: true}, []byte{0xff, 1}},
+
+		{&branch{key: byteArray(317), value: []byte{0x01}, dirty: true}, []byte{255, 254}},
+		{&branch{key: byteArray(318), value: []byte{0x01}, dirty: true}, []byte{255, 255, 0}},
+		{&branch{key: byteArray(573), value: []byte{0x01}, dirty: true}, []byte{255, 255, 255, 0}},
 	}
 
 	for _, test := range tests {
@@ -101,7 +101,7 @@ func TestFailingPk(t *testing.T) {
 		br     *branch
 		header []byte
 	}{
-		{&branch{byteArray(2 << 16), [16]node{}, []byte{0x01}, true}, []byte{255, 254}},
+		{&branch{key: byteArray(2 << 16), value: []byte{0x01}, dirty: true}, []byte{255, 254}},
 	}
 
 	for _, test := range tests {
@@ -117,15 +117,15 @@ func TestLeafHeader(t *testing.T) {
 		br     *leaf
 		header []byte
 	}{
-		{&leaf{nil, nil, true}, []byte{0x40}},
-		{&leaf{[]byte{0x00}, nil, true}, []byte{0x41}},
-		{&leaf{[]byte{0x00, 0x00, 0xf, 0x3}, nil, true}, []byte{0x44}},
-		{&leaf{byteArray(62), nil, true}, []byte{0x7e}},
-		{&leaf{byteArray(63), nil, true}, []byte{0x7f, 0}},
-		{&leaf{byteArray(64), []byte{0x01}, true}, []byte{0x7f, 1}},
-
-		{&leaf{byteArray(318), []byte{0x01}, true}, []byte{0x7f, 0xff, 0}},
-		{&leaf{byteArray(573), []byte{0x01}, true}, []byte{0x7f, 0xff, 0xff, 0}},
+		{&leaf{dirty: true}, []byte{0x40}},
+		{&leaf{key: []byte{0x00}, dirty: true}, []byte{0x41}},
+		{&leaf{key: []byte{0x00, 0x00, 0xf, 0x3}, dirty: true}, []byte{0x44}},
+		{&leaf{key: byteArray(62), dirty: true}, []byte{0x7e}},
+		{&leaf{key: byteArray(63), dirty: true}, []byte{0x7f, 0}},
+		{&leaf{key: byteArray(64), value: []byte{0x01}, dirty: true}, []byte{0x7f, 1}},
+
+		{&leaf{key: byteArray(318), value: []byte{0x01}, dirty: true}, []byte{0x7f, 0xff, 0}},
+		{&leaf{key: byteArray(573), value: []byte{0x01}, dirty: true}, []byte{0x7f, 0xff, 0xff, 0}},
 	}
 
 	for i, test := range tests {
@@ -248,3 +248,202 @@ func TestEncodeRoot(t *testing.T) {
 		}
 	}
 }
+
+func TestDecodeLeaf(t *testing.T) {
+	randKeys := generateRand(100)
+	randVals := generateRand(100)
+
+	for i, testKey := range randKeys {
+		l := &leaf{key: keyToNibbles(testKey), value: randVals[i], dirty: true}
+		if i%2 == 0 {
+			// make sure odd length partial keys are decoded
+			l.key = l.key[1:]
+		}
+
+		enc, err := l.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := Decode(bytes.NewReader(enc))
+		if err != nil {
+			t.Fatalf("Fail to decode leaf %x: %s", enc, err)
+		}
+
+		dec, ok := res.(*leaf)
+		if !ok {
+			t.Fatalf("Fail to decode leaf: got %T", res)
+		} else if !bytes.Equal(dec.key, l.key) {
+			t.Errorf("Fail to decode leaf key: got %x expected %x", dec.key, l.key)
+		} else if !bytes.Equal(dec.value, l.value) {
+			t.Errorf("Fail to decode leaf value: got %x expected %x", dec.value, l.value)
+		} else if dec.isDirty() {
+			t.Error("Fail: decoded leaf should not be dirty")
+		}
+	}
+}
+
+func TestDecodeBranch(t *testing.T) {
+	tests := []*branch{
+		{key: []byte{}, children: [16]node{}},
+		{key: []byte{0x00}, children: [16]node{}, value: []byte{0x01}},
+		{key: byteArray(63), children: [16]node{}, value: []byte{0x01}},
+		{key: byteArray(318), children: [16]node{}},
+		{key: []byte{0x01, 0x02}, children: [16]node{
+			&leaf{key: []byte{0x03}, value: []byte{0x07}},
+			nil,
+			&leaf{key: generateRandBytes(64), value: byteArray(64)},
+		}, value: []byte("noot")},
+		{key: []byte{0x0f}, children: [16]node{
+			nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+			&branch{key: []byte{0x01}, children: [16]node{&leaf{key: []byte{0x02}, value: []byte{0x03}}}},
+		}},
+	}
+
+	for i, test := range tests {
+		test := test
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			enc, err := test.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := Decode(bytes.NewReader(enc))
+			if err != nil {
+				t.Fatalf("Fail to decode branch %x: %s", enc, err)
+			}
+
+			dec, ok := res.(*branch)
+			if !ok {
+				t.Fatalf("Fail to decode branch: got %T", res)
+			} else if !bytes.Equal(dec.key, test.key) {
+				t.Errorf("Fail to decode branch key: got %x expected %x", dec.key, test.key)
+			} else if !bytes.Equal(dec.value, test.value) {
+				t.Errorf("Fail to decode branch value: got %x expected %x", dec.value, test.value)
+			} else if dec.childrenBitmap() != test.childrenBitmap() {
+				t.Errorf("Fail to decode branch children: got %b expected %b", dec.childrenBitmap(), test.childrenBitmap())
+			}
+
+			// each decoded child should have the merkle value of the original child
+			for j, child := range test.children {
+				if child == nil {
+					continue
+				}
+
+				hasher, err := NewHasher()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expected, err := hasher.Hash(child)
+				if err != nil {
+					t.Fatal(err)
+				} else if !bytes.Equal(dec.children[j].getHash(), expected) {
+					t.Errorf("Fail to decode child %d: got %x expected %x", j, dec.children[j].getHash(), expected)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeInlineChild(t *testing.T) {
+	child := &leaf{key: []byte{0x03}, value: []byte{0x07}}
+	b := &branch{key: []byte{0x01}, value: []byte{0x01}}
+	b.children[3] = child
+
+	enc, err := b.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Decode(bytes.NewReader(enc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec, ok := res.(*branch).children[3].(*leaf)
+	if !ok {
+		t.Fatalf("Fail to decode inline child: got %T", res.(*branch).children[3])
+	} else if !bytes.Equal(dec.key, child.key) || !bytes.Equal(dec.value, child.value) {
+		t.Errorf("Fail to decode inline child: got %v expected %v", dec, child)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := [][]byte{
+		{},
+		{0x01},
+		{0x41},
+		{0x7f},
+		{0x7f, 0xff},
+		{0x82, 0x12},
+		{0xc0, 0x00, 0x00},
+	}
+
+	for _, test := range tests {
+		_, err := Decode(bytes.NewReader(test))
+		if err == nil {
+			t.Errorf("Fail: should error when decoding %x", test)
+		}
+	}
+
+	res, err := Decode(bytes.NewReader([]byte{0}))
+	if err != nil {
+		t.Fatal(err)
+	} else if res != nil {
+		t.Errorf("Fail: should decode empty node as nil, got %v", res)
+	}
+}
+
+func TestEncodeCached(t *testing.T) {
+	trie := &Trie{}
+
+	// keys with different first nibbles end up in different subtrees of the root
+	keys := [][]byte{{0x10, 0x01}, {0x10, 0x02}, {0x20, 0x01}, {0x20, 0x02}}
+	for _, k := range keys {
+		err := trie.Put(k, generateRandBytes(40))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := trie.root.(*branch)
+	left, right := root.children[1], root.children[2]
+	if root.encoding == nil || left.(*branch).encoding == nil || right.(*branch).encoding == nil {
+		t.Fatal("Fail: encodings were not cached when hashing the trie")
+	}
+	cached := right.(*branch).encoding
+
+	err = trie.Put([]byte{0x10, 0x03}, []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the nodes on the path to the modified key are invalidated
+	root = trie.root.(*branch)
+	if root.encoding != nil || root.hash != nil {
+		t.Errorf("Fail: root encoding was not invalidated")
+	}
+
+	if root.children[1].(*branch).encoding != nil {
+		t.Errorf("Fail: encoding of modified subtree was not invalidated")
+	}
+
+	if root.children[2] != right || !bytes.Equal(right.(*branch).encoding, cached) || right.getHash() == nil {
+		t.Errorf("Fail: encoding of unmodified subtree was invalidated")
+	}
+
+	// the cached encodings must give the same result as encoding from scratch
+	expected := hashSerially(t, trie.Entries())
+	h, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if h != expected {
+		t.Errorf("Fail: got root %x expected %x", h, expected)
+	}
+}
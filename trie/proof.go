@@ -0,0 +1,149 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ChainSafe/gossamer/common"
+)
+
+// GenerateProof returns the encoded nodes along the paths from the root to each of the keys
+// the proof can be used with VerifyProof to prove that a key is or isn't in the trie without the full trie
+// each node is only included once, even if it's on the path to multiple keys
+func (t *Trie) GenerateProof(keys [][]byte) ([][]byte, error) {
+	proof := [][]byte{}
+	seen := make(map[string]bool)
+
+	for _, key := range keys {
+		k := keyToNibbles(key)
+
+		err := t.generateProof(t.root, k, &proof, seen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return proof, nil
+}
+
+// generateProof adds the encoding of each node on the path to key to the proof
+func (t *Trie) generateProof(current node, key []byte, proof *[][]byte, seen map[string]bool) error {
+	if current == nil {
+		return nil
+	}
+
+	enc, err := current.Encode()
+	if err != nil {
+		return err
+	}
+
+	if !seen[string(enc)] {
+		seen[string(enc)] = true
+		*proof = append(*proof, enc)
+	}
+
+	switch c := current.(type) {
+	case *branch:
+		length := lenCommonPrefix(c.key, key)
+
+		// key is at this branch, or the key isn't in the trie
+		if length != len(c.key) || len(key) == length {
+			return nil
+		}
+
+		return t.generateProof(c.children[key[length]], key[length+1:], proof, seen)
+	case *leaf:
+		return nil
+	default:
+		return errors.New("generate proof error: invalid node")
+	}
+}
+
+// VerifyProof checks the proof of key against the trie with the given root
+// if the proof shows the key is in the trie, its value is returned
+// if the proof shows the key isn't in the trie, nil is returned
+// if the proof is missing a node on the path to key or is otherwise invalid, an error is returned
+func VerifyProof(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	if root == emptyHash() {
+		return nil, nil
+	}
+
+	// store the encoded nodes by their hash, so that they can be looked up by the hash stored in their parent
+	db := make(map[common.Hash][]byte)
+	for _, enc := range proof {
+		hash, err := common.Blake2bHash(enc)
+		if err != nil {
+			return nil, err
+		}
+		db[hash] = enc
+	}
+
+	enc, ok := db[root]
+	if !ok {
+		return nil, errors.New("cannot verify proof: proof does not contain root")
+	}
+
+	current, err := Decode(bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+
+	k := keyToNibbles(key)
+
+	for {
+		switch c := current.(type) {
+		case *branch:
+			length := lenCommonPrefix(c.key, k)
+
+			if length != len(c.key) {
+				return nil, nil
+			} else if len(k) == length {
+				return c.value, nil
+			}
+
+			current = c.children[k[length]]
+			k = k[length+1:]
+		case *leaf:
+			if bytes.Equal(c.key, k) {
+				return c.value, nil
+			}
+			return nil, nil
+		case nil:
+			return nil, nil
+		default:
+			return nil, errors.New("cannot verify proof: invalid node")
+		}
+
+		// children with encodings < 32 bytes are decoded along with their parent,
+		// otherwise the child is only a stub and it needs to be looked up in the proof
+		if current == nil || len(current.getHash()) < 32 {
+			continue
+		}
+
+		enc, ok = db[common.NewHash(current.getHash())]
+		if !ok {
+			return nil, errors.New("cannot verify proof: proof is missing node")
+		}
+
+		current, err = Decode(bytes.NewReader(enc))
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+)
+
+func TestVerifyProof(t *testing.T) {
+	trie := newEmpty()
+
+	rt := generateRandomTests(1000)
+	for _, test := range rt {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range rt[:100] {
+		proof, err := trie.GenerateProof([][]byte{test.key})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		val, err := VerifyProof(root, test.key, proof)
+		if err != nil {
+			t.Errorf("Fail to verify proof for key %x: %s", test.key, err)
+		} else if !bytes.Equal(val, test.value) {
+			t.Errorf("Fail to verify proof for key %x: got %x expected %x", test.key, val, test.value)
+		}
+	}
+}
+
+func TestVerifyProof_MultipleKeys(t *testing.T) {
+	trie := buildSmallTrie()
+
+	root, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []trieTest{
+		{key: []byte{0x01, 0x35}, value: []byte("pen")},
+		{key: []byte{0x01, 0x35, 0x79}, value: []byte("penguin")},
+		{key: []byte{0xf2}, value: []byte("feather")},
+		{key: []byte{}, value: []byte("floof")},
+		{key: []byte{0x01, 0x36}, value: nil},
+		{key: []byte{0x09, 0xd3, 0x00}, value: nil},
+	}
+
+	keys := [][]byte{}
+	for _, test := range tests {
+		keys = append(keys, test.key)
+	}
+
+	proof, err := trie.GenerateProof(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		val, err := VerifyProof(root, test.key, proof)
+		if err != nil {
+			t.Errorf("Fail to verify proof for key %x: %s", test.key, err)
+		} else if !bytes.Equal(val, test.value) {
+			t.Errorf("Fail to verify proof for key %x: got %x expected %x", test.key, val, test.value)
+		}
+	}
+}
+
+func TestVerifyProof_Invalid(t *testing.T) {
+	trie := newEmpty()
+
+	rt := generateRandomTests(100)
+	for _, test := range rt {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := trie.GenerateProof([][]byte{rt[0].key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// proof without the root
+	_, err = VerifyProof(root, rt[0].key, proof[1:])
+	if err == nil {
+		t.Error("Fail: should not verify proof without root")
+	}
+
+	// proof with a missing node; inline nodes are contained in their parent, so only remove a hashed node
+	if len(proof) > 2 && len(proof[len(proof)-1]) >= 32 {
+		_, err = VerifyProof(root, rt[0].key, proof[:len(proof)-1])
+		if err == nil {
+			t.Error("Fail: should not verify proof with missing node")
+		}
+	}
+
+	// proof against the wrong root
+	_, err = VerifyProof(common.NewHash([]byte("noot")), rt[0].key, proof)
+	if err == nil {
+		t.Error("Fail: should not verify proof against wrong root")
+	}
+}
@@ -0,0 +1,483 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/polkadb"
+)
+
+// Archive is the retain value which keeps every node ever written to the database
+const Archive = 0
+
+// ArchiveMode is the name of the Archive retain value in configuration, see ParseRetain
+const ArchiveMode = "archive"
+
+// ParseRetain parses a configured pruning mode: either ArchiveMode, or the number of committed roots to retain
+func ParseRetain(mode string) (int, error) {
+	if mode == ArchiveMode {
+		return Archive, nil
+	}
+
+	retain, err := strconv.Atoi(mode)
+	if err != nil || retain <= 0 {
+		return 0, fmt.Errorf("invalid pruning mode %q: expected %q or a positive number of roots to retain", mode, ArchiveMode)
+	}
+
+	return retain, nil
+}
+
+// prefixes of the pruner's entries, which are stored in the same database as the nodes
+const (
+	refPrefix     = "ref" // merkle value of a node -> big endian reference count
+	journalPrefix = "jnl" // journalKey -> encoded journal of committed roots
+)
+
+var journalKey = append([]byte(journalPrefix), "roots"...)
+
+// untracked is the reference count recorded for a node which is no longer tracked, see Pruner.counts
+const untracked = -1
+
+// Pruner removes trie nodes from the database once they are no longer referenced by any of the
+// last `retain` committed roots. Each node written through WriteToDB is reference counted by the
+// number of stored parents and retained roots that point to it; when the count drops to zero the
+// node is deleted and its children are dereferenced in turn.
+//
+// The reference counts and the journal of committed roots are written to the database in the same
+// batch as the nodes, so a pruner created on the same database after a restart carries on where the
+// previous one stopped. Nodes written without a pruner are never tracked and never deleted.
+type Pruner struct {
+	db     polkadb.Database
+	retain int
+	lock   sync.Mutex
+
+	// roots is the journal of committed roots, oldest first; each entry holds the root of the trie
+	// followed by the roots of its child tries
+	roots [][]*prunerRoot
+	// counts holds the reference counts changed by the commit in progress, which are read from the
+	// database when they're first needed; a node which is no longer tracked has the count untracked
+	counts map[string]int
+
+	// nodes written to the batch since the last WriteToDB, in the order they were written
+	pending      []*prunerNode
+	pendingRoots []*prunerRoot
+}
+
+type prunerNode struct {
+	key []byte
+	enc []byte
+}
+
+type prunerRoot struct {
+	hash common.Hash
+	key  []byte
+}
+
+// NewPruner returns a Pruner which keeps the nodes referenced by the last `retain` committed roots,
+// loading the journal written by a previous pruner on the same database. If retain is Archive, nothing
+// is ever deleted.
+func NewPruner(db polkadb.Database, retain int) (*Pruner, error) {
+	if retain < 0 {
+		retain = Archive
+	}
+
+	p := &Pruner{
+		db:     db,
+		retain: retain,
+	}
+
+	if retain == Archive {
+		return p, nil
+	}
+
+	has, err := db.Has(journalKey)
+	if err != nil || !has {
+		return p, err
+	}
+
+	enc, err := db.Get(journalKey)
+	if err != nil {
+		return nil, err
+	}
+
+	p.roots, err = decodeJournal(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Retain returns the number of committed roots whose nodes are kept
+func (p *Pruner) Retain() int {
+	return p.retain
+}
+
+// Roots returns the committed roots which are currently retained, oldest first
+func (p *Pruner) Roots() []common.Hash {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	roots := make([]common.Hash, len(p.roots))
+	for i, r := range p.roots {
+		roots[i] = r[0].hash
+	}
+	return roots
+}
+
+// reset discards any nodes recorded since the last commit
+func (p *Pruner) reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pending = nil
+	p.pendingRoots = nil
+}
+
+// record stores a node which was written to the batch under the given key
+func (p *Pruner) record(key, enc []byte) {
+	if p.retain == Archive {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pending = append(p.pending, &prunerNode{key: key, enc: enc})
+}
+
+// recordRoot stores a root which will be added to the journal on commit
+func (p *Pruner) recordRoot(hash common.Hash, key []byte) {
+	if p.retain == Archive {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pendingRoots = append(p.pendingRoots, &prunerRoot{hash: hash, key: key})
+}
+
+// commit updates the reference counts with the nodes written since the last WriteToDB, adds the
+// pending roots to the journal and prunes any nodes no longer referenced by the retained roots.
+// The updated counts and journal, and the deletions, are added to the batch, which is then written.
+// If writing fails, the journal is left as it was before the commit.
+func (p *Pruner) commit(batch polkadb.Batch) error {
+	if p.retain == Archive {
+		return batch.Write()
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.counts = make(map[string]int)
+	defer func() {
+		p.counts = nil
+	}()
+
+	// nodes are written parent first, so go through them in reverse to make sure
+	// children are tracked before their parents reference them
+	for i := len(p.pending) - 1; i >= 0; i-- {
+		n := p.pending[i]
+		_, tracked, err := p.count(n.key)
+		if err != nil {
+			return err
+		}
+		if tracked {
+			continue
+		}
+
+		p.counts[string(n.key)] = 0
+
+		children, err := childKeys(n.enc)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			err = p.inc(child)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	p.pending = nil
+
+	roots := p.roots
+	if len(p.pendingRoots) > 0 {
+		for _, r := range p.pendingRoots {
+			err := p.inc(r.key)
+			if err != nil {
+				return err
+			}
+		}
+		roots = append(roots[:len(roots):len(roots)], p.pendingRoots)
+		p.pendingRoots = nil
+	}
+
+	for len(roots) > p.retain {
+		pruned := roots[0]
+		roots = roots[1:]
+
+		for _, r := range pruned {
+			err := p.deref(batch, roots, r)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, count := range p.counts {
+		err := p.writeCount(batch, []byte(key), count)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := batch.Put(journalKey, encodeJournal(roots))
+	if err != nil {
+		return err
+	}
+
+	err = batch.Write()
+	if err != nil {
+		return err
+	}
+
+	p.roots = roots
+	return nil
+}
+
+// deref removes a reference to the root, deleting it if it's no longer referenced. a root whose
+// encoding is < 32 bytes is also stored under its hash, which is deleted once none of the remaining
+// roots in the journal use it, see writeRootToDB
+func (p *Pruner) deref(batch polkadb.Batch, roots [][]*prunerRoot, r *prunerRoot) error {
+	if !bytes.Equal(r.hash[:], r.key) && !journalHas(roots, r.hash) {
+		err := batch.Delete(r.hash[:])
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.dec(batch, r.key)
+}
+
+// journalHas returns true if a root with the given hash is in the journal
+func journalHas(roots [][]*prunerRoot, hash common.Hash) bool {
+	for _, entry := range roots {
+		for _, r := range entry {
+			if r.hash == hash {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// count returns the reference count of the node with the given key, and whether the node is tracked
+func (p *Pruner) count(key []byte) (int, bool, error) {
+	if count, ok := p.counts[string(key)]; ok {
+		return count, count != untracked, nil
+	}
+
+	refKey := append([]byte(refPrefix), key...)
+	has, err := p.db.Has(refKey)
+	if err != nil || !has {
+		return 0, false, err
+	}
+
+	enc, err := p.db.Get(refKey)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(enc) != 8 {
+		return 0, false, errors.New("cannot read reference count: invalid encoding")
+	}
+
+	count := int(binary.BigEndian.Uint64(enc))
+	p.counts[string(key)] = count
+	return count, true, nil
+}
+
+// writeCount adds the reference count of the node with the given key to the batch, removing it if the
+// node is no longer tracked
+func (p *Pruner) writeCount(batch polkadb.Batch, key []byte, count int) error {
+	refKey := append([]byte(refPrefix), key...)
+	if count == untracked {
+		return batch.Delete(refKey)
+	}
+
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, uint64(count))
+	return batch.Put(refKey, enc)
+}
+
+// inc increments the reference count of the node with the given key, if it is tracked
+func (p *Pruner) inc(key []byte) error {
+	count, tracked, err := p.count(key)
+	if err != nil || !tracked {
+		return err
+	}
+
+	p.counts[string(key)] = count + 1
+	return nil
+}
+
+// dec decrements the reference count of the node with the given key, if it is tracked.
+// If the node is no longer referenced, it is deleted from the database and its children are dereferenced.
+func (p *Pruner) dec(batch polkadb.Batch, key []byte) error {
+	count, tracked, err := p.count(key)
+	if err != nil || !tracked {
+		return err
+	}
+
+	if count > 1 {
+		p.counts[string(key)] = count - 1
+		return nil
+	}
+
+	p.counts[string(key)] = untracked
+
+	enc, err := p.db.Get(key)
+	if err != nil {
+		return err
+	}
+
+	err = batch.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	children, err := childKeys(enc)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		err = p.dec(batch, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeJournal encodes the journal as its number of entries, followed by the number of roots in each
+// entry and the hash, key length and key of each root
+func encodeJournal(roots [][]*prunerRoot) []byte {
+	buf := &bytes.Buffer{}
+	n := make([]byte, 4)
+
+	binary.BigEndian.PutUint32(n, uint32(len(roots)))
+	buf.Write(n)
+	for _, entry := range roots {
+		binary.BigEndian.PutUint32(n, uint32(len(entry)))
+		buf.Write(n)
+		for _, r := range entry {
+			buf.Write(r.hash[:])
+			buf.WriteByte(byte(len(r.key)))
+			buf.Write(r.key)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// decodeJournal decodes a journal encoded by encodeJournal
+func decodeJournal(enc []byte) ([][]*prunerRoot, error) {
+	errInvalid := errors.New("cannot decode pruner journal: invalid encoding")
+	r := bytes.NewReader(enc)
+
+	var entries uint32
+	err := binary.Read(r, binary.BigEndian, &entries)
+	if err != nil {
+		return nil, errInvalid
+	}
+
+	roots := [][]*prunerRoot{}
+	for i := uint32(0); i < entries; i++ {
+		var size uint32
+		err = binary.Read(r, binary.BigEndian, &size)
+		if err != nil || int(size) > r.Len() {
+			return nil, errInvalid
+		}
+
+		entry := make([]*prunerRoot, size)
+		for j := range entry {
+			root := &prunerRoot{}
+			_, err = io.ReadFull(r, root.hash[:])
+			if err != nil {
+				return nil, errInvalid
+			}
+
+			length, err := r.ReadByte()
+			if err != nil || length > 32 {
+				return nil, errInvalid
+			}
+
+			root.key = make([]byte, length)
+			_, err = io.ReadFull(r, root.key)
+			if err != nil {
+				return nil, errInvalid
+			}
+
+			entry[j] = root
+		}
+		roots = append(roots, entry)
+	}
+
+	if r.Len() != 0 {
+		return nil, errInvalid
+	}
+
+	return roots, nil
+}
+
+// childKeys returns the merkle values of the children of the encoded node
+func childKeys(enc []byte) ([][]byte, error) {
+	if len(enc) == 0 {
+		return nil, nil
+	}
+
+	n, err := Decode(bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := n.(*branch)
+	if !ok {
+		return nil, nil
+	}
+
+	keys := [][]byte{}
+	for _, child := range b.children {
+		if child != nil {
+			keys = append(keys, child.getHash())
+		}
+	}
+
+	return keys, nil
+}
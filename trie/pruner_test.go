@@ -0,0 +1,355 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ChainSafe/gossamer/common"
+	"github.com/ChainSafe/gossamer/polkadb"
+)
+
+func newPrunedTrie(t *testing.T, retain int) (*Trie, *polkadb.MemDatabase) {
+	hasher, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := polkadb.NewMemDatabase()
+	pruner, err := NewPruner(db, retain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trie := NewEmptyTrie(&Database{
+		Db:     db,
+		Hasher: hasher,
+		Pruner: pruner,
+	})
+
+	return trie, db
+}
+
+// nodeKeys returns the keys of the nodes in the db, leaving out the pruner's reference counts and journal
+func nodeKeys(db *polkadb.MemDatabase) [][]byte {
+	keys := [][]byte{}
+	for _, key := range db.Keys() {
+		if bytes.HasPrefix(key, []byte(refPrefix)) || bytes.Equal(key, journalKey) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// commitRandom puts some random key-values into the trie, writes it to the db and returns the new root
+func commitRandom(t *testing.T, trie *Trie, size int) (common.Hash, []trieTest) {
+	rt := generateRandomTests(size)
+	for _, test := range rt {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatalf("Fail to put with key %x and value %x: %s", test.key, test.value, err)
+		}
+	}
+
+	err := trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return root, rt
+}
+
+// reachable adds the keys of all nodes stored in the db under the given key to the set
+func reachable(t *testing.T, db polkadb.Database, key []byte, set map[string]bool) {
+	enc, err := db.Get(key)
+	if err != nil || len(enc) == 0 {
+		t.Fatalf("Fail to get node %x from db", key)
+	}
+
+	set[string(key)] = true
+
+	children, err := childKeys(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, child := range children {
+		reachable(t, db, child, set)
+	}
+}
+
+func TestPruner_Archive(t *testing.T) {
+	trie, db := newPrunedTrie(t, Archive)
+
+	roots := []common.Hash{}
+	tests := [][]trieTest{}
+	for i := 0; i < 4; i++ {
+		root, rt := commitRandom(t, trie, 100)
+		roots = append(roots, root)
+		tests = append(tests, rt)
+	}
+
+	for i, root := range roots {
+		res, err := LoadFromDB(trie.db, root)
+		if err != nil {
+			t.Fatalf("Fail to load root %x: %s", root, err)
+		}
+
+		for _, test := range tests[i] {
+			val, err := res.Get(test.key)
+			if err != nil {
+				t.Errorf("Fail to get key %x: %s", test.key, err)
+			} else if !bytes.Equal(val, test.value) {
+				t.Errorf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
+			}
+		}
+	}
+
+	if len(trie.db.Pruner.Roots()) != 0 {
+		t.Errorf("Fail: archive pruner should not journal roots")
+	}
+
+	if len(nodeKeys(db)) == 0 {
+		t.Errorf("Fail: nothing was written to the db")
+	}
+}
+
+func TestPruner_Prune(t *testing.T) {
+	retain := 2
+	trie, db := newPrunedTrie(t, retain)
+
+	roots := []common.Hash{}
+	for i := 0; i < 5; i++ {
+		root, _ := commitRandom(t, trie, 100)
+		roots = append(roots, root)
+
+		// delete some of the keys so that old nodes become unreferenced
+		rt := generateRandomTests(10)
+		for _, test := range rt {
+			err := trie.Put(test.key, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, test := range rt {
+			err := trie.Delete(test.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	retained := trie.db.Pruner.Roots()
+	if len(retained) != retain {
+		t.Fatalf("Fail: got %d retained roots expected %d", len(retained), retain)
+	}
+
+	for i, root := range roots {
+		_, err := LoadFromDB(trie.db, root)
+		if i < len(roots)-retain && err == nil {
+			t.Errorf("Fail: root %x should have been pruned", root)
+		} else if i >= len(roots)-retain && err != nil {
+			t.Errorf("Fail to load retained root %x: %s", root, err)
+		}
+	}
+
+	// the db should only contain the nodes of the retained roots
+	expected := make(map[string]bool)
+	for _, root := range retained {
+		expected[string(root[:])] = true
+		reachable(t, db, root[:], expected)
+	}
+
+	keys := nodeKeys(db)
+	if len(keys) != len(expected) {
+		t.Errorf("Fail: got %d keys in db expected %d", len(keys), len(expected))
+	}
+
+	for _, key := range keys {
+		if !expected[string(key)] {
+			t.Errorf("Fail: unreferenced node %x was not pruned", key)
+		}
+	}
+}
+
+func TestPruner_Restart(t *testing.T) {
+	retain := 2
+	trie, db := newPrunedTrie(t, retain)
+
+	roots := []common.Hash{}
+	for i := 0; i < 3; i++ {
+		root, _ := commitRandom(t, trie, 100)
+		roots = append(roots, root)
+	}
+
+	// a new pruner on the same db picks up the journal and reference counts of the previous one
+	pruner, err := NewPruner(db, retain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pruner.Roots(), trie.db.Pruner.Roots()) {
+		t.Fatalf("Fail: got roots %x after restart expected %x", pruner.Roots(), trie.db.Pruner.Roots())
+	}
+
+	restarted, err := LoadFromDB(&Database{
+		Db:     db,
+		Hasher: trie.db.Hasher,
+		Pruner: pruner,
+	}, roots[len(roots)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		root, _ := commitRandom(t, restarted, 100)
+		roots = append(roots, root)
+	}
+
+	// nodes written before the restart are pruned once they're no longer referenced
+	for i, root := range roots {
+		_, err := LoadFromDB(restarted.db, root)
+		if i < len(roots)-retain && err == nil {
+			t.Errorf("Fail: root %x should have been pruned", root)
+		} else if i >= len(roots)-retain && err != nil {
+			t.Errorf("Fail to load retained root %x: %s", root, err)
+		}
+	}
+
+	expected := make(map[string]bool)
+	for _, root := range pruner.Roots() {
+		expected[string(root[:])] = true
+		reachable(t, db, root[:], expected)
+	}
+
+	keys := nodeKeys(db)
+	if len(keys) != len(expected) {
+		t.Errorf("Fail: got %d keys in db expected %d", len(keys), len(expected))
+	}
+
+	// only the nodes still in the db have reference counts
+	for _, key := range db.Keys() {
+		if bytes.HasPrefix(key, []byte(refPrefix)) && !expected[string(key[len(refPrefix):])] {
+			t.Errorf("Fail: reference count of pruned node %x was not removed", key[len(refPrefix):])
+		}
+	}
+}
+
+func TestPruner_SmallRoot(t *testing.T) {
+	trie, db := newPrunedTrie(t, 1)
+
+	err := trie.Put([]byte{0x01}, []byte("noot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Put([]byte{0x01}, []byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.WriteToDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadFromDB(trie.db, first)
+	if err == nil {
+		t.Errorf("Fail: root %x should have been pruned", first)
+	}
+
+	second, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := LoadFromDB(trie.db, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := res.Get([]byte{0x01})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("other")) {
+		t.Errorf("Fail to get key %x with value %x: got %x", []byte{0x01}, []byte("other"), val)
+	}
+
+	// the root node is stored under both its encoding and its hash
+	if len(nodeKeys(db)) != 2 {
+		t.Errorf("Fail: got %d keys in db expected 2", len(nodeKeys(db)))
+	}
+}
+
+func TestParseRetain(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected int
+	}{
+		{ArchiveMode, Archive},
+		{"1", 1},
+		{"256", 256},
+	}
+
+	for _, test := range tests {
+		retain, err := ParseRetain(test.mode)
+		if err != nil {
+			t.Fatal(err)
+		} else if retain != test.expected {
+			t.Errorf("Fail: got %d expected %d for %q", retain, test.expected, test.mode)
+		}
+	}
+
+	for _, mode := range []string{"", "0", "-1", "noot"} {
+		_, err := ParseRetain(mode)
+		if err == nil {
+			t.Errorf("Fail: expected error for %q", mode)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// generations is used to give every trie a unique generation when a snapshot is taken
+var generations uint64
+
+// Snapshot returns a copy-on-write snapshot of the trie
+// the snapshot shares all of its nodes with the trie; a node is only copied when either the trie or the
+// snapshot modifies it, so changes to one are never seen by the other
+// the snapshot can be thrown away with Discard or applied back to the trie with Merge
+func (t *Trie) Snapshot() *Trie {
+	// both tries move to a new generation, so that neither of them modifies the nodes they now share
+	t.generation = atomic.AddUint64(&generations, 1)
+
+	// child tries are snapshotted along with the trie
+	var children map[string]*Trie
+	if len(t.children) > 0 {
+		children = make(map[string]*Trie, len(t.children))
+		for k, child := range t.children {
+			children[k] = child.Snapshot()
+		}
+	}
+
+	return &Trie{
+		db:         t.db,
+		root:       t.root,
+		generation: atomic.AddUint64(&generations, 1),
+		parent:     t,
+		base:       t.root,
+		children:   children,
+	}
+}
+
+// Merge replaces the contents of the trie with the contents of the snapshot
+// it returns an error if the snapshot was not taken from this trie, or if the trie has been modified
+// since the snapshot was taken
+// the snapshot is discarded once it's merged
+func (t *Trie) Merge(snapshot *Trie) error {
+	if snapshot.parent != t {
+		return errors.New("cannot merge snapshot: not a snapshot of this trie")
+	}
+
+	if snapshot.base != t.root {
+		return errors.New("cannot merge snapshot: trie was modified after snapshot was taken")
+	}
+
+	for k, child := range snapshot.children {
+		if child.parent != nil && (child.parent != t.children[k] || child.base != child.parent.root) {
+			return errors.New("cannot merge snapshot: child trie was modified after snapshot was taken")
+		}
+	}
+
+	// child tries that were snapshotted are merged back into the trie's child tries; child tries
+	// that were created or loaded in the snapshot are taken as they are
+	children := make(map[string]*Trie, len(snapshot.children))
+	for k, child := range snapshot.children {
+		if child.parent == nil {
+			children[k] = child
+			continue
+		}
+
+		children[k] = child.parent
+		err := child.parent.Merge(child)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.root = snapshot.root
+	t.generation = snapshot.generation
+	t.children = children
+	snapshot.Discard()
+	return nil
+}
+
+// Discard throws away the contents of the snapshot
+// a discarded snapshot is empty and cannot be merged
+func (t *Trie) Discard() {
+	t.root = nil
+	t.parent = nil
+	t.base = nil
+	t.children = nil
+	t.generation = atomic.AddUint64(&generations, 1)
+}
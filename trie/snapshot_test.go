@@ -0,0 +1,189 @@
+// Copyright 2019 ChainSafe Systems (ON) Corp.
+// This file is part of gossamer.
+//
+// The gossamer library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gossamer library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the gossamer library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	trie := newEmpty()
+
+	rt := generateRandomTests(1000)
+	for _, test := range rt {
+		err := trie.Put(test.key, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := trie.Snapshot()
+
+	// modify the snapshot, the original trie should be unchanged
+	for i, test := range rt {
+		var err error
+		if i%2 == 0 {
+			err = snapshot.Delete(test.key)
+		} else {
+			err = snapshot.Put(test.key, []byte("noot"))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if hash != expected {
+		t.Fatalf("Fail: modifying snapshot changed trie: got root %x expected %x", hash, expected)
+	}
+
+	for _, test := range rt {
+		val, err := trie.Get(test.key)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(val, test.value) {
+			t.Fatalf("Fail to get key %x with value %x: got %x", test.key, test.value, val)
+		}
+	}
+
+	for i, test := range rt {
+		val, err := snapshot.Get(test.key)
+		if err != nil {
+			t.Fatal(err)
+		} else if i%2 == 0 && val != nil {
+			t.Fatalf("Fail: key %x should be deleted in snapshot: got %x", test.key, val)
+		} else if i%2 == 1 && !bytes.Equal(val, []byte("noot")) {
+			t.Fatalf("Fail to get key %x with value %x from snapshot: got %x", test.key, []byte("noot"), val)
+		}
+	}
+}
+
+func TestSnapshot_ModifyParent(t *testing.T) {
+	trie := buildSmallTrie()
+	snapshot := trie.Snapshot()
+
+	expected, err := snapshot.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Put([]byte{0x01, 0x35, 0x46}, []byte("raccoon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Delete([]byte{0x01, 0x35})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := snapshot.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if hash != expected {
+		t.Fatalf("Fail: modifying trie changed snapshot: got root %x expected %x", hash, expected)
+	}
+
+	val, err := snapshot.Get([]byte{0x01, 0x35})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(val, []byte("pen")) {
+		t.Fatalf("Fail to get key from snapshot: got %x", val)
+	}
+
+	// the trie was modified after the snapshot was taken, so merging should fail
+	err = trie.Merge(snapshot)
+	if err == nil {
+		t.Fatal("Fail: should not merge snapshot into modified trie")
+	}
+}
+
+func TestSnapshot_Merge(t *testing.T) {
+	trie := buildSmallTrie()
+	snapshot := trie.Snapshot()
+
+	runTests(t, snapshot, []trieTest{
+		{key: []byte{0x01, 0x35, 0x46}, value: []byte("raccoon"), op: PUT},
+		{key: []byte{0x09, 0xd3}, op: DEL},
+	})
+
+	expected, err := snapshot.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trie.Merge(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if hash != expected {
+		t.Fatalf("Fail: got root %x expected %x", hash, expected)
+	}
+
+	runTests(t, trie, []trieTest{
+		{key: []byte{0x01, 0x35, 0x46}, value: []byte("raccoon"), op: GET},
+		{key: []byte{0x09, 0xd3}, value: nil, op: GET},
+		{key: []byte{0x01, 0x35}, value: []byte("pen"), op: GET},
+	})
+
+	// a merged snapshot can't be merged again
+	err = trie.Merge(snapshot)
+	if err == nil {
+		t.Fatal("Fail: should not merge snapshot twice")
+	}
+}
+
+func TestSnapshot_Discard(t *testing.T) {
+	trie := buildSmallTrie()
+
+	expected, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := trie.Snapshot()
+	err = snapshot.Put([]byte{0x01, 0x35, 0x46}, []byte("raccoon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot.Discard()
+
+	hash, err := trie.Hash()
+	if err != nil {
+		t.Fatal(err)
+	} else if hash != expected {
+		t.Fatalf("Fail: got root %x expected %x", hash, expected)
+	}
+
+	err = trie.Merge(snapshot)
+	if err == nil {
+		t.Fatal("Fail: should not merge discarded snapshot")
+	}
+}
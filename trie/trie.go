@@ -26,8 +26,12 @@ import (
 // The zero value is an empty trie with no database.
 // Use NewTrie to create a trie that sits on top of a database.
 type Trie struct {
-	db   *Database
-	root node
+	db         *Database
+	root       node
+	generation uint64
+	parent     *Trie            // trie this trie is a snapshot of, if any
+	base       node             // root of the parent trie when the snapshot was taken
+	children   map[string]*Trie // child tries that have been used, by their key without ChildStorageKeyPrefix
 }
 
 // NewEmptyTrie creates a trie with a nil root and merkleRoot
@@ -62,13 +66,39 @@ func (t *Trie) Encode() ([]byte, error) {
 }
 
 // Hash returns the hashed root of the trie
+// the roots of any child tries are updated in the trie before it's hashed
+// modified subtrees are hashed concurrently, and the merkle values of unmodified subtrees are reused
 func (t *Trie) Hash() (common.Hash, error) {
-	encRoot, err := t.Encode()
+	err := t.updateChildRoots()
 	if err != nil {
 		return [32]byte{}, err
 	}
 
-	return common.Blake2bHash(encRoot)
+	if t.root == nil {
+		return emptyHash(), nil
+	}
+
+	err = hashParallel(t.root)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	hasher, err := NewHasher()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	merkle, err := hasher.Hash(t.root)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	// the root is always hashed, even if its encoding is < 32 bytes
+	if len(merkle) < 32 {
+		return common.Blake2bHash(merkle)
+	}
+
+	return common.NewHash(merkle), nil
 }
 
 // Entries returns all the key-value pairs in the trie as a map of keys to values
@@ -107,7 +137,7 @@ func (t *Trie) tryPut(key, value []byte) (err error) {
 	var n node
 
 	if len(value) > 0 {
-		_, n, err = t.insert(t.root, k, &leaf{key: nil, value: value, dirty: true})
+		_, n, err = t.insert(t.root, k, &leaf{key: nil, value: value, dirty: true, generation: t.generation})
 	} else {
 		_, n, err = t.delete(t.root, k)
 	}
@@ -138,13 +168,17 @@ func (t *Trie) insert(parent node, key []byte, value node) (ok bool, n node, err
 		}
 	case *leaf:
 		// need to convert this leaf into a branch
-		br := &branch{dirty: true}
+		br := &branch{dirty: true, generation: t.generation}
 		length := lenCommonPrefix(key, p.key)
 
 		if bytes.Equal(p.key, key) && len(key) == length {
+			// replacing the value of the leaf, so the new leaf takes its partial key
+			value.setKey(key)
 			return true, value, nil
 		}
 
+		p = t.maybeCopy(p).(*leaf)
+
 		br.key = key[:length]
 		parentKey := p.key
 
@@ -155,6 +189,7 @@ func (t *Trie) insert(parent node, key []byte, value node) (ok bool, n node, err
 			// if we are not replacing previous leaf, then add it as a child to the new branch
 			if len(parentKey) > len(key) {
 				p.key = p.key[length+1:]
+				p.setDirty(true)
 				br.children[parentKey[length]] = p
 			}
 
@@ -171,6 +206,7 @@ func (t *Trie) insert(parent node, key []byte, value node) (ok bool, n node, err
 		} else {
 			// otherwise, make the leaf a child of the branch and update its partial key
 			p.key = p.key[length+1:]
+			p.setDirty(true)
 			br.children[parentKey[length]] = p
 			br.children[key[length]] = value
 		}
@@ -187,10 +223,13 @@ func (t *Trie) insert(parent node, key []byte, value node) (ok bool, n node, err
 // inserts the value node as the branch's child at the index that's
 // the first nibble of the key
 func (t *Trie) updateBranch(p *branch, key []byte, value node) (ok bool, n node, err error) {
+	p = t.maybeCopy(p).(*branch)
 	length := lenCommonPrefix(key, p.key)
 
 	// whole parent key matches
 	if length == len(p.key) {
+		p.setDirty(true)
+
 		// if node has same key as this branch, then update the value at this branch
 		if bytes.Equal(key, p.key) {
 			switch v := value.(type) {
@@ -219,9 +258,10 @@ func (t *Trie) updateBranch(p *branch, key []byte, value node) (ok bool, n node,
 
 	// we need to branch out at the point where the keys diverge
 	// update partial keys, new branch has key up to matching length
-	br := &branch{key: key[:length], dirty: true}
+	br := &branch{key: key[:length], dirty: true, generation: t.generation}
 
 	parentIndex := p.key[length]
+	p.setDirty(true)
 	_, br.children[parentIndex], err = t.insert(nil, p.key[length+1:], p)
 	if err != nil {
 		return false, nil, err
@@ -268,12 +308,12 @@ func (t *Trie) retrieve(parent node, key []byte) (value *leaf, err error) {
 		length := lenCommonPrefix(p.key, key)
 
 		// found the value at this node
-		if bytes.Equal(p.key, key) || len(key) == 0 {
+		if bytes.Equal(p.key, key) {
 			return &leaf{key: p.key, value: p.value, dirty: true}, nil
 		}
 
-		// did not find value
-		if bytes.Equal(p.key[:length], key) && len(key) < len(p.key) {
+		// did not find value, key diverges from or ends within this branch's partial key
+		if length < len(p.key) {
 			return nil, nil
 		}
 
@@ -306,7 +346,15 @@ func (t *Trie) delete(parent node, key []byte) (ok bool, n node, err error) {
 	case *branch:
 		length := lenCommonPrefix(p.key, key)
 
-		if bytes.Equal(p.key, key) || len(key) == 0 {
+		// key is not in this subtree
+		if length < len(p.key) {
+			return false, p, nil
+		}
+
+		p = t.maybeCopy(p).(*branch)
+		p.setDirty(true)
+
+		if bytes.Equal(p.key, key) {
 			// found the value at this node
 			p.value = nil
 			n = p
@@ -319,9 +367,9 @@ func (t *Trie) delete(parent node, key []byte) (ok bool, n node, err error) {
 			n = p
 		}
 
-		ok, n, err = handleDeletion(p, n, key)
+		ok, n, err = t.handleDeletion(p, n, key)
 	case *leaf:
-		if bytes.Equal(key, p.key) || len(key) == 0 {
+		if bytes.Equal(key, p.key) {
 			ok = true
 		} else {
 			ok = true
@@ -336,14 +384,14 @@ func (t *Trie) delete(parent node, key []byte) (ok bool, n node, err error) {
 // handleDeletion is called when a value is deleted from a branch
 // if the updated branch only has 1 child, it should be combined with that child
 // if the upated branch only has a value, it should be turned into a leaf
-func handleDeletion(p *branch, n node, key []byte) (ok bool, nn node, err error) {
+func (t *Trie) handleDeletion(p *branch, n node, key []byte) (ok bool, nn node, err error) {
 	nn = n
 	length := lenCommonPrefix(p.key, key)
 	bitmap := p.childrenBitmap()
 
 	// if branch has no children, just a value, turn it into a leaf
 	if bitmap == 0 && p.value != nil {
-		nn = &leaf{key: key[:length], value: p.value}
+		nn = &leaf{key: key[:length], value: p.value, dirty: true, generation: t.generation}
 	} else if p.numChildren() == 1 && p.value == nil {
 		// there is only 1 child and no value, combine the child branch with this branch
 		// find index of child
@@ -358,9 +406,9 @@ func handleDeletion(p *branch, n node, key []byte) (ok bool, nn node, err error)
 		child := p.children[i]
 		switch c := child.(type) {
 		case *leaf:
-			nn = &leaf{key: append(append(p.key, []byte{byte(i)}...), c.key...), value: c.value}
+			nn = &leaf{key: append(append(p.key, []byte{byte(i)}...), c.key...), value: c.value, dirty: true, generation: t.generation}
 		case *branch:
-			br := new(branch)
+			br := &branch{dirty: true, generation: t.generation}
 			br.key = append(p.key, append([]byte{byte(i)}, c.key...)...)
 
 			// adopt the grandchildren
@@ -382,6 +430,24 @@ func handleDeletion(p *branch, n node, key []byte) (ok bool, nn node, err error)
 	return ok, nn, err
 }
 
+// maybeCopy returns n if it was created by this generation of the trie, otherwise it returns a copy of n
+// that belongs to this generation. nodes from other generations may be shared with a snapshot
+// and must not be modified
+func (t *Trie) maybeCopy(n node) node {
+	if n.getGeneration() == t.generation {
+		return n
+	}
+
+	cpy := n.copy()
+	switch c := cpy.(type) {
+	case *branch:
+		c.generation = t.generation
+	case *leaf:
+		c.generation = t.generation
+	}
+	return cpy
+}
+
 // lenCommonPrefix returns the length of the common prefix between two keys
 func lenCommonPrefix(a, b []byte) int {
 	var length, max = 0, len(a)
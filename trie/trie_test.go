@@ -555,3 +555,35 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestPutAndGetUpdate(t *testing.T) {
+	trie := newEmpty()
+
+	tests := []trieTest{
+		{key: []byte{0x00, 0x22}, value: []byte("noot"), op: PUT},
+		{key: []byte{0x11, 0x11}, value: []byte("odd"), op: PUT},
+		{key: []byte{0x11, 0x11}, value: []byte("stuff"), op: PUT},
+		{key: []byte{0x11, 0x11}, value: []byte("stuff"), op: GET},
+		{key: []byte{0x00, 0x22}, value: []byte("noot"), op: GET},
+	}
+
+	runTests(t, trie, tests)
+}
+
+func TestDeleteMissingKey(t *testing.T) {
+	trie := newEmpty()
+
+	tests := []trieTest{
+		{key: []byte{0x00}, value: []byte("noot"), op: PUT},
+		{key: []byte{0x00, 0x11}, value: []byte("odd"), op: PUT},
+		{key: []byte{0x11}, op: DEL},
+		{key: []byte{0x11}, value: nil, op: GET},
+		{key: []byte{0x00}, value: []byte("noot"), op: GET},
+		{key: []byte{0x00, 0x11}, value: []byte("odd"), op: GET},
+		{key: []byte{}, op: DEL},
+		{key: []byte{}, value: nil, op: GET},
+		{key: []byte{0x00}, value: []byte("noot"), op: GET},
+	}
+
+	runTests(t, trie, tests)
+}